@@ -8,13 +8,41 @@ package main
 //   mfa4aws [command]
 //
 // Available Commands:
+//   agent       Holds AWS STS sessions in memory for other mfa4aws commands, which find it through MFA4AWS_AUTH_SOCK
+//   exec        Executes a command with AWS STS access keys set in its environment
 //   help        Help about any command
+//   login       Writes AWS STS access keys into a derived profile in $HOME/.aws/credentials
+//   logout      Unsets AWS STS access keys on the shell by wrapping the result in eval, and removes cached sessions
+//   mfa         Manages the IAM user's MFA devices
+//   process     Generates AWS STS access keys in the format expected by credential_process in $HOME/.aws/config
+//   rotate      Replaces the long term IAM access keys of a profile with a new pair and deletes the old key
+//   serve       Serves AWS STS access keys over the HTTP endpoints SDKs read credentials from
 //   shell      Generates AWS STS access keys for use on the shell by wrapping the result in eval
+//   status      Shows the identity and remaining lifetime of the session in the environment or the session cache
+//   totp        Manages virtual MFA seeds used to generate the MFA value instead of --token
+//   vault       Manages long term IAM access keys held in the encrypted vault instead of $HOME/.aws/credentials
 //
 // Flags:
-//   -h, --help             help for mfa4aws
-//   -p, --profile string   AWS Profile name in $HOME/.aws/credentials (default "default")
-//   -t, --token string     Current MFA value to use for STS generation
+//       --config-file string              AWS config file (default $AWS_CONFIG_FILE or $HOME/.aws/config)
+//       --credentials-file string         AWS credentials file (default $AWS_SHARED_CREDENTIALS_FILE or $HOME/.aws/credentials)
+//       --error-format string             Format errors are written in, one of json, text (default "text")
+//       --duration duration               Lifetime of a new session such as 4h or 90m, overriding duration_seconds in the profile
+//   -f, --force                           Ignore any cached session and generate new STS credentials
+//   -h, --help                            help for mfa4aws
+//       --iam-endpoint string             URL of the IAM endpoint, overriding iam_endpoint in the profile
+//       --max-retries int                 Times a throttled or failed call to STS and IAM is retried, calls with an MFA value never are (default 3)
+//       --min-lifetime duration           Minimum remaining lifetime of a cached session for it to be reused (default 5m0s)
+//   -p, --profile string                  AWS Profile name in the AWS credentials or config file, $AWS_PROFILE when not given (default "default")
+//       --region string                   AWS region to call STS and IAM in, overriding region in the profile
+//       --serial string                   Serial number or ARN of the MFA device to use, overriding mfa_serial in the profile
+//       --sts-endpoint string             URL of the STS endpoint, overriding sts_endpoint in the profile
+//       --sts-regional-endpoints string   Use the regional or legacy global STS endpoint, overriding sts_regional_endpoints in the profile
+//       --timeout duration                Time each call to STS and IAM may take including its retries, 0 for no limit (default 30s)
+//   -t, --token string                    Current MFA value to use for STS generation, or - to read it from stdin (prompted for when not given)
+//       --token-command string            Command whose output is used as the MFA value when --token is not given
+//       --until string                    Time of day such as 18:00, or RFC3339 timestamp, a new session should last until
+//       --use-dualstack-endpoint          Call the dual-stack endpoint of STS
+//       --use-fips-endpoint               Call the FIPS endpoints of STS and IAM
 //
 // Use "mfa4aws [command] --help" for more information about a command.
 //
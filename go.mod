@@ -7,6 +7,8 @@ require (
 	github.com/matryer/moq v0.3.0
 	github.com/spf13/afero v1.9.4
 	github.com/spf13/cobra v1.6.1
-	golang.org/x/net v0.7.0 // indirect
+	github.com/spf13/pflag v1.0.5
+	golang.org/x/crypto v0.17.0
+	golang.org/x/term v0.15.0
 	gopkg.in/ini.v1 v1.67.0
 )
@@ -0,0 +1,225 @@
+package agent
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mfa4aws/internal/pkg/aws"
+	"net"
+	"sync"
+	"time"
+)
+
+//GenerateFunc generates new STS Credentials for input
+type GenerateFunc func(input *aws.STSCredentialsInput) (*aws.Credentials, error)
+
+//Agent holds the sessions generated for its clients in memory
+type Agent struct {
+	mu       sync.Mutex
+	generate GenerateFunc
+	sessions map[sessionKey]*heldSession
+	now      func() time.Time
+}
+
+//sessionKey identifies a session by profile, MFA device, files and endpoints
+type sessionKey struct {
+	profile         string
+	serialNumber    string
+	credentialsFile string
+	configFile      string
+	endpoints       aws.EndpointConfig
+}
+
+//heldSession is the session held for a sessionKey, locked while it is generated
+type heldSession struct {
+	mu              sync.Mutex
+	creds           *aws.Credentials
+	durationSeconds int64
+}
+
+//tokenRequiredError is returned by the token provider of requests without an MFA value
+type tokenRequiredError struct {
+	profile string
+}
+
+func (e *tokenRequiredError) Error() string {
+	return fmt.Sprintf("An MFA value is required for profile %s", e.profile)
+}
+
+//mfaDeviceRequiredError is returned by the MFA device selector of requests without a serial number
+type mfaDeviceRequiredError struct {
+	serialNumbers []string
+}
+
+func (e *mfaDeviceRequiredError) Error() string {
+	return aws.ErrMultipleMFADevicesForUser.Error()
+}
+
+//New returns an Agent which calls generate for the sessions it does not hold
+func New(generate GenerateFunc) *Agent {
+	return &Agent{
+		generate: generate,
+		sessions: map[sessionKey]*heldSession{},
+		now:      time.Now,
+	}
+}
+
+//Serve accepts connections on listener and answers their requests until listener is closed
+func (a *Agent) Serve(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			a.ServeConn(conn)
+		}()
+	}
+}
+
+//ServeConn answers each line delimited request read from conn until it is closed
+func (a *Agent) ServeConn(conn io.ReadWriter) error {
+	reader := bufio.NewReader(conn)
+	encoder := json.NewEncoder(conn)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) != 0 {
+			if err := encoder.Encode(a.handle(line)); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (a *Agent) handle(line []byte) *Response {
+	request := &Request{}
+	if err := json.Unmarshal(line, request); err != nil {
+		return errorResponse(ErrCodeInvalidRequest, "Invalid request - "+err.Error())
+	}
+	if request.Version != ProtocolVersion {
+		return errorResponse(ErrCodeUnsupportedVersion, fmt.Sprintf("Unsupported protocol version %d, the agent speaks version %d", request.Version, ProtocolVersion))
+	}
+
+	switch request.Type {
+	case RequestPing:
+		return &Response{Version: ProtocolVersion}
+	case RequestCredentials:
+		if request.Credentials == nil {
+			return errorResponse(ErrCodeInvalidRequest, "Invalid request - missing credentials")
+		}
+		creds, err := a.credentials(request.Credentials)
+		if err != nil {
+			switch err := err.(type) {
+			case *tokenRequiredError:
+				return errorResponse(ErrCodeTokenRequired, err.Error())
+			case *mfaDeviceRequiredError:
+				response := errorResponse(ErrCodeMFADeviceRequired, err.Error())
+				response.Error.SerialNumbers = err.serialNumbers
+				return response
+			}
+			response := errorResponse(ErrCodeFailed, err.Error())
+			response.Error.Category = string(aws.ErrorCategoryOf(err))
+			return response
+		}
+		return &Response{Version: ProtocolVersion, Credentials: creds}
+	case RequestForget:
+		if request.Forget == nil {
+			return errorResponse(ErrCodeInvalidRequest, "Invalid request - missing forget")
+		}
+		return &Response{Version: ProtocolVersion, Sessions: a.forget(request.Forget)}
+	}
+
+	return errorResponse(ErrCodeInvalidRequest, "Unknown request type "+request.Type)
+}
+
+//credentials returns the session held for the request, or generates a new one
+func (a *Agent) credentials(request *CredentialsRequest) (*aws.Credentials, error) {
+	key := sessionKey{
+		profile:         request.Profile,
+		serialNumber:    request.SerialNumber,
+		credentialsFile: request.CredentialsFile,
+		configFile:      request.ConfigFile,
+		endpoints:       request.endpoints(),
+	}
+
+	a.mu.Lock()
+	held, ok := a.sessions[key]
+	if !ok {
+		held = &heldSession{}
+		a.sessions[key] = held
+	}
+	a.mu.Unlock()
+
+	held.mu.Lock()
+	defer held.mu.Unlock()
+
+	minimumLifetime := time.Duration(request.MinimumLifetimeSeconds) * time.Second
+	otherDuration := held.creds != nil && request.DurationSeconds != 0 && request.DurationSeconds != held.durationSeconds
+	if held.creds != nil && !request.Force && !otherDuration && held.creds.Expiration.Sub(a.now()) > minimumLifetime {
+		return held.creds, nil
+	}
+
+	creds, err := a.generate(&aws.STSCredentialsInput{
+		Profile:        request.Profile,
+		TokenCode:      request.TokenCode,
+		TokenGenerated: request.TokenGenerated,
+		TokenProvider: func() (string, error) {
+			return "", &tokenRequiredError{request.Profile}
+		},
+		SerialNumber: request.SerialNumber,
+		SelectMFADevice: func(serialNumbers []string) (string, error) {
+			return "", &mfaDeviceRequiredError{serialNumbers}
+		},
+		Force:           request.Force,
+		MinimumLifetime: minimumLifetime,
+		Duration:        time.Duration(request.DurationSeconds) * time.Second,
+		ProfileSource: aws.ProfileSource{
+			CredentialsFile: request.CredentialsFile,
+			ConfigFile:      request.ConfigFile,
+			Endpoints:       request.endpoints(),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	held.creds, held.durationSeconds = creds, request.DurationSeconds
+
+	return creds, nil
+}
+
+//forget drops the sessions selected by request and returns how many were held
+func (a *Agent) forget(request *ForgetRequest) int {
+	a.mu.Lock()
+	sessions := make(map[sessionKey]*heldSession, len(a.sessions))
+	for key, held := range a.sessions {
+		sessions[key] = held
+	}
+	a.mu.Unlock()
+
+	forgotten := 0
+	for key, held := range sessions {
+		held.mu.Lock()
+		if held.creds != nil && (request.All || (len(request.Profile) != 0 && key.profile == request.Profile) ||
+			(len(request.AccessKeyID) != 0 && held.creds.AWSAccessKeyID == request.AccessKeyID)) {
+			held.creds = nil
+			forgotten++
+		}
+		held.mu.Unlock()
+	}
+	return forgotten
+}
+
+func errorResponse(code string, message string) *Response {
+	return &Response{
+		Version: ProtocolVersion,
+		Error:   &Error{Code: code, Message: message},
+	}
+}
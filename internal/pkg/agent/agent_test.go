@@ -0,0 +1,351 @@
+package agent
+
+import (
+	"bufio"
+	"mfa4aws/internal/pkg/aws"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+//fakeGenerator records the inputs it is called with, asking for the MFA value and device like GenerateSTSCredentials
+type fakeGenerator struct {
+	serialNumbers []string
+	calls         int
+	inputs        []aws.STSCredentialsInput
+	err           error
+}
+
+func (g *fakeGenerator) generate(input *aws.STSCredentialsInput) (*aws.Credentials, error) {
+	g.calls++
+	g.inputs = append(g.inputs, *input)
+	if g.err != nil {
+		return nil, g.err
+	}
+
+	serialNumber := input.SerialNumber
+	if len(serialNumber) == 0 && len(g.serialNumbers) > 1 {
+		var err error
+		if serialNumber, err = input.SelectMFADevice(g.serialNumbers); err != nil {
+			return nil, err
+		}
+	}
+
+	tokenCode := input.TokenCode
+	if len(tokenCode) == 0 {
+		var err error
+		if tokenCode, err = input.TokenProvider(); err != nil {
+			return nil, err
+		}
+	}
+
+	return &aws.Credentials{
+		AWSAccessKeyID:  "AHIAACNB4F5KCDQXSGYW4",
+		AWSSessionToken: input.Profile + "/" + serialNumber + "/" + tokenCode,
+		Expiration:      time.Now().Add(time.Hour),
+	}, nil
+}
+
+func newTestClient(t *testing.T, agent *Agent) *Client {
+	clientConn, agentConn := net.Pipe()
+	go agent.ServeConn(agentConn)
+	t.Cleanup(func() { clientConn.Close() })
+	return NewClient(clientConn)
+}
+
+func TestAgentProtocol(t *testing.T) {
+	generator := &fakeGenerator{}
+	clientConn, agentConn := net.Pipe()
+	defer clientConn.Close()
+	go New(generator.generate).ServeConn(agentConn)
+
+	reader := bufio.NewReader(clientConn)
+	tests := []struct {
+		name    string
+		request string
+		want    string
+	}{
+		{
+			"Valid/Ping",
+			`{"version":1,"type":"ping"}`,
+			`{"version":1}`,
+		},
+		{
+			"Valid/TokenRequired",
+			`{"version":1,"type":"credentials","credentials":{"profile":"work"}}`,
+			`{"version":1,"error":{"code":"token_required","message":"An MFA value is required for profile work"}}`,
+		},
+		{
+			"Invalid/Version",
+			`{"version":2,"type":"ping"}`,
+			`{"version":1,"error":{"code":"unsupported_version","message":"Unsupported protocol version 2, the agent speaks version 1"}}`,
+		},
+		{
+			"Invalid/Type",
+			`{"version":1,"type":"blah"}`,
+			`{"version":1,"error":{"code":"invalid_request","message":"Unknown request type blah"}}`,
+		},
+		{
+			"Invalid/MissingCredentials",
+			`{"version":1,"type":"credentials"}`,
+			`{"version":1,"error":{"code":"invalid_request","message":"Invalid request - missing credentials"}}`,
+		},
+		{
+			"Valid/Forget",
+			`{"version":1,"type":"forget","forget":{"all":true}}`,
+			`{"version":1}`,
+		},
+		{
+			"Invalid/MissingForget",
+			`{"version":1,"type":"forget"}`,
+			`{"version":1,"error":{"code":"invalid_request","message":"Invalid request - missing forget"}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := clientConn.Write([]byte(tt.request + "\n")); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			got, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatalf("ReadString() error = %v", err)
+			}
+			if strings.TrimSpace(got) != tt.want {
+				t.Errorf("response = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientCredentials(t *testing.T) {
+	generator := &fakeGenerator{}
+	client := newTestClient(t, New(generator.generate))
+
+	var prompted int
+	input := &aws.STSCredentialsInput{
+		Profile: "work",
+		TokenProvider: func() (string, error) {
+			prompted++
+			return "123456", nil
+		},
+		MinimumLifetime: 5 * time.Minute,
+	}
+
+	for i := 0; i < 2; i++ {
+		creds, err := client.Credentials(input)
+		if err != nil {
+			t.Fatalf("Credentials() error = %v", err)
+		}
+		if creds.AWSSessionToken != "work//123456" {
+			t.Errorf("Credentials() = %v, want a session for work", creds.AWSSessionToken)
+		}
+	}
+	if prompted != 1 || generator.calls != 2 {
+		t.Errorf("Credentials() prompted %d times and generated %d times, want 1 and 2", prompted, generator.calls)
+	}
+
+	input.Force = true
+	if _, err := client.Credentials(input); err != nil {
+		t.Fatalf("Credentials() error = %v", err)
+	}
+	if prompted != 2 {
+		t.Errorf("Credentials() prompted %d times with force, want 2", prompted)
+	}
+}
+
+func TestClientCredentialsFilesAndEndpoints(t *testing.T) {
+	generator := &fakeGenerator{}
+	client := newTestClient(t, New(generator.generate))
+
+	input := &aws.STSCredentialsInput{
+		Profile:         "work",
+		TokenCode:       "123456",
+		MinimumLifetime: 5 * time.Minute,
+		ProfileSource: aws.ProfileSource{
+			CredentialsFile: "/home/johnsmith/.aws/credentials",
+			Endpoints:       aws.EndpointConfig{Region: "eu-west-1"},
+		},
+	}
+	if _, err := client.Credentials(input); err != nil {
+		t.Fatalf("Credentials() error = %v", err)
+	}
+	if got := generator.inputs[0]; got.CredentialsFile != input.CredentialsFile || got.Endpoints != input.Endpoints {
+		t.Errorf("generate() input = %+v, want the files and endpoints of the request", got)
+	}
+	if got := generator.inputs[0]; !filepath.IsAbs(got.ConfigFile) {
+		t.Errorf("generate() input config file = %v, want the client's config file resolved", got.ConfigFile)
+	}
+
+	input.CredentialsFile = "/home/johnsmith/work/credentials"
+	if _, err := client.Credentials(input); err != nil {
+		t.Fatalf("Credentials() error = %v", err)
+	}
+	input.Endpoints.Region = "us-west-2"
+	if _, err := client.Credentials(input); err != nil {
+		t.Fatalf("Credentials() error = %v", err)
+	}
+	if generator.calls != 3 {
+		t.Errorf("Credentials() generated %d times, want a session for each file and region", generator.calls)
+	}
+}
+
+func TestClientCredentialsDuration(t *testing.T) {
+	generator := &fakeGenerator{}
+	client := newTestClient(t, New(generator.generate))
+
+	input := &aws.STSCredentialsInput{Profile: "work", TokenCode: "123456", Duration: time.Hour}
+	for _, duration := range []time.Duration{time.Hour, 0, time.Hour, 4 * time.Hour} {
+		input.Duration = duration
+		if _, err := client.Credentials(input); err != nil {
+			t.Fatalf("Credentials() error = %v", err)
+		}
+	}
+	if generator.calls != 2 {
+		t.Errorf("Credentials() generated %d times, want 2", generator.calls)
+	}
+	if last := generator.inputs[1]; last.Force || last.Duration != 4*time.Hour {
+		t.Errorf("generate() input = %+v, want a session for the other duration", last)
+	}
+}
+
+func TestClientCredentialsGeneratedToken(t *testing.T) {
+	for _, seed := range []string{"654321", ""} {
+		generator := &fakeGenerator{}
+		client := newTestClient(t, New(generator.generate))
+
+		input := &aws.STSCredentialsInput{
+			Profile:                "work",
+			GeneratedTokenProvider: func() (string, error) { return seed, nil },
+			TokenProvider:          func() (string, error) { return "123456", nil },
+		}
+		creds, err := client.Credentials(input)
+		if err != nil {
+			t.Fatalf("Credentials() error = %v", err)
+		}
+
+		want, wantGenerated := "work//654321", true
+		if len(seed) == 0 {
+			want, wantGenerated = "work//123456", false
+		}
+		last := generator.inputs[len(generator.inputs)-1]
+		if creds.AWSSessionToken != want || last.TokenGenerated != wantGenerated {
+			t.Errorf("Credentials() = %v generated %v, want %v generated %v", creds.AWSSessionToken, last.TokenGenerated, want, wantGenerated)
+		}
+	}
+}
+
+func TestAgentCredentialsConcurrent(t *testing.T) {
+	blocked, release := make(chan struct{}), make(chan struct{})
+	a := New(func(input *aws.STSCredentialsInput) (*aws.Credentials, error) {
+		if input.Profile == "slow" {
+			close(blocked)
+			<-release
+		}
+		return &aws.Credentials{Expiration: time.Now().Add(time.Hour)}, nil
+	})
+
+	done := make(chan error)
+	go func() {
+		_, err := a.credentials(&CredentialsRequest{Profile: "slow"})
+		done <- err
+	}()
+	<-blocked
+
+	//a session for another profile is generated while the slow one is still waiting on STS
+	if _, err := a.credentials(&CredentialsRequest{Profile: "work"}); err != nil {
+		t.Errorf("credentials() error = %v", err)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Errorf("credentials() error = %v", err)
+	}
+}
+
+func TestClientCredentialsMFADevice(t *testing.T) {
+	generator := &fakeGenerator{serialNumbers: []string{"phone", "yubikey"}}
+	client := newTestClient(t, New(generator.generate))
+
+	creds, err := client.Credentials(&aws.STSCredentialsInput{
+		Profile:   "work",
+		TokenCode: "123456",
+		SelectMFADevice: func(serialNumbers []string) (string, error) {
+			return serialNumbers[1], nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Credentials() error = %v", err)
+	}
+	if creds.AWSSessionToken != "work/yubikey/123456" {
+		t.Errorf("Credentials() = %v, want a session for the yubikey", creds.AWSSessionToken)
+	}
+
+	_, err = client.Credentials(&aws.STSCredentialsInput{Profile: "work", TokenCode: "123456"})
+	if agentErr, ok := err.(*Error); !ok || agentErr.Code != ErrCodeMFADeviceRequired || len(agentErr.SerialNumbers) != 2 {
+		t.Errorf("Credentials() error = %v, want %s", err, ErrCodeMFADeviceRequired)
+	}
+}
+
+func TestClientCredentialsError(t *testing.T) {
+	generator := &fakeGenerator{err: aws.ErrInvalidToken}
+	client := newTestClient(t, New(generator.generate))
+
+	_, err := client.Credentials(&aws.STSCredentialsInput{Profile: "work", TokenCode: "123456"})
+	if agentErr, ok := err.(*Error); !ok || agentErr.Code != ErrCodeFailed || agentErr.Message != "Invalid token code" {
+		t.Errorf("Credentials() error = %v, want %s", err, ErrCodeFailed)
+	}
+	if category := aws.ErrorCategoryOf(err); category != aws.ErrorCategoryMFA {
+		t.Errorf("ErrorCategoryOf() = %v, want %v", category, aws.ErrorCategoryMFA)
+	}
+}
+
+func TestClientForget(t *testing.T) {
+	generator := &fakeGenerator{}
+	client := newTestClient(t, New(generator.generate))
+
+	credentials := func(profile string) {
+		if _, err := client.Credentials(&aws.STSCredentialsInput{Profile: profile, TokenCode: "123456", MinimumLifetime: 5 * time.Minute}); err != nil {
+			t.Fatalf("Credentials() error = %v", err)
+		}
+	}
+	credentials("work")
+	credentials("home")
+
+	tests := []struct {
+		name      string
+		request   *ForgetRequest
+		want      int
+		wantCalls int
+	}{
+		{"Valid/Profile", &ForgetRequest{Profile: "work"}, 1, 3},
+		{"Valid/AccessKeyID", &ForgetRequest{AccessKeyID: "AHIAACNB4F5KCDQXSGYW4"}, 2, 5},
+		{"Valid/All", &ForgetRequest{All: true}, 2, 7},
+		{"Valid/OtherProfile", &ForgetRequest{Profile: "prod"}, 0, 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := client.Forget(tt.request)
+			if err != nil {
+				t.Fatalf("Forget() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Forget() = %v, want %v", got, tt.want)
+			}
+
+			//forgotten sessions are generated again, the others are still held
+			credentials("work")
+			credentials("home")
+			if generator.calls != tt.wantCalls {
+				t.Errorf("Credentials() generated %d times, want %d", generator.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestClientPing(t *testing.T) {
+	client := newTestClient(t, New((&fakeGenerator{}).generate))
+	if err := client.Ping(); err != nil {
+		t.Errorf("Ping() error = %v", err)
+	}
+}
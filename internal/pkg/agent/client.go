@@ -0,0 +1,139 @@
+package agent
+
+import (
+	"bufio"
+	"encoding/json"
+	"mfa4aws/internal/pkg/aws"
+	"net"
+	"time"
+)
+
+const (
+	//EnvNameAuthSock is the environment variable holding the path of the agent's socket
+	EnvNameAuthSock string = "MFA4AWS_AUTH_SOCK"
+
+	socketNetwork string = "unix"
+)
+
+//Client sends requests to an Agent
+type Client struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+//Dial connects to the agent listening on the Unix socket path
+func Dial(path string) (*Client, error) {
+	conn, err := net.Dial(socketNetwork, path)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(conn), nil
+}
+
+//NewClient returns a Client sending requests on conn
+func NewClient(conn net.Conn) *Client {
+	return &Client{
+		conn:   conn,
+		reader: bufio.NewReader(conn),
+	}
+}
+
+//Close closes the connection to the agent
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+//Ping checks the agent is running and speaks the same protocol version
+func (c *Client) Ping() error {
+	_, err := c.send(&Request{Version: ProtocolVersion, Type: RequestPing})
+	return err
+}
+
+//Credentials asks the agent for a session for input, answering its prompts with input's providers
+func (c *Client) Credentials(input *aws.STSCredentialsInput) (*aws.Credentials, error) {
+	//the files are resolved here, as the environment of the agent may name others
+	credentialsFile, configFile, err := aws.ProfileFiles(input.CredentialsFile, input.ConfigFile)
+	if err != nil {
+		return nil, err
+	}
+
+	request := &CredentialsRequest{
+		Profile:                input.Profile,
+		SerialNumber:           input.SerialNumber,
+		TokenCode:              input.TokenCode,
+		DurationSeconds:        int64(input.Duration / time.Second),
+		MinimumLifetimeSeconds: int64(input.MinimumLifetime / time.Second),
+		Force:                  input.Force,
+		CredentialsFile:        credentialsFile,
+		ConfigFile:             configFile,
+		Region:                 input.Endpoints.Region,
+		STSRegionalEndpoints:   input.Endpoints.STSRegionalEndpoints,
+		UseFIPSEndpoint:        input.Endpoints.UseFIPSEndpoint,
+		UseDualStackEndpoint:   input.Endpoints.UseDualStackEndpoint,
+		STSEndpoint:            input.Endpoints.STSEndpoint,
+		IAMEndpoint:            input.Endpoints.IAMEndpoint,
+	}
+
+	generatedTokenProvider := input.GeneratedTokenProvider
+	for {
+		response, err := c.send(&Request{Version: ProtocolVersion, Type: RequestCredentials, Credentials: request})
+		if err == nil {
+			return response.Credentials, nil
+		}
+
+		agentErr, ok := err.(*Error)
+		if !ok {
+			return nil, err
+		}
+
+		switch {
+		case agentErr.Code == ErrCodeTokenRequired && len(request.TokenCode) == 0 && generatedTokenProvider != nil:
+			if request.TokenCode, err = generatedTokenProvider(); err != nil {
+				return nil, err
+			}
+			request.TokenGenerated = len(request.TokenCode) != 0
+			//without a stored seed TokenProvider is asked next
+			generatedTokenProvider = nil
+		case agentErr.Code == ErrCodeTokenRequired && len(request.TokenCode) == 0 && input.TokenProvider != nil:
+			if request.TokenCode, err = input.TokenProvider(); err != nil {
+				return nil, err
+			}
+		case agentErr.Code == ErrCodeMFADeviceRequired && len(request.SerialNumber) == 0 && input.SelectMFADevice != nil:
+			if request.SerialNumber, err = input.SelectMFADevice(agentErr.SerialNumbers); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, err
+		}
+	}
+}
+
+//Forget drops the sessions the agent holds matching request and returns how many were dropped
+func (c *Client) Forget(request *ForgetRequest) (int, error) {
+	response, err := c.send(&Request{Version: ProtocolVersion, Type: RequestForget, Forget: request})
+	if err != nil {
+		return 0, err
+	}
+	return response.Sessions, nil
+}
+
+//send writes request to the agent and returns its response, or the Error it holds
+func (c *Client) send(request *Request) (*Response, error) {
+	if err := json.NewEncoder(c.conn).Encode(request); err != nil {
+		return nil, err
+	}
+
+	line, err := c.reader.ReadBytes('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	response := &Response{}
+	if err := json.Unmarshal(line, response); err != nil {
+		return nil, err
+	}
+	if response.Error != nil {
+		return nil, response.Error
+	}
+	return response, nil
+}
@@ -0,0 +1,153 @@
+/*
+Package agent holds STS sessions in memory for other mfa4aws processes, which reach it over a Unix socket.
+
+# Protocol
+
+Clients connect to the socket named by MFA4AWS_AUTH_SOCK and write requests as single line JSON documents. The agent
+answers each request with a single line JSON response, in order, and several requests may be sent on a connection.
+Every request and response carries the protocol version, currently 1. Requests of any other version are answered with
+an unsupported_version error.
+
+A ping request checks the agent is running:
+
+	{"version":1,"type":"ping"}
+	{"version":1}
+
+A credentials request asks for a session for a profile, read from credentials_file and config_file and reaching STS
+and IAM as set by region, sts_regional_endpoints, use_fips_endpoint, use_dualstack_endpoint, sts_endpoint and
+iam_endpoint. Those left out are the agent's own. The agent returns a session it holds for the same profile, MFA device,
+files and endpoints when it has more than minimum_lifetime_seconds remaining, unless force is set or the session was
+generated for another duration_seconds:
+
+	{"version":1,"type":"credentials","credentials":{"profile":"work","minimum_lifetime_seconds":300}}
+	{"version":1,"credentials":{"aws_access_key_id":"...","aws_secret_access_key":"...","aws_session_token":"...",...}}
+
+When a new session requires an MFA value the agent answers with a token_required error, and the client repeats the
+request with token_code set. When the user has several MFA devices and none is pinned with serial_number, the agent
+answers with an mfa_device_required error listing them in serial_numbers, and the client repeats the request with
+serial_number set:
+
+	{"version":1,"error":{"code":"token_required","message":"An MFA value is required for profile work"}}
+	{"version":1,"type":"credentials","credentials":{"profile":"work","token_code":"123456"}}
+
+A forget request drops the sessions the agent holds for a profile, or for the session using access_key_id, or all of
+them, and answers with the number dropped:
+
+	{"version":1,"type":"forget","forget":{"profile":"work"}}
+	{"version":1,"sessions":1}
+
+Any other failure is answered with an invalid_request or failed error holding the message to show the user. Failed
+errors also carry the category of the failure, one of config, auth, mfa, network, throttling or unknown:
+
+	{"version":1,"error":{"code":"failed","message":"Invalid token code","category":"mfa"}}
+*/
+package agent
+
+import (
+	"fmt"
+	"mfa4aws/internal/pkg/aws"
+	"strings"
+)
+
+const (
+	//ProtocolVersion is the version of the protocol spoken by the agent and its clients
+	ProtocolVersion = 1
+
+	//RequestPing checks the agent is running
+	RequestPing string = "ping"
+	//RequestCredentials asks for a session for a profile
+	RequestCredentials string = "credentials"
+	//RequestForget drops held sessions
+	RequestForget string = "forget"
+
+	//ErrCodeTokenRequired is returned when a new session requires an MFA value
+	ErrCodeTokenRequired string = "token_required"
+	//ErrCodeMFADeviceRequired is returned when the user has several MFA devices and none is pinned
+	ErrCodeMFADeviceRequired string = "mfa_device_required"
+	//ErrCodeUnsupportedVersion is returned for requests of another protocol version
+	ErrCodeUnsupportedVersion string = "unsupported_version"
+	//ErrCodeInvalidRequest is returned for malformed or unknown requests
+	ErrCodeInvalidRequest string = "invalid_request"
+	//ErrCodeFailed is returned when no session could be generated
+	ErrCodeFailed string = "failed"
+)
+
+//Request is a request sent by a client to the agent
+type Request struct {
+	Version     int                 `json:"version"`
+	Type        string              `json:"type"`
+	Credentials *CredentialsRequest `json:"credentials,omitempty"`
+	Forget      *ForgetRequest      `json:"forget,omitempty"`
+}
+
+//CredentialsRequest holds the parameters of a credentials request
+type CredentialsRequest struct {
+	Profile                string `json:"profile"`
+	SerialNumber           string `json:"serial_number,omitempty"`
+	TokenCode              string `json:"token_code,omitempty"`
+	TokenGenerated         bool   `json:"token_generated,omitempty"`
+	DurationSeconds        int64  `json:"duration_seconds,omitempty"`
+	MinimumLifetimeSeconds int64  `json:"minimum_lifetime_seconds,omitempty"`
+	Force                  bool   `json:"force,omitempty"`
+	CredentialsFile        string `json:"credentials_file,omitempty"`
+	ConfigFile             string `json:"config_file,omitempty"`
+	Region                 string `json:"region,omitempty"`
+	STSRegionalEndpoints   string `json:"sts_regional_endpoints,omitempty"`
+	UseFIPSEndpoint        bool   `json:"use_fips_endpoint,omitempty"`
+	UseDualStackEndpoint   bool   `json:"use_dualstack_endpoint,omitempty"`
+	STSEndpoint            string `json:"sts_endpoint,omitempty"`
+	IAMEndpoint            string `json:"iam_endpoint,omitempty"`
+}
+
+//ForgetRequest selects the sessions dropped by a forget request
+type ForgetRequest struct {
+	Profile     string `json:"profile,omitempty"`
+	AccessKeyID string `json:"access_key_id,omitempty"`
+	All         bool   `json:"all,omitempty"`
+}
+
+//endpoints returns the region and endpoints of the request
+func (r *CredentialsRequest) endpoints() aws.EndpointConfig {
+	return aws.EndpointConfig{
+		Region:               r.Region,
+		STSRegionalEndpoints: r.STSRegionalEndpoints,
+		UseFIPSEndpoint:      r.UseFIPSEndpoint,
+		UseDualStackEndpoint: r.UseDualStackEndpoint,
+		STSEndpoint:          r.STSEndpoint,
+		IAMEndpoint:          r.IAMEndpoint,
+	}
+}
+
+//Response is the agent's answer to a Request
+type Response struct {
+	Version     int              `json:"version"`
+	Credentials *aws.Credentials `json:"credentials,omitempty"`
+	Sessions    int              `json:"sessions,omitempty"`
+	Error       *Error           `json:"error,omitempty"`
+}
+
+//Error is a failed Request
+type Error struct {
+	Code          string   `json:"code"`
+	Message       string   `json:"message"`
+	Category      string   `json:"category,omitempty"`
+	SerialNumbers []string `json:"serial_numbers,omitempty"`
+}
+
+func (e *Error) Error() string {
+	if len(e.SerialNumbers) == 0 {
+		return e.Message
+	}
+	return fmt.Sprintf("%s, select one of %s", e.Message, strings.Join(e.SerialNumbers, ", "))
+}
+
+//ErrorCategory returns the category of the failure, MFA for errors asking for an MFA value or device
+func (e *Error) ErrorCategory() aws.ErrorCategory {
+	switch {
+	case e.Code == ErrCodeTokenRequired, e.Code == ErrCodeMFADeviceRequired:
+		return aws.ErrorCategoryMFA
+	case len(e.Category) != 0:
+		return aws.ErrorCategory(e.Category)
+	}
+	return aws.ErrorCategoryUnknown
+}
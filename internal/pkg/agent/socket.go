@@ -0,0 +1,59 @@
+package agent
+
+import (
+	"errors"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+)
+
+const (
+	socketFolder string = ".aws/mfa4aws"
+	socketFile   string = "agent.sock"
+
+	socketDirMode  os.FileMode = 0700
+	socketFileMode os.FileMode = 0600
+)
+
+var (
+	//ErrAgentRunning is returned when another agent is already listening on the socket
+	ErrAgentRunning = errors.New("An agent is already listening on the socket")
+)
+
+//DefaultSocketPath returns the path of the agent's socket in $HOME/.aws/mfa4aws
+func DefaultSocketPath() (string, error) {
+	user, err := user.Current()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(user.HomeDir, socketFolder, socketFile), nil
+}
+
+//Listen listens on the Unix socket path, replacing a stale socket
+func Listen(path string) (net.Listener, error) {
+	if err := os.MkdirAll(filepath.Dir(path), socketDirMode); err != nil {
+		return nil, err
+	}
+
+	if _, err := os.Stat(path); err == nil {
+		if conn, err := net.Dial(socketNetwork, path); err == nil {
+			conn.Close()
+			return nil, ErrAgentRunning
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+
+	listener, err := net.Listen(socketNetwork, path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, socketFileMode); err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	return listener, nil
+}
@@ -0,0 +1,11 @@
+//Package appfs holds the filesystem the AWS files, session cache and vault are read from and written to
+package appfs
+
+import (
+	"github.com/spf13/afero"
+)
+
+var (
+	//Fs is the filesystem used by the aws and vault packages, replaced by an in-memory one in tests
+	Fs = afero.NewOsFs()
+)
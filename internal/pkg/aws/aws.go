@@ -1,50 +1,238 @@
 package aws
 
 import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/iam"
 	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
 )
 
 //Credentials represents the set of attributes used to authenticate to AWS with a short lived session
 type Credentials struct {
-	AWSAccessKeyID     string `ini:"aws_access_key_id"`
-	AWSSecretAccessKey string `ini:"aws_secret_access_key"`
-	AWSSessionToken    string `ini:"aws_session_token"`
-	AWSSecurityToken   string `ini:"aws_security_token"`
-	PrincipalARN       string `ini:"x_principal_arn"`
+	AWSAccessKeyID     string    `ini:"aws_access_key_id" json:"aws_access_key_id"`
+	AWSSecretAccessKey string    `ini:"aws_secret_access_key" json:"aws_secret_access_key"`
+	AWSSessionToken    string    `ini:"aws_session_token" json:"aws_session_token"`
+	AWSSecurityToken   string    `ini:"aws_security_token" json:"aws_security_token"`
+	PrincipalARN       string    `ini:"x_principal_arn" json:"x_principal_arn"`
+	Expiration         time.Time `ini:"x_expiration" json:"x_expiration"`
+}
+
+//AccountID returns the AWS account ID from the PrincipalARN, or an empty string when it is not a valid ARN
+func (c *Credentials) AccountID() string {
+	principal, err := arn.Parse(c.PrincipalARN)
+	if err != nil {
+		return ""
+	}
+	return principal.AccountID
+}
+
+//TokenProvider returns the current MFA value, it is only called when a new session is required
+type TokenProvider func() (string, error)
+
+//STSCredentialsInput represents the parameters used to generate STS Credentials
+type STSCredentialsInput struct {
+	//Profile is the AWS profile name holding the IAM user's long term access keys
+	Profile string
+
+	//TokenCode is the current MFA value. It is only required when no cached session can be used
+	TokenCode string
+
+	//TokenProvider is called for the MFA value when TokenCode is empty and no cached session can be used
+	TokenProvider TokenProvider
+
+	//GeneratedTokenProvider is called before TokenProvider for an MFA value generated from a stored TOTP seed
+	GeneratedTokenProvider TokenProvider
+
+	//TokenGenerated marks TokenCode as generated from a stored TOTP seed
+	TokenGenerated bool
+
+	//SerialNumber pins the MFA device to use, overriding any mfa_serial in the profile
+	SerialNumber string
+
+	//SelectMFADevice is called to choose an MFA device when the user has several and none is pinned
+	SelectMFADevice MFADeviceSelector
+
+	//Force bypasses the session cache and always requests a new session
+	Force bool
+
+	//MinimumLifetime is the remaining lifetime a cached session must have to be reused
+	MinimumLifetime time.Duration
+
+	//Duration is the lifetime of a new session, overriding any duration_seconds in the profile. Zero leaves it to STS
+	Duration time.Duration
+
+	ProfileSource
 }
 
-//GenerateSTSCredentials created STS Credentials
-func GenerateSTSCredentials(profile string, tokenCode string) (*Credentials, error) {
+//GenerateSTSCredentials created STS Credentials, reusing a cached session when one is still valid
+func GenerateSTSCredentials(input *STSCredentialsInput) (*Credentials, error) {
+
+	profileName := input.Profile
+	if len(profileName) == 0 {
+		profileName = profileDefault
+	}
 
-	awsSession, err := createSession("", profile)
+	awsSession, p, err := createSession(input.ProfileSource, profileName, input.SerialNumber)
 	if err != nil {
 		return nil, err
 	}
 
+	duration := sessionDuration(p, input.Duration)
+	if err := validateSessionDuration(p, duration); err != nil {
+		return nil, err
+	}
+
+	mfaSerialNumber := input.SerialNumber
+	if len(mfaSerialNumber) == 0 {
+		mfaSerialNumber = p.mfaSerial()
+	}
+
+	key, err := newSessionKey(input.CredentialsFile, input.ConfigFile, profileName, mfaSerialNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	//the cache is read before IAM or STS are called, so a cached session needs neither the network nor the long term keys
+	if !input.Force {
+		cached, err := readCachedCredentials(key, input.MinimumLifetime, duration)
+		if err != nil {
+			return nil, err
+		}
+		if cached != nil {
+			return cached, nil
+		}
+	}
+
 	iamInstance := iam.New(awsSession)
 
-	mfaSerialNumber, err := getIAMUserMFADevice(iamInstance)
+	//a pinned device is left to STS to check when the user is not allowed iam:ListMFADevices, as role users often are not
+	verified := true
+	if len(mfaSerialNumber) == 0 {
+		mfaSerialNumber, err = getIAMUserMFADevice(iamInstance, input.Requests, "", input.SelectMFADevice)
+		if err != nil {
+			return nil, err
+		}
+	} else if _, err := getIAMUserMFADevice(iamInstance, input.Requests, mfaSerialNumber, nil); err != nil {
+		var requestErr *RequestError
+		if !errors.As(err, &requestErr) || requestErr.Code != errCodeAccessDenied {
+			return nil, err
+		}
+		verified = false
+	}
+
+	//only the first role is assumed by the IAM user, chained roles are limited to a session shorter than any MaxSessionDuration
+	if p.isRole() {
+		roles := p.roles()
+		if err := checkIAMRoleSessionDuration(iamInstance, input.Requests, roles[0].RoleARN, roleSessionDuration(roles, 0, duration)); err != nil {
+			return nil, err
+		}
+	}
+
+	tokenCode, generated := input.TokenCode, input.TokenGenerated
+	if len(tokenCode) == 0 && input.GeneratedTokenProvider != nil {
+		tokenCode, err = input.GeneratedTokenProvider()
+		if err != nil {
+			return nil, err
+		}
+		generated = len(tokenCode) != 0
+	}
+	if len(tokenCode) == 0 && input.TokenProvider != nil {
+		tokenCode, err = input.TokenProvider()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	//a malformed value never reaches STS, so any ErrInvalidToken below means the device's value was rejected
+	if err := ValidateToken(tokenCode); err != nil {
+		return nil, err
+	}
+
+	var creds *Credentials
+	if p.isRole() {
+		creds, err = generateRoleCredentials(awsSession, input.Endpoints, input.Requests, p.roles(), tokenCode, mfaSerialNumber, duration)
+	} else {
+		creds, err = generateSessionCredentials(sts.New(awsSession), input.Requests, tokenCode, mfaSerialNumber, duration)
+	}
+	//resyncing cannot help when the value was generated here, or the device may not be the user's
+	if errors.Is(err, ErrInvalidToken) && !generated && verified {
+		if failures, ferr := recordTokenFailure(mfaSerialNumber, time.Now()); ferr == nil && failures >= resyncTokenFailures {
+			return nil, fmt.Errorf("%w, %d values in a row have been rejected for device %s. If its clock has drifted, resync it with mfa4aws mfa resync --serial %s",
+				err, failures, mfaSerialNumber, mfaSerialNumber)
+		}
+		return nil, err
+	}
 	if err != nil {
 		return nil, err
 	}
+	_ = resetTokenFailures(mfaSerialNumber)
 
-	stsInstance := sts.New(awsSession)
-	stsSessionCredentials, err := getSTSSessionToken(stsInstance, tokenCode, mfaSerialNumber)
+	key.SerialNumber = mfaSerialNumber
+	if err := writeCachedCredentials(key, creds); err != nil {
+		return nil, err
+	}
+
+	return creds, nil
+}
+
+func generateSessionCredentials(stsInstance stsiface.STSAPI, requests RequestConfig, tokenCode string, mfaSerialNumber string,
+	duration time.Duration) (*Credentials, error) {
+
+	stsSessionCredentials, err := getSTSSessionToken(stsInstance, requests, tokenCode, mfaSerialNumber, int64(duration/time.Second))
 	if err != nil {
 		return nil, err
 	}
 
-	identity, err := getSTSIdentity(stsInstance)
+	identity, err := getSTSIdentity(stsInstance, requests)
 	if err != nil {
 		return nil, err
 	}
 
+	return newCredentials(stsSessionCredentials, identity.ARN), nil
+}
+
+//generateRoleCredentials assumes each role in turn, the first with MFA
+func generateRoleCredentials(awsSession *session.Session, endpoints EndpointConfig, requests RequestConfig, roles []*profile, tokenCode string, mfaSerialNumber string,
+	duration time.Duration) (*Credentials, error) {
+
+	var creds *Credentials
+	for i, role := range roles {
+		stsInstance := sts.New(awsSession)
+		roleTokenCode, roleMFASerialNumber := tokenCode, mfaSerialNumber
+		if i > 0 {
+			config, err := sessionEndpoints(endpoints, role, mfaSerialNumber)
+			if err != nil {
+				return nil, err
+			}
+			stsInstance = sts.New(newSession(credentials.NewStaticCredentials(
+				creds.AWSAccessKeyID, creds.AWSSecretAccessKey, creds.AWSSessionToken), config, requests))
+			roleTokenCode, roleMFASerialNumber = "", ""
+		}
+
+		durationSeconds := int64(roleSessionDuration(roles, i, duration) / time.Second)
+		stsRoleCredentials, assumedRoleUser, err := assumeRole(stsInstance, requests, role, roleTokenCode, roleMFASerialNumber, durationSeconds)
+		if err != nil {
+			return nil, err
+		}
+		creds = newCredentials(stsRoleCredentials, *assumedRoleUser.Arn)
+	}
+
+	return creds, nil
+}
+
+func newCredentials(stsCredentials *sts.Credentials, principalARN string) *Credentials {
 	return &Credentials{
-		AWSAccessKeyID:     *stsSessionCredentials.AccessKeyId,
-		AWSSecretAccessKey: *stsSessionCredentials.SecretAccessKey,
-		AWSSessionToken:    *stsSessionCredentials.SessionToken,
-		AWSSecurityToken:   *stsSessionCredentials.SessionToken,
-		PrincipalARN:       identity.ARN,
-	}, nil
+		AWSAccessKeyID:     *stsCredentials.AccessKeyId,
+		AWSSecretAccessKey: *stsCredentials.SecretAccessKey,
+		AWSSessionToken:    *stsCredentials.SessionToken,
+		AWSSecurityToken:   *stsCredentials.SessionToken,
+		PrincipalARN:       principalARN,
+		Expiration:         *stsCredentials.Expiration,
+	}
 }
@@ -1,24 +1,32 @@
 package aws
 
 import (
+	"errors"
+	"fmt"
+	"mfa4aws/internal/pkg/appfs"
+	"net/http"
+	"net/http/httptest"
 	"os/user"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/aws/aws-sdk-go/service/sts"
 	"github.com/spf13/afero"
 )
 
 func init() {
-	appFs = afero.NewMemMapFs()
+	appfs.Fs = afero.NewMemMapFs()
 
 	//Known Path
-	err := afero.WriteFile(appFs, "/knowntestfile.txt", []byte(`test`), 0644)
+	err := afero.WriteFile(appfs.Fs, "/knowntestfile.txt", []byte(`test`), 0644)
 	if err != nil {
 		panic(err)
 	}
 
-	err = afero.WriteFile(appFs, "/emptyknowntestfile.txt", []byte(nil), 0000)
+	err = afero.WriteFile(appfs.Fs, "/emptyknowntestfile.txt", []byte(nil), 0000)
 	if err != nil {
 		panic(err)
 	}
@@ -28,12 +36,12 @@ func init() {
 	path := filepath.Join(user.HomeDir, ".aws")
 	credentialsFile := filepath.Join(path, "credentials")
 
-	err = appFs.MkdirAll(path, 0755)
+	err = appfs.Fs.MkdirAll(path, 0755)
 	if err != nil {
 		panic(err)
 	}
 
-	err = afero.WriteFile(appFs, credentialsFile, []byte(`
+	err = afero.WriteFile(appfs.Fs, credentialsFile, []byte(`
 	[default]
 	aws_access_key_id = blahblah
 	aws_secret_access_key = blahblah/blahblah`), 0644)
@@ -101,7 +109,10 @@ func TestGenerateSTSCredentials(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := GenerateSTSCredentials(tt.args.profile, tt.args.tokenCode)
+			got, err := GenerateSTSCredentials(&STSCredentialsInput{
+				Profile:   tt.args.profile,
+				TokenCode: tt.args.tokenCode,
+			})
 			if (err != nil) != tt.wantErr {
 				t.Errorf("GenerateSTSCredentials() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -112,3 +123,193 @@ func TestGenerateSTSCredentials(t *testing.T) {
 		})
 	}
 }
+
+func TestGenerateSTSCredentialsCached(t *testing.T) {
+	const credentialsFile = "/cached/credentials"
+	if err := afero.WriteFile(appfs.Fs, credentialsFile, []byte(`
+[cached-session]
+aws_access_key_id = blahblah
+aws_secret_access_key = blahblah/blahblah`), 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	cached := &Credentials{
+		AWSAccessKeyID: "ASIACACHED",
+		Expiration:     time.Now().Add(time.Hour).UTC().Round(time.Second),
+	}
+	key, err := newSessionKey(credentialsFile, "/cached/config", "cached-session", "arn:aws:iam::123456789012:mfa/johnsmith")
+	if err != nil {
+		t.Fatalf("newSessionKey() error = %v", err)
+	}
+	if err := writeCachedCredentials(key, cached); err != nil {
+		t.Fatalf("writeCachedCredentials() error = %v", err)
+	}
+
+	//IAM and STS are unreachable, so only a session read from the cache can be returned
+	input := &STSCredentialsInput{
+		Profile:         "cached-session",
+		MinimumLifetime: 5 * time.Minute,
+		ProfileSource: ProfileSource{
+			CredentialsFile: credentialsFile,
+			ConfigFile:      "/cached/config",
+			Endpoints:       EndpointConfig{Region: "us-east-1", STSEndpoint: "http://127.0.0.1:1", IAMEndpoint: "http://127.0.0.1:1"},
+			Requests:        RequestConfig{MaxRetries: 0},
+		},
+	}
+	got, err := GenerateSTSCredentials(input)
+	if err != nil {
+		t.Fatalf("GenerateSTSCredentials() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, cached) {
+		t.Errorf("GenerateSTSCredentials() = %v, want the cached session %v", got, cached)
+	}
+
+	//the cached session would outlast a shorter requested duration
+	input.Duration = MinSessionDuration
+	if got, err := GenerateSTSCredentials(input); err == nil {
+		t.Errorf("GenerateSTSCredentials() = %v, want no cached session for a shorter duration", got)
+	}
+	input.Duration = 0
+
+	//a profile of the same name in another credentials file may belong to another account
+	const otherCredentialsFile = "/other/credentials"
+	if err := afero.WriteFile(appfs.Fs, otherCredentialsFile, []byte(`
+[cached-session]
+aws_access_key_id = blahblah
+aws_secret_access_key = blahblah/blahblah`), 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	input.CredentialsFile = otherCredentialsFile
+	if got, err := GenerateSTSCredentials(input); err == nil {
+		t.Errorf("GenerateSTSCredentials() = %v, want no session cached for another credentials file", got)
+	}
+}
+
+//newFakeIAM returns a fake IAM listing serialNumbers as the user's MFA devices, or failing with code when given
+func newFakeIAM(code string, serialNumbers ...string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(code) != 0 {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprintf(w, `<ErrorResponse><Error><Type>Sender</Type><Code>%s</Code><Message>Failed</Message></Error></ErrorResponse>`, code)
+			return
+		}
+
+		fmt.Fprint(w, `<ListMFADevicesResponse><ListMFADevicesResult><MFADevices>`)
+		for _, serialNumber := range serialNumbers {
+			fmt.Fprintf(w, `<member><UserName>johnsmith</UserName><SerialNumber>%s</SerialNumber><EnableDate>2020-08-01T12:00:00Z</EnableDate></member>`, serialNumber)
+		}
+		fmt.Fprint(w, `</MFADevices><IsTruncated>false</IsTruncated></ListMFADevicesResult></ListMFADevicesResponse>`)
+	}))
+}
+
+func TestGenerateSTSCredentialsPinned(t *testing.T) {
+	const pinned = "arn:aws:iam::123456789012:mfa/pinned"
+
+	tests := []struct {
+		name      string
+		iam       *httptest.Server
+		wantCalls int32
+		wantErr   bool
+	}{
+		{"Valid/Registered", newFakeIAM("", "arn:aws:iam::123456789012:mfa/other", pinned), 2, false},
+		{"Valid/ListMFADevicesDenied", newFakeIAM(errCodeAccessDenied), 2, false},
+		{"Invalid/NotRegistered", newFakeIAM("", "arn:aws:iam::123456789012:mfa/other"), 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer tt.iam.Close()
+			server, calls := newFakeSTS(0, "", 0)
+			defer server.Close()
+
+			got, err := GenerateSTSCredentials(&STSCredentialsInput{
+				Profile:      "default",
+				TokenCode:    "123456",
+				SerialNumber: pinned,
+				Force:        true,
+				ProfileSource: ProfileSource{
+					Endpoints: EndpointConfig{Region: "us-east-1", STSEndpoint: server.URL, IAMEndpoint: tt.iam.URL},
+				},
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GenerateSTSCredentials() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got.AWSAccessKeyID != "ASIAEXAMPLE" {
+				t.Errorf("GenerateSTSCredentials() = %v, want the session issued by STS", got)
+			}
+			if *calls != tt.wantCalls {
+				t.Errorf("GenerateSTSCredentials() called STS %d times, want %d", *calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestGenerateSTSCredentialsResyncHint(t *testing.T) {
+	server, _ := newFakeSTS(100, sts.ErrCodeInvalidIdentityTokenException, 0)
+	defer server.Close()
+	iamServer := newFakeIAM("", "arn:aws:iam::123456789012:mfa/entered", "arn:aws:iam::123456789012:mfa/generated", "arn:aws:iam::123456789012:mfa/client")
+	defer iamServer.Close()
+	deniedIAMServer := newFakeIAM(errCodeAccessDenied)
+	defer deniedIAMServer.Close()
+
+	token := func() (string, error) { return "123456", nil }
+	tests := []struct {
+		name     string
+		input    STSCredentialsInput
+		iam      *httptest.Server
+		wantHint bool
+	}{
+		{"Valid/Entered", STSCredentialsInput{TokenProvider: token, SerialNumber: "arn:aws:iam::123456789012:mfa/entered"}, iamServer, true},
+		{"Valid/Generated", STSCredentialsInput{GeneratedTokenProvider: token, SerialNumber: "arn:aws:iam::123456789012:mfa/generated"}, iamServer, false},
+		{"Valid/GeneratedByClient", STSCredentialsInput{TokenCode: "123456", TokenGenerated: true, SerialNumber: "arn:aws:iam::123456789012:mfa/client"}, iamServer, false},
+		{"Valid/Unverified", STSCredentialsInput{TokenProvider: token, SerialNumber: "arn:aws:iam::123456789012:mfa/unverified"}, deniedIAMServer, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := tt.input
+			input.Profile, input.Force = "default", true
+			input.Endpoints = EndpointConfig{Region: "us-east-1", STSEndpoint: server.URL, IAMEndpoint: tt.iam.URL}
+
+			var err error
+			for i := 0; i < resyncTokenFailures; i++ {
+				_, err = GenerateSTSCredentials(&input)
+			}
+			if !errors.Is(err, ErrInvalidToken) {
+				t.Fatalf("GenerateSTSCredentials() error = %v, want ErrInvalidToken", err)
+			}
+			if gotHint := strings.Contains(err.Error(), "mfa resync"); gotHint != tt.wantHint {
+				t.Errorf("GenerateSTSCredentials() error = %v, want resync hint %v", err, tt.wantHint)
+			}
+		})
+	}
+}
+
+func TestCredentialsAccountID(t *testing.T) {
+	tests := []struct {
+		name  string
+		creds *Credentials
+		want  string
+	}{
+		{
+			"Valid/User",
+			&Credentials{PrincipalARN: "arn:aws:iam::162171167783:user/johnsmith"},
+			"162171167783",
+		},
+		{
+			"Valid/AssumedRole",
+			&Credentials{PrincipalARN: "arn:aws:sts::210987654321:assumed-role/admin/johnsmith"},
+			"210987654321",
+		},
+		{
+			"Invalid/NotAnARN",
+			&Credentials{PrincipalARN: "162171167783:user/johnsmith"},
+			"",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.creds.AccountID(); got != tt.want {
+				t.Errorf("AccountID() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
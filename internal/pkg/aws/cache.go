@@ -0,0 +1,192 @@
+package aws
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"mfa4aws/internal/pkg/appfs"
+	"os"
+	"os/user"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+const (
+	cacheFolder   string = ".aws/mfa4aws/cache"
+	cacheFileExt  string = ".json"
+	cacheFileMode        = 0600
+	cacheDirMode         = 0700
+
+	//cachedSessionDurationSlack allows for the seconds between requesting a session and STS issuing it
+	cachedSessionDurationSlack = time.Minute
+)
+
+//sessionKey identifies the sessions issued for a profile of a credentials and config file and an MFA device
+type sessionKey struct {
+	CredentialsFile string `json:"credentials_file"`
+	ConfigFile      string `json:"config_file"`
+	Profile         string `json:"profile"`
+	SerialNumber    string `json:"serial_number"`
+}
+
+//newSessionKey returns the key of the sessions of profile read from the resolved credentials and config files
+func newSessionKey(credentialsPath string, configPath string, profile string, serialNumber string) (sessionKey, error) {
+	credentialsPath, configPath, err := ProfileFiles(credentialsPath, configPath)
+	if err != nil {
+		return sessionKey{}, err
+	}
+	return sessionKey{CredentialsFile: credentialsPath, ConfigFile: configPath, Profile: profile, SerialNumber: serialNumber}, nil
+}
+
+//cachedSession represents a set of STS credentials issued for a sessionKey
+type cachedSession struct {
+	sessionKey
+	Credentials *Credentials `json:"credentials"`
+}
+
+func cacheDir() (string, error) {
+	user, err := user.Current()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(user.HomeDir, cacheFolder), nil
+}
+
+func cacheKey(key sessionKey) string {
+	sum := sha256.Sum256([]byte(key.CredentialsFile + "\x00" + key.ConfigFile + "\x00" + key.Profile + "\x00" + key.SerialNumber))
+	return hex.EncodeToString(sum[:])
+}
+
+func cachePath(key sessionKey) (string, error) {
+	dir, err := cacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, cacheKey(key)+cacheFileExt), nil
+}
+
+//readCachedCredentials returns the cached credentials for key lasting from minLifetime to maxLifetime, otherwise nil
+func readCachedCredentials(key sessionKey, minLifetime time.Duration, maxLifetime time.Duration) (*Credentials, error) {
+	var session *cachedSession
+	if len(key.SerialNumber) == 0 {
+		var err error
+		session, err = findCachedSession(func(session *cachedSession) bool {
+			return session.CredentialsFile == key.CredentialsFile && session.ConfigFile == key.ConfigFile && session.Profile == key.Profile
+		})
+		if err != nil || session == nil {
+			return nil, err
+		}
+	} else {
+		path, err := cachePath(key)
+		if err != nil {
+			return nil, err
+		}
+
+		f, err := openFile(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil, nil
+			}
+			return nil, err
+		}
+
+		session = &cachedSession{}
+		if err := json.Unmarshal(f, session); err != nil || session.Credentials == nil {
+			return nil, nil
+		}
+	}
+
+	remaining := time.Until(session.Credentials.Expiration)
+	if remaining < minLifetime {
+		return nil, nil
+	}
+	//a session issued for a longer duration than the one requested must not outlive it, zero leaving it to STS
+	if maxLifetime != 0 && remaining > maxLifetime+cachedSessionDurationSlack {
+		return nil, nil
+	}
+
+	return session.Credentials, nil
+}
+
+//writeCachedCredentials stores the credentials for key readable only by the current user
+func writeCachedCredentials(key sessionKey, creds *Credentials) error {
+	path, err := cachePath(key)
+	if err != nil {
+		return err
+	}
+
+	if err := appfs.Fs.MkdirAll(filepath.Dir(path), cacheDirMode); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(&cachedSession{
+		sessionKey:  key,
+		Credentials: creds,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := afero.WriteFile(appfs.Fs, path, data, cacheFileMode); err != nil {
+		return err
+	}
+
+	return appfs.Fs.Chmod(path, cacheFileMode)
+}
+
+//findCachedSession returns the cached session matching match which expires last, or nil when none does
+func findCachedSession(match func(session *cachedSession) bool) (*cachedSession, error) {
+	sessions, err := readCachedSessions()
+	if err != nil {
+		return nil, err
+	}
+
+	var found *cachedSession
+	for _, session := range sessions {
+		if !match(session) {
+			continue
+		}
+		if found == nil || session.Credentials.Expiration.After(found.Credentials.Expiration) {
+			found = session
+		}
+	}
+	return found, nil
+}
+
+//readCachedSessions returns every readable cached session keyed by the path of its file
+func readCachedSessions() (map[string]*cachedSession, error) {
+	dir, err := cacheDir()
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := afero.ReadDir(appfs.Fs, dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	sessions := map[string]*cachedSession{}
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != cacheFileExt {
+			continue
+		}
+
+		path := filepath.Join(dir, file.Name())
+		data, err := openFile(path)
+		if err != nil {
+			continue
+		}
+
+		session := &cachedSession{}
+		if err := json.Unmarshal(data, session); err != nil || session.Credentials == nil {
+			continue
+		}
+		sessions[path] = session
+	}
+	return sessions, nil
+}
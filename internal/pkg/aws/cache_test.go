@@ -0,0 +1,200 @@
+package aws
+
+import (
+	"mfa4aws/internal/pkg/appfs"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_cacheKey(t *testing.T) {
+	key := sessionKey{
+		CredentialsFile: "/home/johnsmith/.aws/credentials",
+		ConfigFile:      "/home/johnsmith/.aws/config",
+		Profile:         "default",
+		SerialNumber:    "arn:aws:iam::123456789012:mfa/johnsmith",
+	}
+
+	other := key
+	other.Profile = "work"
+	if cacheKey(key) == cacheKey(other) {
+		t.Errorf("cacheKey() is equal for different profiles")
+	}
+
+	other = key
+	other.SerialNumber = "arn:aws:iam::123456789012:mfa/backup"
+	if cacheKey(key) == cacheKey(other) {
+		t.Errorf("cacheKey() is equal for different MFA devices")
+	}
+
+	other = key
+	other.CredentialsFile = "/home/johnsmith/work/credentials"
+	if cacheKey(key) == cacheKey(other) {
+		t.Errorf("cacheKey() is equal for different credentials files")
+	}
+
+	other = key
+	other.ConfigFile = "/home/johnsmith/work/config"
+	if cacheKey(key) == cacheKey(other) {
+		t.Errorf("cacheKey() is equal for different config files")
+	}
+}
+
+func Test_readCachedCredentials(t *testing.T) {
+	valid := &Credentials{
+		AWSAccessKeyID:     "ASIAVALID",
+		AWSSecretAccessKey: "blahblah/blahblah",
+		AWSSessionToken:    "FQoGZXIvYshgsSJHIOSLKj6nr0FOKIuOP68yKRKvPp3nj9MyaPcvN8PApmWd3yKuTJWf",
+		AWSSecurityToken:   "FQoGZXIvYshgsSJHIOSLKj6nr0FOKIuOP68yKRKvPp3nj9MyaPcvN8PApmWd3yKuTJWf",
+		PrincipalARN:       "arn:aws:iam::123456789012:user/johnsmith",
+		Expiration:         time.Now().Add(time.Hour).UTC().Round(time.Second),
+	}
+	expiring := &Credentials{
+		AWSAccessKeyID: "ASIAEXPIRING",
+		Expiration:     time.Now().Add(time.Minute).UTC().Round(time.Second),
+	}
+
+	if err := writeCachedCredentials(sessionKey{Profile: "cache-valid", SerialNumber: "arn:aws:iam::123456789012:mfa/johnsmith"}, valid); err != nil {
+		t.Fatalf("writeCachedCredentials() error = %v", err)
+	}
+	if err := writeCachedCredentials(sessionKey{Profile: "cache-expiring", SerialNumber: "arn:aws:iam::123456789012:mfa/johnsmith"}, expiring); err != nil {
+		t.Fatalf("writeCachedCredentials() error = %v", err)
+	}
+
+	type args struct {
+		profile      string
+		serialNumber string
+		minLifetime  time.Duration
+		maxLifetime  time.Duration
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    *Credentials
+		wantErr bool
+	}{
+		{
+			"Valid/CachedSession",
+			args{
+				profile:      "cache-valid",
+				serialNumber: "arn:aws:iam::123456789012:mfa/johnsmith",
+				minLifetime:  5 * time.Minute,
+			},
+			valid,
+			false,
+		},
+		{
+			"Valid/LongerDuration",
+			args{
+				profile:      "cache-valid",
+				serialNumber: "arn:aws:iam::123456789012:mfa/johnsmith",
+				minLifetime:  5 * time.Minute,
+				maxLifetime:  4 * time.Hour,
+			},
+			valid,
+			false,
+		},
+		{
+			"Valid/ShorterDuration",
+			args{
+				profile:      "cache-valid",
+				serialNumber: "arn:aws:iam::123456789012:mfa/johnsmith",
+				minLifetime:  5 * time.Minute,
+				maxLifetime:  30 * time.Minute,
+			},
+			nil,
+			false,
+		},
+		{
+			"Valid/NoCachedSession",
+			args{
+				profile:      "cache-unknown",
+				serialNumber: "arn:aws:iam::123456789012:mfa/johnsmith",
+				minLifetime:  5 * time.Minute,
+			},
+			nil,
+			false,
+		},
+		{
+			"Valid/DifferentDevice",
+			args{
+				profile:      "cache-valid",
+				serialNumber: "arn:aws:iam::123456789012:mfa/backup",
+				minLifetime:  5 * time.Minute,
+			},
+			nil,
+			false,
+		},
+		{
+			"Valid/AnyDevice",
+			args{
+				profile:      "cache-valid",
+				serialNumber: "",
+				minLifetime:  5 * time.Minute,
+			},
+			valid,
+			false,
+		},
+		{
+			"Valid/AnyDeviceNoCachedSession",
+			args{
+				profile:      "cache-unknown",
+				serialNumber: "",
+				minLifetime:  5 * time.Minute,
+			},
+			nil,
+			false,
+		},
+		{
+			"Valid/BelowMinimumLifetime",
+			args{
+				profile:      "cache-expiring",
+				serialNumber: "arn:aws:iam::123456789012:mfa/johnsmith",
+				minLifetime:  5 * time.Minute,
+			},
+			nil,
+			false,
+		},
+		{
+			"Valid/AboveMinimumLifetime",
+			args{
+				profile:      "cache-expiring",
+				serialNumber: "arn:aws:iam::123456789012:mfa/johnsmith",
+				minLifetime:  0,
+			},
+			expiring,
+			false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readCachedCredentials(sessionKey{Profile: tt.args.profile, SerialNumber: tt.args.serialNumber}, tt.args.minLifetime, tt.args.maxLifetime)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("readCachedCredentials() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readCachedCredentials() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_writeCachedCredentials(t *testing.T) {
+	if err := writeCachedCredentials(sessionKey{Profile: "cache-mode", SerialNumber: "arn:aws:iam::123456789012:mfa/johnsmith"}, &Credentials{}); err != nil {
+		t.Fatalf("writeCachedCredentials() error = %v", err)
+	}
+
+	path, err := cachePath(sessionKey{Profile: "cache-mode", SerialNumber: "arn:aws:iam::123456789012:mfa/johnsmith"})
+	if err != nil {
+		t.Fatalf("cachePath() error = %v", err)
+	}
+
+	info, err := appfs.Fs.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if info.Mode().Perm() != cacheFileMode {
+		t.Errorf("writeCachedCredentials() mode = %v, want %v", info.Mode().Perm(), cacheFileMode)
+	}
+}
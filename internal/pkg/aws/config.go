@@ -0,0 +1,56 @@
+package aws
+
+import (
+	"os"
+	"os/user"
+	"path/filepath"
+)
+
+const (
+	configProfilePrefix string = "profile "
+
+	configAWSAccessKeyID     string = "aws_access_key_id"
+	configAWSSecretAccessKey string = "aws_secret_access_key"
+	configRegion             string = "region"
+	configRoleARN            string = "role_arn"
+	configSourceProfile      string = "source_profile"
+	configMFASerial          string = "mfa_serial"
+	configRoleSessionName    string = "role_session_name"
+	configDurationSeconds    string = "duration_seconds"
+	configExternalID         string = "external_id"
+
+	configSTSRegionalEndpoints string = "sts_regional_endpoints"
+	configUseFIPSEndpoint      string = "use_fips_endpoint"
+	configUseDualStackEndpoint string = "use_dualstack_endpoint"
+	configSTSEndpoint          string = "sts_endpoint"
+	configIAMEndpoint          string = "iam_endpoint"
+)
+
+//configFilePath returns path, or else $AWS_CONFIG_FILE or $HOME/.aws/config when path is empty
+func configFilePath(path string) (string, error) {
+	const (
+		awsConfigFolder string = ".aws"
+		awsConfigFile   string = "config"
+	)
+
+	if len(path) != 0 {
+		return path, nil
+	}
+	if path := os.Getenv(envConfigFile); len(path) != 0 {
+		return path, nil
+	}
+
+	user, err := user.Current()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(user.HomeDir, awsConfigFolder, awsConfigFile), nil
+}
+
+//configSectionName returns the section name used for profile in $HOME/.aws/config
+func configSectionName(profile string) string {
+	if profile == profileDefault {
+		return profileDefault
+	}
+	return configProfilePrefix + profile
+}
@@ -0,0 +1,352 @@
+package aws
+
+import (
+	"bytes"
+	"mfa4aws/internal/pkg/appfs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/afero"
+	"gopkg.in/ini.v1"
+)
+
+const (
+	credentialsFileMode = 0600
+	credentialsDirMode  = 0700
+)
+
+var (
+	sectionHeaderRegex = regexp.MustCompile(`^\s*\[\s*([^\]]*?)\s*\]`)
+)
+
+//WriteCredentialsProfile writes the Credentials into the profile section of the shared credentials file
+func WriteCredentialsProfile(path string, profile string, creds *Credentials) error {
+	path, err := credentialsFilePath(path)
+	if err != nil {
+		return err
+	}
+
+	section, err := renderProfile(profile, creds)
+	if err != nil {
+		return err
+	}
+
+	mode := os.FileMode(credentialsFileMode)
+	existing, err := openFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if info, err := appfs.Fs.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	return writeFileAtomic(path, replaceProfile(existing, profile, section), mode)
+}
+
+//ReadCredentialsFileKeys returns the long term keys of profile in the shared credentials file
+func ReadCredentialsFileKeys(path string, profile string) (*AccessKeys, error) {
+	path, err := credentialsFilePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := openFile(path)
+	if err != nil {
+		return nil, &FileError{Path: path, Err: ErrAWSCredentialsFileNotFound}
+	}
+
+	file, err := loadProfileFile(path, data, ErrInvalidAWSCredentialsFile)
+	if err != nil {
+		return nil, err
+	}
+
+	section := file.section(profile)
+	if section == nil {
+		return nil, &ProfileError{Profile: profile, Reason: "is not defined in the AWS credentials file"}
+	}
+
+	keys := &AccessKeys{
+		AccessKeyID:     section.Key(configAWSAccessKeyID).String(),
+		SecretAccessKey: section.Key(configAWSSecretAccessKey).String(),
+	}
+	if len(keys.AccessKeyID) == 0 || len(keys.SecretAccessKey) == 0 {
+		return nil, &ProfileError{Profile: profile, Reason: "has no long term keys", Path: path, Line: file.location(profile, "")}
+	}
+	return keys, nil
+}
+
+//RemoveCredentialsFileKeys removes the long term keys from the profile section of the shared credentials file
+func RemoveCredentialsFileKeys(path string, profile string) error {
+	path, err := credentialsFilePath(path)
+	if err != nil {
+		return err
+	}
+
+	existing, err := openFile(path)
+	if err != nil {
+		return &FileError{Path: path, Err: ErrAWSCredentialsFileNotFound}
+	}
+
+	mode := os.FileMode(credentialsFileMode)
+	if info, err := appfs.Fs.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	return writeFileAtomic(path, removeProfileKeys(existing, profile, configAWSAccessKeyID, configAWSSecretAccessKey), mode)
+}
+
+//WriteCredentialsFileKeys sets the long term keys in the profile section of the shared credentials file
+func WriteCredentialsFileKeys(path string, profile string, keys *AccessKeys) error {
+	path, err := credentialsFilePath(path)
+	if err != nil {
+		return err
+	}
+
+	mode := os.FileMode(credentialsFileMode)
+	existing, err := openFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if info, err := appfs.Fs.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	return writeFileAtomic(path, setProfileKeys(existing, profile, []iniKeyValue{
+		{configAWSAccessKeyID, keys.AccessKeyID},
+		{configAWSSecretAccessKey, keys.SecretAccessKey},
+	}), mode)
+}
+
+//iniKeyValue is a key and its value in an ini section
+type iniKeyValue struct {
+	key   string
+	value string
+}
+
+//setProfileKeys returns file with values set in the profile section
+func setProfileKeys(file []byte, profile string, values []iniKeyValue) []byte {
+	lines := strings.SplitAfter(string(file), "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	set := map[string]bool{}
+	header := -1
+	inProfile := false
+	for i, line := range lines {
+		if match := sectionHeaderRegex.FindStringSubmatch(line); match != nil {
+			inProfile = match[1] == profile
+			if inProfile && header == -1 {
+				header = i
+			}
+			continue
+		}
+		match := keyLineRegex.FindStringSubmatch(line)
+		if !inProfile || match == nil {
+			continue
+		}
+		for _, x := range values {
+			if x.key == match[1] {
+				lines[i] = x.key + " = " + x.value + "\n"
+				set[x.key] = true
+			}
+		}
+	}
+
+	var missing []string
+	for _, x := range values {
+		if !set[x.key] {
+			missing = append(missing, x.key+" = "+x.value+"\n")
+		}
+	}
+
+	if header == -1 {
+		section := "[" + profile + "]\n" + strings.Join(missing, "")
+		return replaceProfile(file, profile, []byte(section))
+	}
+
+	if !strings.HasSuffix(lines[header], "\n") {
+		lines[header] += "\n"
+	}
+	out := append(append(append([]string{}, lines[:header+1]...), missing...), lines[header+1:]...)
+	if len(out) > 0 && !strings.HasSuffix(out[len(out)-1], "\n") {
+		out[len(out)-1] += "\n"
+	}
+	return []byte(strings.Join(out, ""))
+}
+
+//removeProfileKeys returns file without the lines setting any of keys in the profile section
+func removeProfileKeys(file []byte, profile string, keys ...string) []byte {
+	out := bytes.NewBuffer(nil)
+	inProfile := false
+	for _, line := range strings.SplitAfter(string(file), "\n") {
+		if match := sectionHeaderRegex.FindStringSubmatch(line); match != nil {
+			inProfile = match[1] == profile
+		} else if match := keyLineRegex.FindStringSubmatch(line); inProfile && match != nil && containsString(keys, match[1]) {
+			continue
+		}
+		out.WriteString(line)
+	}
+	return out.Bytes()
+}
+
+func containsString(values []string, value string) bool {
+	for _, x := range values {
+		if x == value {
+			return true
+		}
+	}
+	return false
+}
+
+//renderProfile serialises the Credentials as an ini section named profile
+func renderProfile(profile string, creds *Credentials) ([]byte, error) {
+	cfg := ini.Empty()
+	section, err := cfg.NewSection(profile)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := section.ReflectFrom(creds); err != nil {
+		return nil, err
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if _, err := cfg.WriteTo(buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+//replaceProfile returns file with the profile section replaced by section
+func replaceProfile(file []byte, profile string, section []byte) []byte {
+	lines := strings.SplitAfter(string(file), "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	start := -1
+	for i, line := range lines {
+		if match := sectionHeaderRegex.FindStringSubmatch(line); match != nil && match[1] == profile {
+			start = i
+			break
+		}
+	}
+
+	if start == -1 {
+		out := bytes.NewBuffer(file)
+		if len(file) > 0 {
+			if !bytes.HasSuffix(file, []byte("\n")) {
+				out.WriteString("\n")
+			}
+			out.WriteString("\n")
+		}
+		out.Write(section)
+		return out.Bytes()
+	}
+
+	end := len(lines)
+	for i := start + 1; i < len(lines); i++ {
+		if sectionHeaderRegex.MatchString(lines[i]) {
+			end = i
+			break
+		}
+	}
+
+	for end > start+1 && isCommentOrBlank(lines[end-1]) && end < len(lines) {
+		end--
+	}
+
+	out := bytes.NewBuffer(nil)
+	for _, line := range lines[:start] {
+		out.WriteString(line)
+	}
+	out.Write(section)
+	if end < len(lines) && !isBlank(lines[end]) {
+		out.WriteString("\n")
+	}
+	for _, line := range lines[end:] {
+		out.WriteString(line)
+	}
+	return out.Bytes()
+}
+
+//removeProfile returns file without the profile section
+func removeProfile(file []byte, profile string) []byte {
+	lines := strings.SplitAfter(string(file), "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	start := -1
+	for i, line := range lines {
+		if match := sectionHeaderRegex.FindStringSubmatch(line); match != nil && match[1] == profile {
+			start = i
+			break
+		}
+	}
+	if start == -1 {
+		return file
+	}
+
+	end := len(lines)
+	for i := start + 1; i < len(lines); i++ {
+		if sectionHeaderRegex.MatchString(lines[i]) {
+			end = i
+			break
+		}
+	}
+
+	for end > start+1 && isCommentOrBlank(lines[end-1]) && end < len(lines) {
+		end--
+	}
+
+	if start > 0 && isBlank(lines[start-1]) && (end == len(lines) || isBlank(lines[end])) {
+		start--
+	}
+
+	out := bytes.NewBuffer(nil)
+	for _, line := range append(lines[:start:start], lines[end:]...) {
+		out.WriteString(line)
+	}
+	return out.Bytes()
+}
+
+func isBlank(line string) bool {
+	return len(strings.TrimSpace(line)) == 0
+}
+
+func isCommentOrBlank(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return isBlank(trimmed) || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, ";")
+}
+
+//writeFileAtomic writes data to a temporary file alongside path and renames it into place
+func writeFileAtomic(path string, data []byte, mode os.FileMode) error {
+	dir := filepath.Dir(path)
+	if err := appfs.Fs.MkdirAll(dir, credentialsDirMode); err != nil {
+		return err
+	}
+
+	tmp, err := afero.TempFile(appfs.Fs, dir, "."+filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	defer appfs.Fs.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := appfs.Fs.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+
+	return appfs.Fs.Rename(tmp.Name(), path)
+}
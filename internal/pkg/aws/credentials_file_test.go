@@ -0,0 +1,269 @@
+package aws
+
+import (
+	"mfa4aws/internal/pkg/appfs"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+func Test_replaceProfile(t *testing.T) {
+	section := []byte("[work-mfa]\naws_access_key_id = ASIANEW\n")
+
+	type args struct {
+		file    string
+		profile string
+	}
+	tests := []struct {
+		name string
+		args args
+		want string
+	}{
+		{
+			"Valid/EmptyFile",
+			args{
+				file:    "",
+				profile: "work-mfa",
+			},
+			"[work-mfa]\naws_access_key_id = ASIANEW\n",
+		},
+		{
+			"Valid/AppendProfile",
+			args{
+				file:    "# my keys\n[work]\naws_access_key_id = AKIAWORK\n",
+				profile: "work-mfa",
+			},
+			"# my keys\n[work]\naws_access_key_id = AKIAWORK\n\n[work-mfa]\naws_access_key_id = ASIANEW\n",
+		},
+		{
+			"Valid/AppendProfileNoTrailingNewline",
+			args{
+				file:    "[work]\naws_access_key_id = AKIAWORK",
+				profile: "work-mfa",
+			},
+			"[work]\naws_access_key_id = AKIAWORK\n\n[work-mfa]\naws_access_key_id = ASIANEW\n",
+		},
+		{
+			"Valid/ReplaceLastProfile",
+			args{
+				file:    "[work]\naws_access_key_id = AKIAWORK\n\n[work-mfa]\naws_access_key_id = ASIAOLD\naws_session_token = OLD\n",
+				profile: "work-mfa",
+			},
+			"[work]\naws_access_key_id = AKIAWORK\n\n[work-mfa]\naws_access_key_id = ASIANEW\n",
+		},
+		{
+			"Valid/ReplaceProfileKeepsFollowingSections",
+			args{
+				file:    "[work-mfa]\naws_access_key_id = ASIAOLD\n\n; personal account\n[home]\naws_access_key_id = AKIAHOME\n",
+				profile: "work-mfa",
+			},
+			"[work-mfa]\naws_access_key_id = ASIANEW\n\n; personal account\n[home]\naws_access_key_id = AKIAHOME\n",
+		},
+		{
+			"Valid/ReplaceProfileNoBlankLineBeforeNextSection",
+			args{
+				file:    "[work-mfa]\naws_access_key_id = ASIAOLD\n[home]\naws_access_key_id = AKIAHOME\n",
+				profile: "work-mfa",
+			},
+			"[work-mfa]\naws_access_key_id = ASIANEW\n\n[home]\naws_access_key_id = AKIAHOME\n",
+		},
+		{
+			"Valid/SimilarProfileName",
+			args{
+				file:    "[work-mfa-old]\naws_access_key_id = AKIAOLD\n",
+				profile: "work-mfa",
+			},
+			"[work-mfa-old]\naws_access_key_id = AKIAOLD\n\n[work-mfa]\naws_access_key_id = ASIANEW\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(replaceProfile([]byte(tt.args.file), tt.args.profile, section)); got != tt.want {
+				t.Errorf("replaceProfile() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteCredentialsProfile(t *testing.T) {
+	const path = "/writecredentials/credentials"
+
+	err := afero.WriteFile(appfs.Fs, path, []byte("# long term keys\n[work]\naws_access_key_id = AKIAWORK\naws_secret_access_key = blahblah\n"), 0600)
+	if err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	creds := &Credentials{
+		AWSAccessKeyID:     "ASIANEW",
+		AWSSecretAccessKey: "blahblah/blahblah",
+		AWSSessionToken:    "FQoGZXIv",
+		AWSSecurityToken:   "FQoGZXIv",
+		PrincipalARN:       "arn:aws:iam::123456789012:user/johnsmith",
+		Expiration:         time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := WriteCredentialsProfile(path, "work-mfa", creds); err != nil {
+			t.Fatalf("WriteCredentialsProfile() error = %v", err)
+		}
+	}
+
+	got, err := openFile(path)
+	if err != nil {
+		t.Fatalf("openFile() error = %v", err)
+	}
+
+	want := `# long term keys
+[work]
+aws_access_key_id = AKIAWORK
+aws_secret_access_key = blahblah
+
+[work-mfa]
+aws_access_key_id     = ASIANEW
+aws_secret_access_key = blahblah/blahblah
+aws_session_token     = FQoGZXIv
+aws_security_token    = FQoGZXIv
+x_principal_arn       = arn:aws:iam::123456789012:user/johnsmith
+x_expiration          = 2020-01-02T03:04:05Z
+`
+	if string(got) != want {
+		t.Errorf("WriteCredentialsProfile() = %q, want %q", got, want)
+	}
+
+	info, err := appfs.Fs.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if info.Mode().Perm() != credentialsFileMode {
+		t.Errorf("WriteCredentialsProfile() mode = %v, want %v", info.Mode().Perm(), credentialsFileMode)
+	}
+}
+
+func Test_removeProfileKeys(t *testing.T) {
+	file := "# long term keys\n[work]\naws_access_key_id = AKIAWORK\n; rotated yearly\naws_secret_access_key=blahblah\nregion = eu-west-1\n\n[home]\naws_access_key_id = AKIAHOME\naws_secret_access_key = blahblah\n"
+	want := "# long term keys\n[work]\n; rotated yearly\nregion = eu-west-1\n\n[home]\naws_access_key_id = AKIAHOME\naws_secret_access_key = blahblah\n"
+
+	if got := string(removeProfileKeys([]byte(file), "work", configAWSAccessKeyID, configAWSSecretAccessKey)); got != want {
+		t.Errorf("removeProfileKeys() = %q, want %q", got, want)
+	}
+}
+
+func Test_removeProfile(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		want string
+	}{
+		{
+			"Valid/MiddleSection",
+			"[work]\naws_access_key_id = AKIAWORK\n\n[work-mfa]\naws_session_token = blah\n\n# home account\n[home]\naws_access_key_id = AKIAHOME\n",
+			"[work]\naws_access_key_id = AKIAWORK\n\n# home account\n[home]\naws_access_key_id = AKIAHOME\n",
+		},
+		{
+			"Valid/LastSection",
+			"[work]\naws_access_key_id = AKIAWORK\n\n[work-mfa]\naws_session_token = blah\n",
+			"[work]\naws_access_key_id = AKIAWORK\n",
+		},
+		{
+			"Valid/OnlySection",
+			"[work-mfa]\naws_session_token = blah\n",
+			"",
+		},
+		{
+			"Valid/MissingSection",
+			"[work]\naws_access_key_id = AKIAWORK\n",
+			"[work]\naws_access_key_id = AKIAWORK\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(removeProfile([]byte(tt.file), "work-mfa")); got != tt.want {
+				t.Errorf("removeProfile() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_setProfileKeys(t *testing.T) {
+	values := []iniKeyValue{{configAWSAccessKeyID, "AKIANEW"}, {configAWSSecretAccessKey, "newsecret"}}
+
+	tests := []struct {
+		name string
+		file string
+		want string
+	}{
+		{
+			"Valid/ReplaceKeys",
+			"[home]\naws_access_key_id = AKIAHOME\n\n[work]\n# rotated yearly\naws_access_key_id=AKIAWORK\nregion = eu-west-1\naws_secret_access_key = blahblah\n",
+			"[home]\naws_access_key_id = AKIAHOME\n\n[work]\n# rotated yearly\naws_access_key_id = AKIANEW\nregion = eu-west-1\naws_secret_access_key = newsecret\n",
+		},
+		{
+			"Valid/AddMissingKeys",
+			"[work]\nregion = eu-west-1",
+			"[work]\naws_access_key_id = AKIANEW\naws_secret_access_key = newsecret\nregion = eu-west-1\n",
+		},
+		{
+			"Valid/AppendProfile",
+			"[home]\naws_access_key_id = AKIAHOME\n",
+			"[home]\naws_access_key_id = AKIAHOME\n\n[work]\naws_access_key_id = AKIANEW\naws_secret_access_key = newsecret\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(setProfileKeys([]byte(tt.file), "work", values)); got != tt.want {
+				t.Errorf("setProfileKeys() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCredentialsFileKeys(t *testing.T) {
+	const path = "/credentialsfilekeys/credentials"
+
+	err := afero.WriteFile(appfs.Fs, path, []byte("[work]\naws_access_key_id = AKIAWORK\naws_secret_access_key = blahblah\nregion = eu-west-1\n"), 0600)
+	if err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	keys, err := ReadCredentialsFileKeys(path, "work")
+	if err != nil {
+		t.Fatalf("ReadCredentialsFileKeys() error = %v", err)
+	}
+	if want := (&AccessKeys{AccessKeyID: "AKIAWORK", SecretAccessKey: "blahblah"}); *keys != *want {
+		t.Errorf("ReadCredentialsFileKeys() = %v, want %v", keys, want)
+	}
+
+	if _, err := ReadCredentialsFileKeys(path, "home"); err == nil {
+		t.Errorf("ReadCredentialsFileKeys() expected an error for a missing profile")
+	}
+
+	rotated := &AccessKeys{AccessKeyID: "AKIANEW", SecretAccessKey: "newsecret"}
+	if err := WriteCredentialsFileKeys(path, "work", rotated); err != nil {
+		t.Fatalf("WriteCredentialsFileKeys() error = %v", err)
+	}
+
+	keys, err = ReadCredentialsFileKeys(path, "work")
+	if err != nil {
+		t.Fatalf("ReadCredentialsFileKeys() error = %v", err)
+	}
+	if *keys != *rotated {
+		t.Errorf("ReadCredentialsFileKeys() = %v, want %v", keys, rotated)
+	}
+
+	if err := RemoveCredentialsFileKeys(path, "work"); err != nil {
+		t.Fatalf("RemoveCredentialsFileKeys() error = %v", err)
+	}
+
+	if _, err := ReadCredentialsFileKeys(path, "work"); err == nil {
+		t.Errorf("ReadCredentialsFileKeys() expected an error once the keys are removed")
+	}
+
+	got, err := afero.ReadFile(appfs.Fs, path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if want := "[work]\nregion = eu-west-1\n"; string(got) != want {
+		t.Errorf("RemoveCredentialsFileKeys() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,66 @@
+package aws
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	//MinSessionDuration is the shortest session STS issues
+	MinSessionDuration = 15 * time.Minute
+
+	//MaxSessionTokenDuration is the longest session GetSessionToken issues for an IAM user
+	MaxSessionTokenDuration = 36 * time.Hour
+
+	//MaxRoleSessionDuration is the longest session AssumeRole issues, when allowed by the role's MaxSessionDuration
+	MaxRoleSessionDuration = 12 * time.Hour
+
+	//MaxChainedRoleSessionDuration is the longest session AssumeRole issues using the credentials of another role
+	MaxChainedRoleSessionDuration = time.Hour
+)
+
+//sessionDuration returns duration when set and otherwise the profile's duration_seconds
+func sessionDuration(p *profile, duration time.Duration) time.Duration {
+	if duration != 0 {
+		return duration
+	}
+	return time.Duration(p.DurationSeconds) * time.Second
+}
+
+//validateSessionDuration checks duration is within the range STS accepts for profile p
+func validateSessionDuration(p *profile, duration time.Duration) error {
+	if !p.isRole() {
+		return checkSessionDuration(p.Name, duration, MaxSessionTokenDuration)
+	}
+
+	roles := p.roles()
+	for i, role := range roles {
+		max := MaxRoleSessionDuration
+		if i > 0 {
+			max = MaxChainedRoleSessionDuration
+		}
+		if err := checkSessionDuration(role.Name, roleSessionDuration(roles, i, duration), max); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//roleSessionDuration returns the lifetime of the session for the i-th of roles
+func roleSessionDuration(roles []*profile, i int, duration time.Duration) time.Duration {
+	if i == len(roles)-1 {
+		return duration
+	}
+	return time.Duration(roles[i].DurationSeconds) * time.Second
+}
+
+func checkSessionDuration(profileName string, duration time.Duration, max time.Duration) error {
+	if duration == 0 {
+		return nil
+	}
+	if duration < MinSessionDuration || duration > max {
+		return WithErrorCategory(fmt.Errorf("Session duration %v for profile %s must be between %v and %v", duration, profileName, MinSessionDuration, max),
+			ErrorCategoryConfig)
+	}
+	return nil
+}
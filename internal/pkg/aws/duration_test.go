@@ -0,0 +1,51 @@
+package aws
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_sessionDuration(t *testing.T) {
+	p := &profile{Name: "default", DurationSeconds: 3600}
+	if got := sessionDuration(p, 0); got != time.Hour {
+		t.Errorf("sessionDuration() = %v, want %v", got, time.Hour)
+	}
+	if got := sessionDuration(p, 4*time.Hour); got != 4*time.Hour {
+		t.Errorf("sessionDuration() = %v, want %v", got, 4*time.Hour)
+	}
+}
+
+func Test_validateSessionDuration(t *testing.T) {
+	user := &profile{Name: "default"}
+	role := &profile{Name: "prod", RoleARN: "arn:aws:iam::210987654321:role/admin", Source: user}
+	chained := &profile{Name: "prod-readonly", RoleARN: "arn:aws:iam::210987654321:role/readonly", Source: role}
+	longRole := &profile{Name: "prod", RoleARN: "arn:aws:iam::210987654321:role/admin", DurationSeconds: 86400, Source: user}
+
+	type args struct {
+		p        *profile
+		duration time.Duration
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{"Valid/Default", args{user, 0}, false},
+		{"Valid/User/Minimum", args{user, 15 * time.Minute}, false},
+		{"Valid/User/Maximum", args{user, 36 * time.Hour}, false},
+		{"Valid/Role", args{role, 12 * time.Hour}, false},
+		{"Valid/ChainedRole", args{chained, time.Hour}, false},
+		{"Invalid/User/TooShort", args{user, 10 * time.Minute}, true},
+		{"Invalid/User/TooLong", args{user, 37 * time.Hour}, true},
+		{"Invalid/Role/TooLong", args{role, 13 * time.Hour}, true},
+		{"Invalid/ChainedRole/TooLong", args{chained, 2 * time.Hour}, true},
+		{"Invalid/ChainedRole/SourceRoleTooLong", args{&profile{Name: "readonly", RoleARN: "arn:aws:iam::210987654321:role/readonly", Source: longRole}, time.Hour}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateSessionDuration(tt.args.p, tt.args.duration); (err != nil) != tt.wantErr {
+				t.Errorf("validateSessionDuration() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package aws
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+const (
+	envRegion        string = "AWS_REGION"
+	envDefaultRegion string = "AWS_DEFAULT_REGION"
+
+	//STSRegionalEndpointsLegacy sends requests from the original regions to the global STS endpoint
+	STSRegionalEndpointsLegacy string = "legacy"
+	//STSRegionalEndpointsRegional sends requests to the STS endpoint of the region
+	STSRegionalEndpointsRegional string = "regional"
+)
+
+//partitionRegions is the region used for each partition when none is configured
+var partitionRegions = map[string]string{
+	endpoints.AwsPartitionID:      endpoints.UsEast1RegionID,
+	endpoints.AwsUsGovPartitionID: endpoints.UsGovWest1RegionID,
+	endpoints.AwsCnPartitionID:    endpoints.CnNorth1RegionID,
+}
+
+//dualStackDNSSuffixes is the DNS suffix of the dual-stack endpoints of each partition
+var dualStackDNSSuffixes = map[string]string{
+	endpoints.AwsPartitionID:      "api.aws",
+	endpoints.AwsUsGovPartitionID: "api.aws",
+	endpoints.AwsCnPartitionID:    "api.amazonwebservices.com.cn",
+}
+
+//iamFIPSEndpoints is the FIPS endpoint of IAM in each partition which has one
+var iamFIPSEndpoints = map[string]string{
+	endpoints.AwsPartitionID:      "https://iam-fips.amazonaws.com",
+	endpoints.AwsUsGovPartitionID: "https://iam.us-gov.amazonaws.com",
+}
+
+//EndpointConfig selects the region and endpoints STS and IAM are called on
+type EndpointConfig struct {
+	//Region is the region requests are sent to
+	Region string
+
+	//STSRegionalEndpoints is legacy or regional, as sts_regional_endpoints in the AWS config file
+	STSRegionalEndpoints string
+
+	//UseFIPSEndpoint sends STS and IAM requests to their FIPS endpoints
+	UseFIPSEndpoint bool
+
+	//UseDualStackEndpoint sends STS requests to their dual-stack endpoints. IAM has no dual-stack endpoints
+	UseDualStackEndpoint bool
+
+	//STSEndpoint is the URL STS requests are sent to, overriding any other setting
+	STSEndpoint string
+
+	//IAMEndpoint is the URL IAM requests are sent to, overriding any other setting
+	IAMEndpoint string
+}
+
+//Merge returns c with the fields it leaves empty taken from other
+func (c EndpointConfig) Merge(other EndpointConfig) EndpointConfig {
+	if len(c.Region) == 0 {
+		c.Region = other.Region
+	}
+	if len(c.STSRegionalEndpoints) == 0 {
+		c.STSRegionalEndpoints = other.STSRegionalEndpoints
+	}
+	if len(c.STSEndpoint) == 0 {
+		c.STSEndpoint = other.STSEndpoint
+	}
+	if len(c.IAMEndpoint) == 0 {
+		c.IAMEndpoint = other.IAMEndpoint
+	}
+	c.UseFIPSEndpoint = c.UseFIPSEndpoint || other.UseFIPSEndpoint
+	c.UseDualStackEndpoint = c.UseDualStackEndpoint || other.UseDualStackEndpoint
+	return c
+}
+
+//withPartitionRegion returns c with a region from the environment or the partition of arns
+func (c EndpointConfig) withPartitionRegion(arns ...string) EndpointConfig {
+	for _, region := range []string{c.Region, os.Getenv(envRegion), os.Getenv(envDefaultRegion)} {
+		if len(region) != 0 {
+			c.Region = region
+			return c
+		}
+	}
+
+	for _, x := range arns {
+		if parsed, err := arn.Parse(x); err == nil {
+			c.Region = partitionRegions[parsed.Partition]
+			return c
+		}
+	}
+	return c
+}
+
+//validate checks the settings can be used in the partition of the region
+func (c EndpointConfig) validate() error {
+	if len(c.STSRegionalEndpoints) != 0 {
+		if _, err := endpoints.GetSTSRegionalEndpoint(c.STSRegionalEndpoints); err != nil {
+			return WithErrorCategory(fmt.Errorf("Invalid sts_regional_endpoints %s, must be %s or %s", c.STSRegionalEndpoints,
+				STSRegionalEndpointsLegacy, STSRegionalEndpointsRegional), ErrorCategoryConfig)
+		}
+	}
+
+	for _, endpoint := range []string{c.STSEndpoint, c.IAMEndpoint} {
+		if len(endpoint) == 0 {
+			continue
+		}
+		if u, err := url.Parse(endpoint); err != nil || len(u.Scheme) == 0 || len(u.Host) == 0 {
+			return WithErrorCategory(fmt.Errorf("Invalid endpoint %s, must be a URL such as https://sts.example.com", endpoint), ErrorCategoryConfig)
+		}
+	}
+
+	if c.UseFIPSEndpoint {
+		partition := regionPartition(c.Region)
+		if _, ok := iamFIPSEndpoints[partition.ID()]; !ok {
+			return WithErrorCategory(fmt.Errorf("FIPS endpoints are not available in the %s partition", partition.ID()), ErrorCategoryConfig)
+		}
+	}
+	return nil
+}
+
+//stsRegionalEndpoint returns the SDK setting for STSRegionalEndpoints
+func (c EndpointConfig) stsRegionalEndpoint() endpoints.STSRegionalEndpoint {
+	setting, _ := endpoints.GetSTSRegionalEndpoint(c.STSRegionalEndpoints)
+	return setting
+}
+
+//endpointFor resolves the endpoint of service in region
+func (c EndpointConfig) endpointFor(service string, region string, opts ...func(*endpoints.Options)) (endpoints.ResolvedEndpoint, error) {
+	resolved, err := endpoints.DefaultResolver().EndpointFor(service, region, opts...)
+	if err != nil {
+		return resolved, err
+	}
+
+	if len(region) == 0 {
+		region = endpoints.UsEast1RegionID
+	}
+	partition := regionPartition(region)
+
+	switch service {
+	case sts.EndpointsID:
+		//the replacement endpoints are regional, so requests are no longer signed for the global endpoint
+		if len(c.STSEndpoint) != 0 {
+			resolved.URL, resolved.SigningRegion = c.STSEndpoint, region
+		} else if c.UseFIPSEndpoint || c.UseDualStackEndpoint {
+			name, suffix := "sts", partition.DNSSuffix()
+			if c.UseFIPSEndpoint {
+				name = "sts-fips"
+			}
+			if c.UseDualStackEndpoint {
+				suffix = dualStackDNSSuffixes[partition.ID()]
+			}
+			resolved.URL, resolved.SigningRegion = fmt.Sprintf("https://%s.%s.%s", name, region, suffix), region
+		}
+	case iam.EndpointsID:
+		if len(c.IAMEndpoint) != 0 {
+			resolved.URL = c.IAMEndpoint
+		} else if c.UseFIPSEndpoint {
+			resolved.URL = iamFIPSEndpoints[partition.ID()]
+		}
+	}
+	return resolved, nil
+}
+
+//endpointsResolver returns an SDK resolver using endpointFor
+func endpointsResolver(c EndpointConfig) endpoints.Resolver {
+	return endpoints.ResolverFunc(c.endpointFor)
+}
+
+//regionPartition returns the partition of region, the aws partition for unknown regions
+func regionPartition(region string) endpoints.Partition {
+	if partition, ok := endpoints.PartitionForRegion(endpoints.DefaultPartitions(), region); ok {
+		return partition
+	}
+	return endpoints.AwsPartition()
+}
@@ -0,0 +1,313 @@
+package aws
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+func TestEndpointConfigEndpointFor(t *testing.T) {
+	tests := []struct {
+		name          string
+		config        EndpointConfig
+		service       string
+		region        string
+		opts          []func(*endpoints.Options)
+		wantURL       string
+		signingRegion string
+	}{
+		{
+			"Valid/GlobalSTS",
+			EndpointConfig{},
+			sts.EndpointsID,
+			"us-east-1",
+			nil,
+			"https://sts.amazonaws.com",
+			"us-east-1",
+		},
+		{
+			"Valid/RegionalSTS",
+			EndpointConfig{STSRegionalEndpoints: STSRegionalEndpointsRegional},
+			sts.EndpointsID,
+			"us-east-1",
+			[]func(*endpoints.Options){endpoints.STSRegionalEndpointOption},
+			"https://sts.us-east-1.amazonaws.com",
+			"us-east-1",
+		},
+		{
+			"Valid/GovCloudSTS",
+			EndpointConfig{},
+			sts.EndpointsID,
+			"us-gov-west-1",
+			nil,
+			"https://sts.us-gov-west-1.amazonaws.com",
+			"us-gov-west-1",
+		},
+		{
+			"Valid/GovCloudIAM",
+			EndpointConfig{},
+			"iam",
+			"us-gov-west-1",
+			nil,
+			"https://iam.us-gov.amazonaws.com",
+			"us-gov-west-1",
+		},
+		{
+			"Valid/ChinaSTS",
+			EndpointConfig{},
+			sts.EndpointsID,
+			"cn-north-1",
+			nil,
+			"https://sts.cn-north-1.amazonaws.com.cn",
+			"cn-north-1",
+		},
+		{
+			"Valid/FIPSSTS",
+			EndpointConfig{UseFIPSEndpoint: true},
+			sts.EndpointsID,
+			"us-east-2",
+			nil,
+			"https://sts-fips.us-east-2.amazonaws.com",
+			"us-east-2",
+		},
+		{
+			"Valid/FIPSIAM",
+			EndpointConfig{UseFIPSEndpoint: true},
+			"iam",
+			"us-east-2",
+			nil,
+			"https://iam-fips.amazonaws.com",
+			"us-east-1",
+		},
+		{
+			"Valid/DualStackSTS",
+			EndpointConfig{UseDualStackEndpoint: true},
+			sts.EndpointsID,
+			"eu-west-1",
+			nil,
+			"https://sts.eu-west-1.api.aws",
+			"eu-west-1",
+		},
+		{
+			"Valid/FIPSDualStackSTS",
+			EndpointConfig{UseFIPSEndpoint: true, UseDualStackEndpoint: true},
+			sts.EndpointsID,
+			"us-gov-east-1",
+			nil,
+			"https://sts-fips.us-gov-east-1.api.aws",
+			"us-gov-east-1",
+		},
+		{
+			"Valid/STSEndpointOverride",
+			EndpointConfig{STSEndpoint: "http://localhost:4566", UseFIPSEndpoint: true},
+			sts.EndpointsID,
+			"eu-west-1",
+			nil,
+			"http://localhost:4566",
+			"eu-west-1",
+		},
+		{
+			"Valid/IAMEndpointOverride",
+			EndpointConfig{IAMEndpoint: "http://localhost:4566"},
+			"iam",
+			"eu-west-1",
+			nil,
+			"http://localhost:4566",
+			"us-east-1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.config.endpointFor(tt.service, tt.region, tt.opts...)
+			if err != nil {
+				t.Fatalf("endpointFor() error = %v", err)
+			}
+			if got.URL != tt.wantURL {
+				t.Errorf("endpointFor() URL = %v, want %v", got.URL, tt.wantURL)
+			}
+			if got.SigningRegion != tt.signingRegion {
+				t.Errorf("endpointFor() SigningRegion = %v, want %v", got.SigningRegion, tt.signingRegion)
+			}
+		})
+	}
+}
+
+func TestEndpointConfigWithPartitionRegion(t *testing.T) {
+	tests := []struct {
+		name   string
+		config EndpointConfig
+		env    string
+		arns   []string
+		want   string
+	}{
+		{
+			"Valid/RegionSet",
+			EndpointConfig{Region: "eu-west-1"},
+			"ap-southeast-2",
+			[]string{"arn:aws-us-gov:iam::123456789012:mfa/johnsmith"},
+			"eu-west-1",
+		},
+		{
+			"Valid/RegionEnvironment",
+			EndpointConfig{},
+			"ap-southeast-2",
+			[]string{"arn:aws-us-gov:iam::123456789012:mfa/johnsmith"},
+			"ap-southeast-2",
+		},
+		{
+			"Valid/GovCloudMFASerial",
+			EndpointConfig{},
+			"",
+			[]string{"", "arn:aws-us-gov:iam::123456789012:mfa/johnsmith"},
+			"us-gov-west-1",
+		},
+		{
+			"Valid/ChinaRoleARN",
+			EndpointConfig{},
+			"",
+			[]string{"GAHT12345678", "arn:aws-cn:iam::123456789012:role/admin"},
+			"cn-north-1",
+		},
+		{
+			"Valid/NoARN",
+			EndpointConfig{},
+			"",
+			[]string{"GAHT12345678"},
+			"",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer restoreEnv(envRegion)()
+			defer restoreEnv(envDefaultRegion)()
+			os.Unsetenv(envDefaultRegion)
+			os.Setenv(envRegion, tt.env)
+
+			if got := tt.config.withPartitionRegion(tt.arns...).Region; got != tt.want {
+				t.Errorf("withPartitionRegion() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEndpointConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  EndpointConfig
+		wantErr bool
+	}{
+		{
+			"Valid/Empty",
+			EndpointConfig{},
+			false,
+		},
+		{
+			"Valid/RegionalFIPS",
+			EndpointConfig{Region: "us-gov-west-1", STSRegionalEndpoints: STSRegionalEndpointsRegional, UseFIPSEndpoint: true},
+			false,
+		},
+		{
+			"Valid/EndpointOverrides",
+			EndpointConfig{STSEndpoint: "http://localhost:4566", IAMEndpoint: "https://iam.example.com"},
+			false,
+		},
+		{
+			"Invalid/STSRegionalEndpoints",
+			EndpointConfig{STSRegionalEndpoints: "global"},
+			true,
+		},
+		{
+			"Invalid/EndpointNotURL",
+			EndpointConfig{STSEndpoint: "localhost"},
+			true,
+		},
+		{
+			"Invalid/FIPSInChina",
+			EndpointConfig{Region: "cn-north-1", UseFIPSEndpoint: true},
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.config.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_sessionEndpoints(t *testing.T) {
+	defer restoreEnv(envRegion)()
+	defer restoreEnv(envDefaultRegion)()
+	os.Unsetenv(envRegion)
+	os.Unsetenv(envDefaultRegion)
+
+	root := &profile{Name: "gov", Region: "us-gov-east-1", UseFIPSEndpoint: true, MFASerial: "arn:aws-us-gov:iam::123456789012:mfa/johnsmith"}
+	role := &profile{Name: "admin", RoleARN: "arn:aws-us-gov:iam::123456789012:role/admin", STSRegionalEndpoints: STSRegionalEndpointsRegional, Source: root}
+
+	got, err := sessionEndpoints(EndpointConfig{STSEndpoint: "http://localhost:4566"}, role)
+	if err != nil {
+		t.Fatalf("sessionEndpoints() error = %v", err)
+	}
+	want := EndpointConfig{
+		Region:               "us-gov-east-1",
+		STSRegionalEndpoints: STSRegionalEndpointsRegional,
+		UseFIPSEndpoint:      true,
+		STSEndpoint:          "http://localhost:4566",
+	}
+	if got != want {
+		t.Errorf("sessionEndpoints() = %+v, want %+v", got, want)
+	}
+
+	root.Region = ""
+	if got, _ := sessionEndpoints(EndpointConfig{}, role); got.Region != "us-gov-west-1" {
+		t.Errorf("sessionEndpoints() Region = %v, want the GovCloud region of the MFA device", got.Region)
+	}
+
+	root.Region, root.UseFIPSEndpoint = "cn-north-1", true
+	if _, err := sessionEndpoints(EndpointConfig{}, role); err == nil {
+		t.Errorf("sessionEndpoints() expected an error for FIPS endpoints in China")
+	}
+}
+
+func Test_newSessionSTSEndpoint(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<GetCallerIdentityResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <GetCallerIdentityResult>
+    <Arn>arn:aws-us-gov:iam::123456789012:user/johnsmith</Arn>
+    <UserId>AIDAEXAMPLE</UserId>
+    <Account>123456789012</Account>
+  </GetCallerIdentityResult>
+</GetCallerIdentityResponse>`)
+	}))
+	defer server.Close()
+
+	awsSession := newSession(credentials.NewStaticCredentials("AKIAEXAMPLE", "blahblah", ""),
+		EndpointConfig{Region: "us-gov-west-1", STSEndpoint: server.URL}, RequestConfig{})
+
+	identity, err := getSTSIdentity(sts.New(awsSession), RequestConfig{})
+	if err != nil {
+		t.Fatalf("getSTSIdentity() error = %v", err)
+	}
+	if identity.ARN != "arn:aws-us-gov:iam::123456789012:user/johnsmith" {
+		t.Errorf("getSTSIdentity() ARN = %v, want the identity from the fake STS", identity.ARN)
+	}
+}
+
+//restoreEnv returns a func setting name back to its current value
+func restoreEnv(name string) func() {
+	previous, ok := os.LookupEnv(name)
+	return func() {
+		if ok {
+			os.Setenv(name, previous)
+		} else {
+			os.Unsetenv(name)
+		}
+	}
+}
@@ -1,16 +1,70 @@
 package aws
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+//ErrorCategory groups failures by what the user can do about them
+type ErrorCategory string
+
+const (
+	//ErrorCategoryUnknown is any failure not in another category
+	ErrorCategoryUnknown ErrorCategory = "unknown"
+	//ErrorCategoryConfig is an invalid profile, credentials or config file, flag or setting
+	ErrorCategoryConfig ErrorCategory = "config"
+	//ErrorCategoryAuth is a request AWS refused for the credentials it was signed with
+	ErrorCategoryAuth ErrorCategory = "auth"
+	//ErrorCategoryMFA is an MFA value which is malformed or was rejected, or an MFA device which cannot be used
+	ErrorCategoryMFA ErrorCategory = "mfa"
+	//ErrorCategoryNetwork is a request which never reached AWS or got no answer
+	ErrorCategoryNetwork ErrorCategory = "network"
+	//ErrorCategoryThrottling is a request AWS refused because too many were sent
+	ErrorCategoryThrottling ErrorCategory = "throttling"
+)
+
+//authErrorCodes are the AWS error codes of requests refused for their credentials
+var authErrorCodes = map[string]bool{
+	errCodeAccessDenied:           true,
+	"AccessDeniedException":       true,
+	"InvalidClientTokenId":        true,
+	"SignatureDoesNotMatch":       true,
+	"UnrecognizedClientException": true,
+	"IncompleteSignature":         true,
+	"MissingAuthenticationToken":  true,
+	"ExpiredToken":                true,
+	"ExpiredTokenException":       true,
+	"RequestExpired":              true,
+}
+
+//networkErrorCodes are the AWS SDK error codes of requests which never reached AWS or got no answer
+var networkErrorCodes = map[string]bool{
+	request.ErrCodeRequestError:    true,
+	request.ErrCodeResponseTimeout: true,
+	request.ErrCodeRead:            true,
+	request.CanceledErrorCode:      true,
+}
 
 var (
-	//ErrAWSCredentialsFileNotFound return when no AWS credentials file can be found at $HOME/.aws/credentials
-	ErrAWSCredentialsFileNotFound = errors.New("AWS Credentials file not found at $HOME/.aws/credentials")
+	//ErrAWSCredentialsFileNotFound return when no AWS credentials file can be found, wrapped in a FileError
+	ErrAWSCredentialsFileNotFound = errors.New("AWS Credentials file not found")
+
+	//ErrInvalidAWSCredentialsFile return when AWS credentials file is invaild, wrapped in a FileError
+	ErrInvalidAWSCredentialsFile = errors.New("AWS Credentials file is invalid")
 
-	//ErrInvalidAWSCredentialsFile return when AWS credentials file is invaild
-	ErrInvalidAWSCredentialsFile = errors.New("AWS Credentials at $HOME/.aws/credentials is invalid")
+	//ErrInvalidAWSConfigFile return when AWS config file is invaild, wrapped in a FileError
+	ErrInvalidAWSConfigFile = errors.New("AWS Config file is invalid")
 
 	//ErrNoMFADeviceForUser is return when no MFA devices have been found for the user
-	ErrNoMFADeviceForUser = errors.New("No MFA devices configured for user")
+	ErrNoMFADeviceForUser = errors.New("No MFA devices configured for user, enroll one with mfa4aws mfa enroll")
+
+	//ErrMultipleMFADevicesForUser is returned when the user has several MFA devices and none has been selected
+	ErrMultipleMFADevicesForUser = errors.New("Multiple MFA devices configured for user")
 
 	//ErrTokenHasExpired is returned when the given token has expired
 	ErrTokenHasExpired = errors.New("Token has expired")
@@ -18,3 +72,174 @@ var (
 	//ErrInvalidToken is returned when an invalid token is supplied
 	ErrInvalidToken = errors.New("Invalid token code")
 )
+
+//ProfileError is returned when a profile cannot be resolved from the AWS credentials and config files
+type ProfileError struct {
+	Profile string
+	Reason  string
+	Path    string
+	Line    int
+}
+
+func (e *ProfileError) Error() string {
+	if len(e.Path) == 0 {
+		return fmt.Sprintf("profile %s %s", e.Profile, e.Reason)
+	}
+	return fmt.Sprintf("profile %s %s at %s:%d", e.Profile, e.Reason, filepath.Base(e.Path), e.Line)
+}
+
+//FileError is returned when the AWS credentials or config file at Path is missing or invalid
+type FileError struct {
+	Path string
+	Err  error
+}
+
+func (e *FileError) Error() string {
+	return fmt.Sprintf("%v at %s", e.Err, e.Path)
+}
+
+//Unwrap returns the error the file is reported for, such as ErrAWSCredentialsFileNotFound
+func (e *FileError) Unwrap() error {
+	return e.Err
+}
+
+//RequestError is returned when a request to AWS fails
+type RequestError struct {
+	Category ErrorCategory
+
+	//Code is the AWS error code, empty when the request failed before reaching AWS
+	Code string
+
+	//Message describes the request which failed
+	Message string
+
+	//Err is the error the failure is reported as, such as ErrInvalidToken, or nil
+	Err error
+
+	//Cause is the error returned by the AWS SDK
+	Cause error
+}
+
+//newRequestError returns a RequestError for the request described by message which failed with cause
+func newRequestError(message string, cause error) *RequestError {
+	e := &RequestError{Category: requestErrorCategory(cause), Message: message, Cause: cause}
+	if aerr, ok := cause.(awserr.Error); ok {
+		e.Code = aerr.Code()
+	}
+	return e
+}
+
+//reportedRequestError returns a RequestError for a request which failed with cause, reported as err
+func reportedRequestError(err error, category ErrorCategory, message string, cause error) *RequestError {
+	e := newRequestError(message, cause)
+	e.Err, e.Category = err, category
+	return e
+}
+
+func (e *RequestError) Error() string {
+	if len(e.Message) == 0 && e.Err != nil {
+		return e.Err.Error()
+	}
+
+	cause := e.Cause.Error()
+	if aerr, ok := e.Cause.(awserr.Error); ok {
+		cause = aerr.Message()
+		if aerr.OrigErr() != nil {
+			cause = fmt.Sprintf("%s, %v", cause, aerr.OrigErr())
+		}
+	}
+	if len(e.Message) == 0 {
+		return cause
+	}
+	return fmt.Sprintf("%s - %s", e.Message, cause)
+}
+
+//Unwrap returns the error returned by the AWS SDK
+func (e *RequestError) Unwrap() error {
+	return e.Cause
+}
+
+//Is returns true when the failure is reported as target
+func (e *RequestError) Is(target error) bool {
+	return e.Err != nil && e.Err == target
+}
+
+//ErrorCategory returns the category of the failure
+func (e *RequestError) ErrorCategory() ErrorCategory {
+	return e.Category
+}
+
+//requestErrorCategory returns the category of an error returned by the AWS SDK
+func requestErrorCategory(err error) ErrorCategory {
+	if request.IsErrorThrottle(err) {
+		return ErrorCategoryThrottling
+	}
+
+	var aerr awserr.Error
+	if errors.As(err, &aerr) {
+		switch {
+		case networkErrorCodes[aerr.Code()]:
+			return ErrorCategoryNetwork
+		case authErrorCodes[aerr.Code()]:
+			return ErrorCategoryAuth
+		}
+	}
+
+	var nerr net.Error
+	if errors.As(err, &nerr) {
+		return ErrorCategoryNetwork
+	}
+	return ErrorCategoryUnknown
+}
+
+//categorizedError is an error given a category by WithErrorCategory
+type categorizedError struct {
+	category ErrorCategory
+	err      error
+}
+
+func (e *categorizedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *categorizedError) Unwrap() error {
+	return e.err
+}
+
+func (e *categorizedError) ErrorCategory() ErrorCategory {
+	return e.category
+}
+
+//WithErrorCategory returns err reported as a failure of category
+func WithErrorCategory(err error, category ErrorCategory) error {
+	return &categorizedError{category: category, err: err}
+}
+
+//ErrorCategoryOf returns the category of err. Errors from other packages may give theirs with an ErrorCategory method
+func ErrorCategoryOf(err error) ErrorCategory {
+	var categorized interface{ ErrorCategory() ErrorCategory }
+	if errors.As(err, &categorized) {
+		return categorized.ErrorCategory()
+	}
+
+	var profileErr *ProfileError
+	var fileErr *FileError
+	switch {
+	case errors.As(err, &profileErr), errors.As(err, &fileErr):
+		return ErrorCategoryConfig
+	case errors.Is(err, ErrInvalidToken), errors.Is(err, ErrNoMFADeviceForUser), errors.Is(err, ErrMultipleMFADevicesForUser):
+		return ErrorCategoryMFA
+	case errors.Is(err, ErrTokenHasExpired), errors.Is(err, ErrNoSession):
+		return ErrorCategoryAuth
+	}
+	return ErrorCategoryUnknown
+}
+
+//ErrorCode returns the AWS error code of the request which failed with err, or an empty string
+func ErrorCode(err error) string {
+	var requestErr *RequestError
+	if errors.As(err, &requestErr) {
+		return requestErr.Code
+	}
+	return ""
+}
@@ -0,0 +1,124 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+func TestErrorCategoryOf(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want ErrorCategory
+	}{
+		{
+			"Valid/ProfileError",
+			&ProfileError{Profile: "work", Reason: "not found"},
+			ErrorCategoryConfig,
+		},
+		{
+			"Valid/FileError",
+			&FileError{Path: "/home/johnsmith/.aws/config", Err: ErrInvalidAWSConfigFile},
+			ErrorCategoryConfig,
+		},
+		{
+			"Valid/Categorized",
+			WithErrorCategory(errors.New("Invalid endpoint"), ErrorCategoryConfig),
+			ErrorCategoryConfig,
+		},
+		{
+			"Valid/InvalidToken",
+			ErrInvalidToken,
+			ErrorCategoryMFA,
+		},
+		{
+			"Valid/WrappedMultipleMFADevices",
+			fmt.Errorf("%w, select one of a, b", ErrMultipleMFADevicesForUser),
+			ErrorCategoryMFA,
+		},
+		{
+			"Valid/TokenHasExpired",
+			ErrTokenHasExpired,
+			ErrorCategoryAuth,
+		},
+		{
+			"Valid/AccessDenied",
+			newRequestError("Unable to retrieve user", awserr.New("AccessDenied", "not allowed", nil)),
+			ErrorCategoryAuth,
+		},
+		{
+			"Valid/Throttling",
+			newRequestError("Unable to retrieve user", awserr.New("Throttling", "Rate exceeded", nil)),
+			ErrorCategoryThrottling,
+		},
+		{
+			"Valid/RequestError",
+			newRequestError("Unable to retrieve user", awserr.New(request.ErrCodeRequestError, "send request failed", errors.New("dial tcp"))),
+			ErrorCategoryNetwork,
+		},
+		{
+			"Valid/NetError",
+			newRequestError("Unable to retrieve user", &net.DNSError{Err: "no such host", Name: "sts.amazonaws.com"}),
+			ErrorCategoryNetwork,
+		},
+		{
+			"Valid/Unknown",
+			errors.New("blah"),
+			ErrorCategoryUnknown,
+		},
+		{
+			"Valid/UnknownAWSError",
+			newRequestError("Unable to retrieve user", awserr.New("5000", "blah", nil)),
+			ErrorCategoryUnknown,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ErrorCategoryOf(tt.err); got != tt.want {
+				t.Errorf("ErrorCategoryOf() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestError(t *testing.T) {
+	cause := awserr.New("AccessDenied", "MultiFactorAuthentication failed with invalid MFA one time pass code.", nil)
+	_, err := getSTSSessionToken(&STSAPIMock{
+		GetSessionTokenWithContextFunc: func(ctx context.Context, in1 *sts.GetSessionTokenInput, opts ...request.Option) (*sts.GetSessionTokenOutput, error) {
+			return nil, cause
+		},
+	}, RequestConfig{}, "123456", "sfagstfey", 0)
+
+	if !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("errors.Is() = false, want the error reported as %v", ErrInvalidToken)
+	}
+	if err.Error() != ErrInvalidToken.Error() {
+		t.Errorf("Error() = %v, want %v", err, ErrInvalidToken)
+	}
+
+	var aerr awserr.Error
+	if !errors.As(err, &aerr) || aerr != cause {
+		t.Errorf("errors.As() = %v, want the error returned by STS", aerr)
+	}
+	if code := ErrorCode(err); code != "AccessDenied" {
+		t.Errorf("ErrorCode() = %v, want AccessDenied", code)
+	}
+	if category := ErrorCategoryOf(err); category != ErrorCategoryMFA {
+		t.Errorf("ErrorCategoryOf() = %v, want %v", category, ErrorCategoryMFA)
+	}
+
+	err = newRequestError("Unable to retrieve user", awserr.New(request.ErrCodeRequestError, "send request failed", errors.New("dial tcp")))
+	if want := "Unable to retrieve user - send request failed, dial tcp"; err.Error() != want {
+		t.Errorf("Error() = %v, want %v", err, want)
+	}
+	if errors.Is(err, ErrInvalidToken) {
+		t.Errorf("errors.Is() = true, want a failure not reported as %v", ErrInvalidToken)
+	}
+}
@@ -0,0 +1,85 @@
+package aws
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"mfa4aws/internal/pkg/appfs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+const (
+	tokenFailuresFileExt string = ".failures"
+
+	//resyncTokenFailures is the number of consecutive MFA values rejected for a device before resyncing it is suggested
+	resyncTokenFailures int = 3
+
+	//tokenFailuresWindow is how long a rejected MFA value counts towards resyncTokenFailures
+	tokenFailuresWindow = 30 * time.Minute
+)
+
+//tokenFailures represents the MFA values STS rejected in a row for an MFA device
+type tokenFailures struct {
+	SerialNumber string    `json:"serial_number"`
+	Count        int       `json:"count"`
+	LastFailure  time.Time `json:"last_failure"`
+}
+
+func tokenFailuresPath(serialNumber string) (string, error) {
+	dir, err := cacheDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(serialNumber))
+	return filepath.Join(dir, hex.EncodeToString(sum[:])+tokenFailuresFileExt), nil
+}
+
+//recordTokenFailure counts another MFA value rejected for the device and returns the number rejected in a row
+func recordTokenFailure(serialNumber string, now time.Time) (int, error) {
+	path, err := tokenFailuresPath(serialNumber)
+	if err != nil {
+		return 0, err
+	}
+
+	failures := tokenFailures{SerialNumber: serialNumber}
+	if data, err := openFile(path); err == nil {
+		if err := json.Unmarshal(data, &failures); err != nil || now.Sub(failures.LastFailure) > tokenFailuresWindow {
+			failures = tokenFailures{SerialNumber: serialNumber}
+		}
+	}
+
+	failures.Count++
+	failures.LastFailure = now
+
+	data, err := json.Marshal(&failures)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := appfs.Fs.MkdirAll(filepath.Dir(path), cacheDirMode); err != nil {
+		return 0, err
+	}
+
+	if err := afero.WriteFile(appfs.Fs, path, data, cacheFileMode); err != nil {
+		return 0, err
+	}
+
+	return failures.Count, nil
+}
+
+//resetTokenFailures forgets the MFA values rejected for the device once one is accepted
+func resetTokenFailures(serialNumber string) error {
+	path, err := tokenFailuresPath(serialNumber)
+	if err != nil {
+		return err
+	}
+
+	if err := appfs.Fs.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
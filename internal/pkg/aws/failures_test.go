@@ -0,0 +1,40 @@
+package aws
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_recordTokenFailure(t *testing.T) {
+	const serialNumber = "arn:aws:iam::123456789012:mfa/drifted"
+	now := time.Now()
+
+	for i, offset := range []time.Duration{0, time.Minute, 2 * time.Minute} {
+		got, err := recordTokenFailure(serialNumber, now.Add(offset))
+		if err != nil {
+			t.Fatalf("recordTokenFailure() error = %v", err)
+		}
+		if got != i+1 {
+			t.Errorf("recordTokenFailure() = %v, want %v", got, i+1)
+		}
+	}
+
+	if got, _ := recordTokenFailure("arn:aws:iam::123456789012:mfa/other", now); got != 1 {
+		t.Errorf("recordTokenFailure() other device = %v, want 1", got)
+	}
+
+	if got, _ := recordTokenFailure(serialNumber, now.Add(2*time.Minute+tokenFailuresWindow+time.Second)); got != 1 {
+		t.Errorf("recordTokenFailure() after the window = %v, want 1", got)
+	}
+
+	if err := resetTokenFailures(serialNumber); err != nil {
+		t.Fatalf("resetTokenFailures() error = %v", err)
+	}
+	if err := resetTokenFailures(serialNumber); err != nil {
+		t.Errorf("resetTokenFailures() without failures error = %v", err)
+	}
+
+	if got, _ := recordTokenFailure(serialNumber, now); got != 1 {
+		t.Errorf("recordTokenFailure() after reset = %v, want 1", got)
+	}
+}
@@ -2,24 +2,156 @@ package aws
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
-	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/iam"
 	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"github.com/aws/aws-sdk-go/service/sts"
 )
 
-func getIAMUserMFADevice(iamInstance iamiface.IAMAPI) (string, error) {
-	devices, err := iamInstance.ListMFADevices(&iam.ListMFADevicesInput{})
+//MFADeviceSelector returns the serial number to use when the user has several MFA devices and none is pinned
+type MFADeviceSelector func(serialNumbers []string) (string, error)
+
+//getIAMUserMFADevice returns serialNumber, or the user's MFA device when it is empty
+func getIAMUserMFADevice(iamInstance iamiface.IAMAPI, requests RequestConfig, serialNumber string, selector MFADeviceSelector) (string, error) {
+	serialNumbers, err := listIAMUserMFADevices(iamInstance, requests)
 	if err != nil {
-		if aerr, ok := err.(awserr.Error); ok {
-			return "", fmt.Errorf("Unable to retrive any MFA devices - %v", aerr.Message())
-		}
-		return "", fmt.Errorf("unknown error occurred, %v", err)
+		return "", err
 	}
 
-	if len(devices.MFADevices) == 0 {
+	if len(serialNumbers) == 0 {
 		return "", ErrNoMFADeviceForUser
 	}
 
-	return *devices.MFADevices[0].SerialNumber, nil
+	if len(serialNumber) != 0 {
+		return checkIAMUserMFADevice(serialNumbers, serialNumber)
+	}
+
+	if len(serialNumbers) == 1 {
+		return serialNumbers[0], nil
+	}
+
+	if selector == nil {
+		return "", fmt.Errorf("%w, select one of %s with --serial or mfa_serial", ErrMultipleMFADevicesForUser, strings.Join(serialNumbers, ", "))
+	}
+
+	serialNumber, err = selector(serialNumbers)
+	if err != nil {
+		return "", err
+	}
+
+	return checkIAMUserMFADevice(serialNumbers, serialNumber)
+}
+
+func checkIAMUserMFADevice(serialNumbers []string, serialNumber string) (string, error) {
+	for _, x := range serialNumbers {
+		if x == serialNumber {
+			return serialNumber, nil
+		}
+	}
+	return "", WithErrorCategory(fmt.Errorf("MFA device %s is not registered to the user, registered devices are %s", serialNumber,
+		strings.Join(serialNumbers, ", ")), ErrorCategoryMFA)
+}
+
+//listIAMUserMFADevices returns the serial numbers of all the MFA devices registered to the user
+func listIAMUserMFADevices(iamInstance iamiface.IAMAPI, requests RequestConfig) ([]string, error) {
+	ctx, cancel := requests.context()
+	defer cancel()
+
+	var serialNumbers []string
+
+	input := &iam.ListMFADevicesInput{}
+	for {
+		devices, err := iamInstance.ListMFADevicesWithContext(ctx, input)
+		if err != nil {
+			return nil, newRequestError("Unable to list MFA devices", err)
+		}
+
+		for _, device := range devices.MFADevices {
+			serialNumbers = append(serialNumbers, *device.SerialNumber)
+		}
+
+		if devices.IsTruncated == nil || !*devices.IsTruncated || devices.Marker == nil {
+			return serialNumbers, nil
+		}
+		input.Marker = devices.Marker
+	}
+}
+
+//getIAMRoleMaxSessionDuration returns the MaxSessionDuration of the role roleARN, or zero when it cannot be read
+func getIAMRoleMaxSessionDuration(iamInstance iamiface.IAMAPI, requests RequestConfig, roleARN string) time.Duration {
+	roleName := roleARN[strings.LastIndex(roleARN, "/")+1:]
+
+	ctx, cancel := requests.context()
+	defer cancel()
+
+	role, err := iamInstance.GetRoleWithContext(ctx, &iam.GetRoleInput{RoleName: &roleName})
+	if err != nil || role.Role == nil || role.Role.Arn == nil || role.Role.MaxSessionDuration == nil {
+		return 0
+	}
+
+	//a role of the same name in the user's own account says nothing about a role in another account
+	if *role.Role.Arn != roleARN {
+		return 0
+	}
+
+	return time.Duration(*role.Role.MaxSessionDuration) * time.Second
+}
+
+//checkIAMRoleSessionDuration checks duration does not exceed the MaxSessionDuration of the role roleARN
+func checkIAMRoleSessionDuration(iamInstance iamiface.IAMAPI, requests RequestConfig, roleARN string, duration time.Duration) error {
+	if duration == 0 {
+		return nil
+	}
+	max := getIAMRoleMaxSessionDuration(iamInstance, requests, roleARN)
+	if max != 0 && duration > max {
+		return WithErrorCategory(fmt.Errorf("Session duration %v exceeds the MaxSessionDuration %v of role %s", duration, max, roleARN), ErrorCategoryConfig)
+	}
+	return nil
+}
+
+//iamUser is the IAM user whose long term keys a profile holds
+type iamUser struct {
+	session  *session.Session
+	requests RequestConfig
+	profile  *profile
+	account  string
+	arn      string
+	name     string
+}
+
+//newIAMUser returns the IAM user holding the long term keys of profileName, refusing profiles which assume a role
+func newIAMUser(source ProfileSource, profileName string, arns ...string) (*iamUser, error) {
+	awsSession, p, err := createSession(source, profileName, arns...)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.isRole() {
+		return nil, &ProfileError{Profile: profileName, Reason: fmt.Sprintf("assumes a role, use its source profile %s instead", p.root().Name)}
+	}
+
+	identity, err := getSTSIdentity(sts.New(awsSession), source.Requests)
+	if err != nil {
+		return nil, err
+	}
+
+	name, err := iamUserName(identity.ARN)
+	if err != nil {
+		return nil, err
+	}
+
+	return &iamUser{session: awsSession, requests: source.Requests, profile: p, account: identity.Account, arn: identity.ARN, name: name}, nil
+}
+
+//iamUserName returns the name of the IAM user principalARN, which must not be a role
+func iamUserName(principalARN string) (string, error) {
+	principal, err := arn.Parse(principalARN)
+	if err != nil || principal.Service != "iam" || !strings.HasPrefix(principal.Resource, "user/") {
+		return "", WithErrorCategory(fmt.Errorf("Profile must hold the keys of an IAM user, not %s", principalARN), ErrorCategoryConfig)
+	}
+	return principal.Resource[strings.LastIndex(principal.Resource, "/")+1:], nil
 }
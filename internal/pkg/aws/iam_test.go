@@ -3,9 +3,12 @@ package aws
 //go:generate go run -tags tools github.com/matryer/moq -pkg aws -out iam_test_mock.go $GOPATH/pkg/mod/github.com/aws/aws-sdk-go@v1.34.0/service/iam/iamiface IAMAPI
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/iam"
 	"github.com/aws/aws-sdk-go/service/iam/iamiface"
 
@@ -13,8 +16,20 @@ import (
 )
 
 func Test_getIAMUserMFADevice(t *testing.T) {
+	listDevices := func(serialNumbers ...string) func(context.Context, *iam.ListMFADevicesInput, ...request.Option) (*iam.ListMFADevicesOutput, error) {
+		return func(ctx context.Context, in1 *iam.ListMFADevicesInput, opts ...request.Option) (*iam.ListMFADevicesOutput, error) {
+			output := &iam.ListMFADevicesOutput{}
+			for i := range serialNumbers {
+				output.MFADevices = append(output.MFADevices, &iam.MFADevice{SerialNumber: &serialNumbers[i]})
+			}
+			return output, nil
+		}
+	}
+
 	type args struct {
-		iamInstance iamiface.IAMAPI
+		iamInstance  iamiface.IAMAPI
+		serialNumber string
+		selector     MFADeviceSelector
 	}
 	tests := []struct {
 		name    string
@@ -26,7 +41,7 @@ func Test_getIAMUserMFADevice(t *testing.T) {
 			"Vaild/DeviceFound",
 			args{
 				iamInstance: &IAMAPIMock{
-					ListMFADevicesFunc: func(in1 *iam.ListMFADevicesInput) (*iam.ListMFADevicesOutput, error) {
+					ListMFADevicesWithContextFunc: func(ctx context.Context, in1 *iam.ListMFADevicesInput, opts ...request.Option) (*iam.ListMFADevicesOutput, error) {
 						sn := "shsjdyshe"
 
 						output := &iam.ListMFADevicesOutput{
@@ -41,11 +56,94 @@ func Test_getIAMUserMFADevice(t *testing.T) {
 			"shsjdyshe",
 			false,
 		},
+		{
+			"Vaild/PinnedDevice",
+			args{
+				iamInstance: &IAMAPIMock{
+					ListMFADevicesWithContextFunc: listDevices("phone", "backup"),
+				},
+				serialNumber: "backup",
+			},
+			"backup",
+			false,
+		},
+		{
+			"Vaild/SelectedDevice",
+			args{
+				iamInstance: &IAMAPIMock{
+					ListMFADevicesWithContextFunc: listDevices("phone", "backup"),
+				},
+				selector: func(serialNumbers []string) (string, error) {
+					return serialNumbers[1], nil
+				},
+			},
+			"backup",
+			false,
+		},
+		{
+			"Vaild/Paginated",
+			args{
+				iamInstance: &IAMAPIMock{
+					ListMFADevicesWithContextFunc: func(ctx context.Context, in1 *iam.ListMFADevicesInput, opts ...request.Option) (*iam.ListMFADevicesOutput, error) {
+						truncated, marker := true, "page2"
+						phone, backup := "phone", "backup"
+
+						if in1.Marker == nil {
+							return &iam.ListMFADevicesOutput{
+								MFADevices:  []*iam.MFADevice{{SerialNumber: &phone}},
+								IsTruncated: &truncated,
+								Marker:      &marker,
+							}, nil
+						}
+						return &iam.ListMFADevicesOutput{
+							MFADevices: []*iam.MFADevice{{SerialNumber: &backup}},
+						}, nil
+					},
+				},
+				serialNumber: "backup",
+			},
+			"backup",
+			false,
+		},
+		{
+			"Invaild/PinnedDeviceNotRegistered",
+			args{
+				iamInstance: &IAMAPIMock{
+					ListMFADevicesWithContextFunc: listDevices("phone", "backup"),
+				},
+				serialNumber: "yubikey",
+			},
+			"",
+			true,
+		},
+		{
+			"Invaild/MultipleDevicesNoSelector",
+			args{
+				iamInstance: &IAMAPIMock{
+					ListMFADevicesWithContextFunc: listDevices("phone", "backup"),
+				},
+			},
+			"",
+			true,
+		},
+		{
+			"Invaild/SelectorError",
+			args{
+				iamInstance: &IAMAPIMock{
+					ListMFADevicesWithContextFunc: listDevices("phone", "backup"),
+				},
+				selector: func(serialNumbers []string) (string, error) {
+					return "", errors.New("blah")
+				},
+			},
+			"",
+			true,
+		},
 		{
 			"Invaild/awserrError",
 			args{
 				iamInstance: &IAMAPIMock{
-					ListMFADevicesFunc: func(in1 *iam.ListMFADevicesInput) (*iam.ListMFADevicesOutput, error) {
+					ListMFADevicesWithContextFunc: func(ctx context.Context, in1 *iam.ListMFADevicesInput, opts ...request.Option) (*iam.ListMFADevicesOutput, error) {
 						return nil, awserr.New("5000", "blah", errors.New("blah"))
 					},
 				},
@@ -57,7 +155,7 @@ func Test_getIAMUserMFADevice(t *testing.T) {
 			"Invaild/Error",
 			args{
 				iamInstance: &IAMAPIMock{
-					ListMFADevicesFunc: func(in1 *iam.ListMFADevicesInput) (*iam.ListMFADevicesOutput, error) {
+					ListMFADevicesWithContextFunc: func(ctx context.Context, in1 *iam.ListMFADevicesInput, opts ...request.Option) (*iam.ListMFADevicesOutput, error) {
 						return nil, errors.New("blah")
 					},
 				},
@@ -69,7 +167,7 @@ func Test_getIAMUserMFADevice(t *testing.T) {
 			"Invaild/NoDevices",
 			args{
 				iamInstance: &IAMAPIMock{
-					ListMFADevicesFunc: func(in1 *iam.ListMFADevicesInput) (*iam.ListMFADevicesOutput, error) {
+					ListMFADevicesWithContextFunc: func(ctx context.Context, in1 *iam.ListMFADevicesInput, opts ...request.Option) (*iam.ListMFADevicesOutput, error) {
 
 						output := &iam.ListMFADevicesOutput{
 							MFADevices: nil,
@@ -84,7 +182,7 @@ func Test_getIAMUserMFADevice(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := getIAMUserMFADevice(tt.args.iamInstance)
+			got, err := getIAMUserMFADevice(tt.args.iamInstance, RequestConfig{}, tt.args.serialNumber, tt.args.selector)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("getIAMUserMFADevice() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -95,3 +193,102 @@ func Test_getIAMUserMFADevice(t *testing.T) {
 		})
 	}
 }
+
+func Test_checkIAMRoleSessionDuration(t *testing.T) {
+	roleARN := "arn:aws:iam::210987654321:role/path/admin"
+	getRole := func(arn string, maxSessionDuration int64) func(context.Context, *iam.GetRoleInput, ...request.Option) (*iam.GetRoleOutput, error) {
+		return func(ctx context.Context, in1 *iam.GetRoleInput, opts ...request.Option) (*iam.GetRoleOutput, error) {
+			if *in1.RoleName != "admin" {
+				return nil, errors.New("unexpected input")
+			}
+			return &iam.GetRoleOutput{Role: &iam.Role{Arn: &arn, MaxSessionDuration: &maxSessionDuration}}, nil
+		}
+	}
+
+	type args struct {
+		iamInstance iamiface.IAMAPI
+		duration    time.Duration
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{
+			"Valid/NoDuration",
+			args{
+				iamInstance: &IAMAPIMock{},
+			},
+			false,
+		},
+		{
+			"Valid/WithinMaxSessionDuration",
+			args{
+				iamInstance: &IAMAPIMock{GetRoleWithContextFunc: getRole(roleARN, 14400)},
+				duration:    4 * time.Hour,
+			},
+			false,
+		},
+		{
+			"Valid/GetRoleDenied",
+			args{
+				iamInstance: &IAMAPIMock{
+					GetRoleWithContextFunc: func(ctx context.Context, in1 *iam.GetRoleInput, opts ...request.Option) (*iam.GetRoleOutput, error) {
+						return nil, awserr.New("AccessDenied", "blah", errors.New("blah"))
+					},
+				},
+				duration: 12 * time.Hour,
+			},
+			false,
+		},
+		{
+			"Valid/RoleInAnotherAccount",
+			args{
+				iamInstance: &IAMAPIMock{GetRoleWithContextFunc: getRole("arn:aws:iam::162171167783:role/admin", 3600)},
+				duration:    12 * time.Hour,
+			},
+			false,
+		},
+		{
+			"Invalid/ExceedsMaxSessionDuration",
+			args{
+				iamInstance: &IAMAPIMock{GetRoleWithContextFunc: getRole(roleARN, 3600)},
+				duration:    4 * time.Hour,
+			},
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkIAMRoleSessionDuration(tt.args.iamInstance, RequestConfig{}, roleARN, tt.args.duration); (err != nil) != tt.wantErr {
+				t.Errorf("checkIAMRoleSessionDuration() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_iamUserName(t *testing.T) {
+	tests := []struct {
+		name         string
+		principalARN string
+		want         string
+		wantErr      bool
+	}{
+		{"Valid/User", "arn:aws:iam::123456789012:user/alice", "alice", false},
+		{"Valid/UserWithPath", "arn:aws:iam::123456789012:user/engineering/alice", "alice", false},
+		{"Invalid/AssumedRole", "arn:aws:sts::123456789012:assumed-role/admin/mfa4aws", "", true},
+		{"Invalid/Root", "arn:aws:iam::123456789012:root", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := iamUserName(tt.principalARN)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("iamUserName() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("iamUserName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package aws
+
+import (
+	"github.com/aws/aws-sdk-go/aws/credentials"
+)
+
+const (
+	accessKeyStoreProviderName string = "AccessKeyStoreProvider"
+)
+
+//AccessKeys are the long term access keys of an IAM user
+type AccessKeys struct {
+	AccessKeyID     string `json:"aws_access_key_id"`
+	SecretAccessKey string `json:"aws_secret_access_key"`
+}
+
+//AccessKeyStore holds the long term access keys of IAM users outside the plaintext credentials file
+type AccessKeyStore interface {
+	//HasAccessKeys returns true when keys are stored for profile, without asking for any passphrase
+	HasAccessKeys(profile string) bool
+
+	//AccessKeys returns the keys stored for profile
+	AccessKeys(profile string) (*AccessKeys, error)
+
+	//SetAccessKeys stores keys for profile, replacing any keys already stored
+	SetAccessKeys(profile string, keys *AccessKeys) error
+}
+
+//accessKeyStoreProvider reads the long term keys of profile from an AccessKeyStore when first used
+type accessKeyStoreProvider struct {
+	keys      AccessKeyStore
+	profile   string
+	retrieved bool
+}
+
+func (p *accessKeyStoreProvider) Retrieve() (credentials.Value, error) {
+	keys, err := p.keys.AccessKeys(p.profile)
+	if err != nil {
+		return credentials.Value{ProviderName: accessKeyStoreProviderName}, err
+	}
+	p.retrieved = true
+
+	return credentials.Value{
+		AccessKeyID:     keys.AccessKeyID,
+		SecretAccessKey: keys.SecretAccessKey,
+		ProviderName:    accessKeyStoreProviderName,
+	}, nil
+}
+
+func (p *accessKeyStoreProvider) IsExpired() bool {
+	return !p.retrieved
+}
@@ -0,0 +1,98 @@
+package aws
+
+import (
+	"mfa4aws/internal/pkg/appfs"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+func Test_accessKeyStoreProvider(t *testing.T) {
+	store := mapAccessKeyStore{
+		"work":   {AccessKeyID: "AKIAWORK", SecretAccessKey: "work/work"},
+		"locked": nil,
+	}
+
+	provider := &accessKeyStoreProvider{keys: store, profile: "work"}
+	if !provider.IsExpired() {
+		t.Errorf("IsExpired() = false before Retrieve(), want true")
+	}
+
+	value, err := provider.Retrieve()
+	if err != nil {
+		t.Fatalf("Retrieve() error = %v", err)
+	}
+	if value.AccessKeyID != "AKIAWORK" || value.SecretAccessKey != "work/work" || len(value.SessionToken) != 0 {
+		t.Errorf("Retrieve() = %v, want the keys of profile work", value)
+	}
+	if provider.IsExpired() {
+		t.Errorf("IsExpired() = true after Retrieve(), want false")
+	}
+
+	if _, err := (&accessKeyStoreProvider{keys: store, profile: "locked"}).Retrieve(); err == nil {
+		t.Errorf("Retrieve() expected an error")
+	}
+}
+
+//countingAccessKeyStore counts how often the keys are read, each read asking for the vault passphrase
+type countingAccessKeyStore struct {
+	mapAccessKeyStore
+	opened int
+}
+
+func (s *countingAccessKeyStore) AccessKeys(profile string) (*AccessKeys, error) {
+	s.opened++
+	return s.mapAccessKeyStore.AccessKeys(profile)
+}
+
+func TestGenerateSTSCredentialsAccessKeyStore(t *testing.T) {
+	const credentialsFile = "/vault/credentials"
+	if err := afero.WriteFile(appfs.Fs, credentialsFile, nil, 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	cached := &Credentials{
+		AWSAccessKeyID: "ASIACACHED",
+		Expiration:     time.Now().Add(time.Hour).UTC().Round(time.Second),
+	}
+	key, err := newSessionKey(credentialsFile, "/vault/config", "vault-cached", "arn:aws:iam::123456789012:mfa/johnsmith")
+	if err != nil {
+		t.Fatalf("newSessionKey() error = %v", err)
+	}
+	if err := writeCachedCredentials(key, cached); err != nil {
+		t.Fatalf("writeCachedCredentials() error = %v", err)
+	}
+
+	store := &countingAccessKeyStore{mapAccessKeyStore: mapAccessKeyStore{
+		"vault-cached": {AccessKeyID: "AKIAVAULT", SecretAccessKey: "blahblah"},
+	}}
+	input := &STSCredentialsInput{
+		Profile:         "vault-cached",
+		TokenCode:       "123456",
+		MinimumLifetime: 5 * time.Minute,
+		ProfileSource: ProfileSource{
+			AccessKeyStore:  store,
+			CredentialsFile: credentialsFile,
+			ConfigFile:      "/vault/config",
+			Endpoints:       EndpointConfig{Region: "us-east-1", STSEndpoint: "http://127.0.0.1:1", IAMEndpoint: "http://127.0.0.1:1"},
+		},
+	}
+
+	got, err := GenerateSTSCredentials(input)
+	if err != nil || !reflect.DeepEqual(got, cached) {
+		t.Fatalf("GenerateSTSCredentials() = %v, %v, want the cached session", got, err)
+	}
+	if store.opened != 0 {
+		t.Errorf("AccessKeys() called %d times for a cached session, want 0", store.opened)
+	}
+
+	input.Force = true
+	if _, err := GenerateSTSCredentials(input); err == nil {
+		t.Fatalf("GenerateSTSCredentials() expected an error from the unreachable IAM endpoint")
+	}
+	if store.opened != 1 {
+		t.Errorf("AccessKeys() called %d times for a new session, want 1", store.opened)
+	}
+}
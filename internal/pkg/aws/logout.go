@@ -0,0 +1,144 @@
+package aws
+
+import (
+	"mfa4aws/internal/pkg/appfs"
+	"os"
+
+	"gopkg.in/ini.v1"
+)
+
+const (
+	//configXExpiration is only written into the derived profiles written by login
+	configXExpiration string = "x_expiration"
+)
+
+//LogoutInput represents the sessions to forget
+type LogoutInput struct {
+	//Profile is the profile whose cached sessions and derived profile are removed
+	Profile string
+
+	//AccessKeyID is the access key of the session in the environment
+	AccessKeyID string
+
+	//DerivedProfileSuffix is appended to a profile name for the derived profile written by login
+	DerivedProfileSuffix string
+
+	//All removes every cached session and every derived profile
+	All bool
+
+	//CredentialsFile is the AWS credentials file, $AWS_SHARED_CREDENTIALS_FILE or $HOME/.aws/credentials when empty
+	CredentialsFile string
+}
+
+//LogoutOutput describes what Logout removed
+type LogoutOutput struct {
+	//Sessions is the number of cached sessions removed
+	Sessions int
+
+	//Profiles are the derived profiles removed from the credentials file
+	Profiles []string
+}
+
+//Logout removes the cached sessions selected by input and the derived profiles login wrote
+func Logout(input *LogoutInput) (*LogoutOutput, error) {
+	profiles := map[string]bool{}
+	if len(input.Profile) != 0 {
+		profiles[input.Profile] = true
+	}
+
+	if len(input.AccessKeyID) != 0 {
+		session, err := findCachedSession(func(session *cachedSession) bool {
+			return session.Credentials.AWSAccessKeyID == input.AccessKeyID
+		})
+		if err != nil {
+			return nil, err
+		}
+		if session != nil {
+			profiles[session.Profile] = true
+		}
+	}
+
+	sessions, err := removeCachedSessions(func(session *cachedSession) bool {
+		return input.All || profiles[session.Profile]
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	removed, err := removeDerivedProfiles(input.CredentialsFile, func(name string) bool {
+		if input.All {
+			return true
+		}
+		for profile := range profiles {
+			if name == profile+input.DerivedProfileSuffix {
+				return true
+			}
+		}
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &LogoutOutput{Sessions: sessions, Profiles: removed}, nil
+}
+
+//removeCachedSessions deletes the cached sessions matching match and returns how many were deleted
+func removeCachedSessions(match func(session *cachedSession) bool) (int, error) {
+	sessions, err := readCachedSessions()
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for path, session := range sessions {
+		if !match(session) {
+			continue
+		}
+		if err := appfs.Fs.Remove(path); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+//removeDerivedProfiles removes the sections holding x_expiration matched by match and returns their names
+func removeDerivedProfiles(path string, match func(name string) bool) ([]string, error) {
+	path, err := credentialsFilePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := openFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	file, err := ini.Load(data)
+	if err != nil {
+		return nil, &FileError{Path: path, Err: ErrInvalidAWSCredentialsFile}
+	}
+
+	var removed []string
+	updated := data
+	for _, section := range file.Sections() {
+		if section.HasKey(configXExpiration) && match(section.Name()) {
+			updated = removeProfile(updated, section.Name())
+			removed = append(removed, section.Name())
+		}
+	}
+	if len(removed) == 0 {
+		return nil, nil
+	}
+
+	mode := os.FileMode(credentialsFileMode)
+	if info, err := appfs.Fs.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	return removed, writeFileAtomic(path, updated, mode)
+}
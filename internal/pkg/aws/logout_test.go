@@ -0,0 +1,115 @@
+package aws
+
+import (
+	"mfa4aws/internal/pkg/appfs"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+func TestLogout(t *testing.T) {
+	credentialsPath, err := credentialsFilePath("")
+	if err != nil {
+		t.Fatalf("credentialsFilePath() error = %v", err)
+	}
+	original, err := afero.ReadFile(appfs.Fs, credentialsPath)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	defer afero.WriteFile(appfs.Fs, credentialsPath, original, 0600)
+
+	const credentialsFile = "[logout-work]\naws_access_key_id = AKIAWORK\n\n[logout-work-mfa]\naws_access_key_id = ASIAWORK\nx_expiration = 2020-08-01T12:00:00Z\n\n[logout-home-mfa]\naws_access_key_id = AKIAHOME\n"
+
+	setup := func() {
+		if err := afero.WriteFile(appfs.Fs, credentialsPath, []byte(credentialsFile), 0600); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+		expiration := time.Now().Add(time.Hour)
+		for _, x := range []struct{ profile, serialNumber, accessKeyID string }{
+			{"logout-work", "arn:aws:iam::123456789012:mfa/phone", "ASIAWORK"},
+			{"logout-work", "arn:aws:iam::123456789012:mfa/backup", "ASIABACKUP"},
+			{"logout-home", "arn:aws:iam::123456789012:mfa/phone", "ASIAHOME"},
+		} {
+			if err := writeCachedCredentials(sessionKey{Profile: x.profile, SerialNumber: x.serialNumber}, &Credentials{AWSAccessKeyID: x.accessKeyID, Expiration: expiration}); err != nil {
+				t.Fatalf("writeCachedCredentials() error = %v", err)
+			}
+		}
+	}
+
+	cachedProfiles := func() map[string]int {
+		sessions, err := readCachedSessions()
+		if err != nil {
+			t.Fatalf("readCachedSessions() error = %v", err)
+		}
+		profiles := map[string]int{}
+		for _, session := range sessions {
+			profiles[session.Profile]++
+		}
+		return profiles
+	}
+
+	tests := []struct {
+		name         string
+		input        *LogoutInput
+		want         *LogoutOutput
+		wantSessions map[string]int
+	}{
+		{
+			"Valid/Profile",
+			&LogoutInput{Profile: "logout-work", DerivedProfileSuffix: "-mfa"},
+			&LogoutOutput{Sessions: 2, Profiles: []string{"logout-work-mfa"}},
+			map[string]int{"logout-home": 1},
+		},
+		{
+			"Valid/EnvironmentAccessKey",
+			&LogoutInput{AccessKeyID: "ASIABACKUP", DerivedProfileSuffix: "-mfa"},
+			&LogoutOutput{Sessions: 2, Profiles: []string{"logout-work-mfa"}},
+			map[string]int{"logout-home": 1},
+		},
+		{
+			"Valid/HandWrittenProfileKept",
+			&LogoutInput{Profile: "logout-home", DerivedProfileSuffix: "-mfa"},
+			&LogoutOutput{Sessions: 1},
+			map[string]int{"logout-work": 2},
+		},
+		{
+			"Valid/All",
+			&LogoutInput{All: true, DerivedProfileSuffix: "-mfa"},
+			&LogoutOutput{Sessions: 3, Profiles: []string{"logout-work-mfa"}},
+			map[string]int{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := removeCachedSessions(func(*cachedSession) bool { return true }); err != nil {
+				t.Fatalf("removeCachedSessions() error = %v", err)
+			}
+			setup()
+
+			got, err := Logout(tt.input)
+			if err != nil {
+				t.Fatalf("Logout() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Logout() = %+v, want %+v", got, tt.want)
+			}
+			if sessions := cachedProfiles(); !reflect.DeepEqual(sessions, tt.wantSessions) {
+				t.Errorf("Logout() left cached sessions %v, want %v", sessions, tt.wantSessions)
+			}
+
+			data, err := afero.ReadFile(appfs.Fs, credentialsPath)
+			if err != nil {
+				t.Fatalf("ReadFile() error = %v", err)
+			}
+			if len(tt.want.Profiles) != 0 {
+				if want := "[logout-work]\naws_access_key_id = AKIAWORK\n\n[logout-home-mfa]\naws_access_key_id = AKIAHOME\n"; string(data) != want {
+					t.Errorf("Logout() credentials file = %q, want %q", data, want)
+				}
+			} else if string(data) != credentialsFile {
+				t.Errorf("Logout() credentials file = %q, want it unchanged", data)
+			}
+		})
+	}
+}
@@ -0,0 +1,228 @@
+package aws
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+)
+
+//VirtualMFADevice is a virtual MFA device created for an IAM user
+type VirtualMFADevice struct {
+	//SerialNumber is the ARN of the device, used as mfa_serial
+	SerialNumber string
+
+	//UserName is the IAM user the device is enabled for
+	UserName string
+
+	//AccountID is the AWS account of the user
+	AccountID string
+
+	//Seed is the base32 encoded TOTP secret of the device
+	Seed string
+
+	//QRCodePNG is a PNG image of the QR code holding the seed as an otpauth:// URI
+	QRCodePNG []byte
+}
+
+//MFACodesProvider returns two consecutive MFA values generated for the new device
+type MFACodesProvider func(device *VirtualMFADevice) (string, string, error)
+
+//MFAEnrollmentInput represents the parameters used to enroll a virtual MFA device
+type MFAEnrollmentInput struct {
+	//Profile is the AWS profile name holding the IAM user's long term access keys
+	Profile string
+
+	//DeviceName is the name of the new virtual MFA device, the user name when empty
+	DeviceName string
+
+	//Codes is called for two consecutive MFA values once the device is created
+	Codes MFACodesProvider
+
+	ProfileSource
+}
+
+//MFAResyncInput represents the parameters used to resynchronise an MFA device whose clock has drifted
+type MFAResyncInput struct {
+	//Profile is the AWS profile name holding the IAM user's long term access keys
+	Profile string
+
+	//SerialNumber is the MFA device to resync, overriding any mfa_serial in the profile
+	SerialNumber string
+
+	//SelectMFADevice is called to choose an MFA device when the user has several and none is given
+	SelectMFADevice MFADeviceSelector
+
+	//Codes is called for two consecutive MFA values generated by the device
+	Codes func(serialNumber string) (string, string, error)
+
+	ProfileSource
+}
+
+//EnrollMFADevice creates and enables a virtual MFA device for the IAM user of the profile
+func EnrollMFADevice(input *MFAEnrollmentInput) (*VirtualMFADevice, error) {
+
+	profileName := input.Profile
+	if len(profileName) == 0 {
+		profileName = profileDefault
+	}
+
+	user, err := newIAMUser(input.ProfileSource, profileName)
+	if err != nil {
+		return nil, err
+	}
+
+	deviceName := input.DeviceName
+	if len(deviceName) == 0 {
+		deviceName = user.name
+	}
+
+	device, err := enrollMFADevice(iam.New(user.session), user.requests, user.name, deviceName, input.Codes)
+	if err != nil {
+		return nil, err
+	}
+	device.AccountID = user.account
+	return device, nil
+}
+
+//enrollMFADevice creates and enables deviceName for userName, deleting it when it cannot be enabled
+func enrollMFADevice(iamInstance iamiface.IAMAPI, requests RequestConfig, userName string, deviceName string, codes MFACodesProvider) (*VirtualMFADevice, error) {
+	ctx, cancel := requests.context()
+	output, err := iamInstance.CreateVirtualMFADeviceWithContext(ctx, &iam.CreateVirtualMFADeviceInput{VirtualMFADeviceName: &deviceName})
+	cancel()
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == iam.ErrCodeEntityAlreadyExistsException {
+			return nil, WithErrorCategory(fmt.Errorf("A virtual MFA device named %s already exists, choose another name with --device-name", deviceName),
+				ErrorCategoryMFA)
+		}
+		return nil, newRequestError("Unable to create virtual MFA device", err)
+	}
+
+	device := &VirtualMFADevice{
+		SerialNumber: *output.VirtualMFADevice.SerialNumber,
+		UserName:     userName,
+		Seed:         string(output.VirtualMFADevice.Base32StringSeed),
+		QRCodePNG:    output.VirtualMFADevice.QRCodePNG,
+	}
+
+	if err := enableMFADevice(iamInstance, requests, device, codes); err != nil {
+		//deleting is not cancelled and holds off exiting on an interrupt, so no stray device is left behind
+		release := requests.hold()
+		ctx, cancel := requests.detached().context()
+		_, derr := iamInstance.DeleteVirtualMFADeviceWithContext(ctx, &iam.DeleteVirtualMFADeviceInput{SerialNumber: &device.SerialNumber})
+		cancel()
+		release()
+		if derr != nil {
+			return nil, fmt.Errorf("%v, deleting virtual MFA device %s also failed - %v", err, device.SerialNumber, derr)
+		}
+		return nil, err
+	}
+
+	return device, nil
+}
+
+//ResyncMFADevice resynchronises the IAM user's MFA device and returns its serial number
+func ResyncMFADevice(input *MFAResyncInput) (string, error) {
+
+	profileName := input.Profile
+	if len(profileName) == 0 {
+		profileName = profileDefault
+	}
+
+	user, err := newIAMUser(input.ProfileSource, profileName, input.SerialNumber)
+	if err != nil {
+		return "", err
+	}
+
+	serialNumber := input.SerialNumber
+	if len(serialNumber) == 0 {
+		serialNumber = user.profile.mfaSerial()
+	}
+
+	iamInstance := iam.New(user.session)
+	serialNumber, err = getIAMUserMFADevice(iamInstance, user.requests, serialNumber, input.SelectMFADevice)
+	if err != nil {
+		return "", err
+	}
+
+	code1, code2, err := input.Codes(serialNumber)
+	if err != nil {
+		return "", err
+	}
+
+	if err := resyncMFADevice(iamInstance, user.requests, user.name, serialNumber, code1, code2); err != nil {
+		return "", err
+	}
+	_ = resetTokenFailures(serialNumber)
+
+	return serialNumber, nil
+}
+
+//resyncMFADevice resynchronises the MFA device serialNumber of userName with two consecutive MFA values
+func resyncMFADevice(iamInstance iamiface.IAMAPI, requests RequestConfig, userName string, serialNumber string, code1 string, code2 string) error {
+	if err := validateConsecutiveTokens(code1, code2); err != nil {
+		return err
+	}
+
+	ctx, cancel := requests.context()
+	defer cancel()
+
+	_, err := iamInstance.ResyncMFADeviceWithContext(ctx, &iam.ResyncMFADeviceInput{
+		UserName:            &userName,
+		SerialNumber:        &serialNumber,
+		AuthenticationCode1: &code1,
+		AuthenticationCode2: &code2,
+	}, withoutRetries)
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == iam.ErrCodeInvalidAuthenticationCodeException {
+			return reportedRequestError(ErrInvalidToken, ErrorCategoryMFA,
+				fmt.Sprintf("%v, enter two consecutive codes from device %s", ErrInvalidToken, serialNumber), err)
+		}
+		return newRequestError(fmt.Sprintf("Unable to resync MFA device %s", serialNumber), err)
+	}
+	return nil
+}
+
+//validateConsecutiveTokens checks code1 and code2 are well formed and not the same MFA value
+func validateConsecutiveTokens(code1 string, code2 string) error {
+	for _, code := range []string{code1, code2} {
+		if err := ValidateToken(code); err != nil {
+			return err
+		}
+	}
+	if code1 == code2 {
+		return WithErrorCategory(errors.New("MFA values must be two consecutive codes, wait for the next code before entering it"), ErrorCategoryMFA)
+	}
+	return nil
+}
+
+func enableMFADevice(iamInstance iamiface.IAMAPI, requests RequestConfig, device *VirtualMFADevice, codes MFACodesProvider) error {
+	code1, code2, err := codes(device)
+	if err != nil {
+		return err
+	}
+
+	if err := validateConsecutiveTokens(code1, code2); err != nil {
+		return err
+	}
+
+	ctx, cancel := requests.context()
+	defer cancel()
+
+	_, err = iamInstance.EnableMFADeviceWithContext(ctx, &iam.EnableMFADeviceInput{
+		UserName:            &device.UserName,
+		SerialNumber:        &device.SerialNumber,
+		AuthenticationCode1: &code1,
+		AuthenticationCode2: &code2,
+	}, withoutRetries)
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == iam.ErrCodeInvalidAuthenticationCodeException {
+			return reportedRequestError(ErrInvalidToken, ErrorCategoryMFA,
+				fmt.Sprintf("%v, enter two consecutive codes from the new device", ErrInvalidToken), err)
+		}
+		return newRequestError("Unable to enable MFA device", err)
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/iam"
+)
+
+func Test_enrollMFADevice(t *testing.T) {
+	const serialNumber = "arn:aws:iam::123456789012:mfa/alice"
+
+	create := func(ctx context.Context, in1 *iam.CreateVirtualMFADeviceInput, opts ...request.Option) (*iam.CreateVirtualMFADeviceOutput, error) {
+		sn := serialNumber
+		return &iam.CreateVirtualMFADeviceOutput{VirtualMFADevice: &iam.VirtualMFADevice{
+			SerialNumber:     &sn,
+			Base32StringSeed: []byte("JBSWY3DPEHPK3PXP"),
+			QRCodePNG:        []byte("png"),
+		}}, nil
+	}
+	enable := func(err error) func(context.Context, *iam.EnableMFADeviceInput, ...request.Option) (*iam.EnableMFADeviceOutput, error) {
+		return func(ctx context.Context, in1 *iam.EnableMFADeviceInput, opts ...request.Option) (*iam.EnableMFADeviceOutput, error) {
+			return &iam.EnableMFADeviceOutput{}, err
+		}
+	}
+	codes := func(code1 string, code2 string, err error) MFACodesProvider {
+		return func(device *VirtualMFADevice) (string, string, error) {
+			return code1, code2, err
+		}
+	}
+
+	tests := []struct {
+		name        string
+		create      func(context.Context, *iam.CreateVirtualMFADeviceInput, ...request.Option) (*iam.CreateVirtualMFADeviceOutput, error)
+		enable      func(context.Context, *iam.EnableMFADeviceInput, ...request.Option) (*iam.EnableMFADeviceOutput, error)
+		codes       MFACodesProvider
+		wantDeleted bool
+		wantErr     bool
+	}{
+		{
+			"Valid/Enabled",
+			create,
+			enable(nil),
+			codes("123456", "654321", nil),
+			false,
+			false,
+		},
+		{
+			"Invalid/DeviceExists",
+			func(ctx context.Context, in1 *iam.CreateVirtualMFADeviceInput, opts ...request.Option) (*iam.CreateVirtualMFADeviceOutput, error) {
+				return nil, awserr.New(iam.ErrCodeEntityAlreadyExistsException, "MFADevice entity at the same path and name already exists", nil)
+			},
+			enable(nil),
+			codes("123456", "654321", nil),
+			false,
+			true,
+		},
+		{
+			"Invalid/PromptFailed",
+			create,
+			enable(nil),
+			codes("", "", errors.New("EOF")),
+			true,
+			true,
+		},
+		{
+			"Invalid/MalformedCode",
+			create,
+			enable(nil),
+			codes("123456", "abc", nil),
+			true,
+			true,
+		},
+		{
+			"Invalid/SameCode",
+			create,
+			enable(nil),
+			codes("123456", "123456", nil),
+			true,
+			true,
+		},
+		{
+			"Invalid/Interrupted",
+			create,
+			enable(nil),
+			codes("", "", context.Canceled),
+			true,
+			true,
+		},
+		{
+			"Invalid/CodesRejected",
+			create,
+			enable(awserr.New(iam.ErrCodeInvalidAuthenticationCodeException, "Authentication code for device is not valid", nil)),
+			codes("123456", "654321", nil),
+			true,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deleted, held := false, false
+			hold := func() func() {
+				held = true
+				return func() { held = false }
+			}
+			iamInstance := &IAMAPIMock{
+				CreateVirtualMFADeviceWithContextFunc: tt.create,
+				EnableMFADeviceWithContextFunc:        tt.enable,
+				DeleteVirtualMFADeviceWithContextFunc: func(ctx context.Context, in1 *iam.DeleteVirtualMFADeviceInput, opts ...request.Option) (*iam.DeleteVirtualMFADeviceOutput, error) {
+					if !held {
+						t.Errorf("enrollMFADevice() deleted the device without holding off exiting")
+					}
+					deleted = *in1.SerialNumber == serialNumber
+					return &iam.DeleteVirtualMFADeviceOutput{}, nil
+				},
+			}
+
+			got, err := enrollMFADevice(iamInstance, RequestConfig{Hold: hold}, "alice", "alice", tt.codes)
+			if held {
+				t.Errorf("enrollMFADevice() did not release the hold on exiting")
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("enrollMFADevice() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if deleted != tt.wantDeleted {
+				t.Errorf("enrollMFADevice() deleted = %v, want %v", deleted, tt.wantDeleted)
+			}
+			if err != nil {
+				return
+			}
+			if got.SerialNumber != serialNumber || got.Seed != "JBSWY3DPEHPK3PXP" || got.UserName != "alice" {
+				t.Errorf("enrollMFADevice() = %+v", got)
+			}
+		})
+	}
+}
+
+func Test_resyncMFADevice(t *testing.T) {
+	const serialNumber = "arn:aws:iam::123456789012:mfa/alice"
+
+	tests := []struct {
+		name    string
+		code1   string
+		code2   string
+		err     error
+		wantErr bool
+	}{
+		{"Valid/Resynced", "123456", "654321", nil, false},
+		{"Invalid/MalformedCode", "123456", "", nil, true},
+		{"Invalid/SameCode", "123456", "123456", nil, true},
+		{"Invalid/CodesRejected", "123456", "654321", awserr.New(iam.ErrCodeInvalidAuthenticationCodeException, "Authentication code for device is not valid", nil), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			iamInstance := &IAMAPIMock{
+				ResyncMFADeviceWithContextFunc: func(ctx context.Context, in1 *iam.ResyncMFADeviceInput, opts ...request.Option) (*iam.ResyncMFADeviceOutput, error) {
+					called = true
+					if *in1.UserName != "alice" || *in1.SerialNumber != serialNumber || *in1.AuthenticationCode1 != tt.code1 || *in1.AuthenticationCode2 != tt.code2 {
+						return nil, errors.New("unexpected input")
+					}
+					return &iam.ResyncMFADeviceOutput{}, tt.err
+				},
+			}
+
+			err := resyncMFADevice(iamInstance, RequestConfig{}, "alice", serialNumber, tt.code1, tt.code2)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("resyncMFADevice() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if called != (tt.err != nil || !tt.wantErr) {
+				t.Errorf("resyncMFADevice() called IAM = %v", called)
+			}
+		})
+	}
+}
@@ -0,0 +1,374 @@
+package aws
+
+import (
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/ini.v1"
+)
+
+var (
+	keyLineRegex = regexp.MustCompile(`^\s*([^=:\s#;\[][^=:]*?)\s*[=:]`)
+)
+
+//profile represents an AWS profile resolved from the credentials and config files
+type profile struct {
+	Name            string
+	AccessKeyID     string
+	SecretAccessKey string
+	Region          string
+	RoleARN         string
+	MFASerial       string
+	RoleSessionName string
+	ExternalID      string
+	DurationSeconds int64
+
+	STSRegionalEndpoints string
+	UseFIPSEndpoint      bool
+	UseDualStackEndpoint bool
+	STSEndpoint          string
+	IAMEndpoint          string
+
+	//StoredKeys is set when the long term keys are held in the AccessKeyStore rather than the credentials file
+	StoredKeys bool
+
+	//Source is the resolved source_profile of a role profile
+	Source *profile
+}
+
+//isRole returns true when the profile assumes a role using its source profile's credentials
+func (p *profile) isRole() bool {
+	return len(p.RoleARN) != 0
+}
+
+//root returns the profile at the end of the source_profile chain which holds the long term keys
+func (p *profile) root() *profile {
+	for p.Source != nil {
+		p = p.Source
+	}
+	return p
+}
+
+//roles returns the role profiles of the source_profile chain, starting with the role closest to the root
+func (p *profile) roles() []*profile {
+	var roles []*profile
+	for x := p; x.Source != nil; x = x.Source {
+		roles = append([]*profile{x}, roles...)
+	}
+	return roles
+}
+
+//mfaSerial returns the first mfa_serial configured along the source_profile chain
+func (p *profile) mfaSerial() string {
+	for x := p; x != nil; x = x.Source {
+		if len(x.MFASerial) != 0 {
+			return x.MFASerial
+		}
+	}
+	return ""
+}
+
+//endpoints returns the region and endpoint settings configured along the source_profile chain
+func (p *profile) endpoints() EndpointConfig {
+	var config EndpointConfig
+	for x := p; x != nil; x = x.Source {
+		config = config.Merge(EndpointConfig{
+			Region:               x.Region,
+			STSRegionalEndpoints: x.STSRegionalEndpoints,
+			UseFIPSEndpoint:      x.UseFIPSEndpoint,
+			UseDualStackEndpoint: x.UseDualStackEndpoint,
+			STSEndpoint:          x.STSEndpoint,
+			IAMEndpoint:          x.IAMEndpoint,
+		})
+	}
+	return config
+}
+
+//profileFile is a parsed AWS credentials or config file along with the line of each section and key
+type profileFile struct {
+	path     string
+	file     *ini.File
+	sections map[string]int
+	keys     map[string]map[string]int
+}
+
+func loadProfileFile(path string, data []byte, invalidErr error) (*profileFile, error) {
+	file, err := ini.Load(data)
+	if err != nil {
+		return nil, &FileError{Path: path, Err: invalidErr}
+	}
+
+	f := &profileFile{
+		path:     path,
+		file:     file,
+		sections: map[string]int{},
+		keys:     map[string]map[string]int{},
+	}
+
+	section := ini.DefaultSection
+	for i, line := range strings.Split(string(data), "\n") {
+		if match := sectionHeaderRegex.FindStringSubmatch(line); match != nil {
+			section = match[1]
+			if _, ok := f.sections[section]; !ok {
+				f.sections[section] = i + 1
+				f.keys[section] = map[string]int{}
+			}
+			continue
+		}
+		if match := keyLineRegex.FindStringSubmatch(line); match != nil && f.keys[section] != nil {
+			f.keys[section][match[1]] = i + 1
+		}
+	}
+
+	return f, nil
+}
+
+func (f *profileFile) section(name string) *ini.Section {
+	if f == nil {
+		return nil
+	}
+	section, err := f.file.GetSection(name)
+	if err != nil {
+		return nil
+	}
+	return section
+}
+
+//location returns the line of key in section, or of the section itself when key is empty
+func (f *profileFile) location(section string, key string) int {
+	if len(key) != 0 {
+		if line, ok := f.keys[section][key]; ok {
+			return line
+		}
+	}
+	return f.sections[section]
+}
+
+//profileResolver merges profiles from the AWS credentials and config files
+type profileResolver struct {
+	credentials *profileFile
+	config      *profileFile
+	keys        AccessKeyStore
+}
+
+//newProfileResolver loads the credentials and config files
+func newProfileResolver(credentialsPath string, configPath string, keys AccessKeyStore) (*profileResolver, error) {
+	optional := keys != nil && len(credentialsPath) == 0 && len(os.Getenv(envSharedCredentialsFile)) == 0
+
+	credentialsPath, err := credentialsFilePath(credentialsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	configPath, err = configFilePath(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	resolver := &profileResolver{
+		keys: keys,
+	}
+
+	data, err := openFile(credentialsPath)
+	if err != nil && (!optional || !os.IsNotExist(err)) {
+		return nil, &FileError{Path: credentialsPath, Err: ErrAWSCredentialsFileNotFound}
+	}
+	if err == nil {
+		resolver.credentials, err = loadProfileFile(credentialsPath, data, ErrInvalidAWSCredentialsFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	data, err = openFile(configPath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		resolver.config, err = loadProfileFile(configPath, data, ErrInvalidAWSConfigFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return resolver, nil
+}
+
+//configSection returns the config file section for profile and its name
+func (r *profileResolver) configSection(name string) (*ini.Section, string) {
+	sectionName := configSectionName(name)
+	if section := r.config.section(sectionName); section != nil {
+		return section, sectionName
+	}
+	if name == profileDefault {
+		sectionName = configProfilePrefix + profileDefault
+		if section := r.config.section(sectionName); section != nil {
+			return section, sectionName
+		}
+	}
+	return nil, ""
+}
+
+//exists returns true when profile is defined in either file or has keys in the AccessKeyStore
+func (r *profileResolver) exists(name string) bool {
+	section, _ := r.configSection(name)
+	return section != nil || r.credentials.section(name) != nil || r.hasStoredKeys(name)
+}
+
+func (r *profileResolver) hasStoredKeys(name string) bool {
+	return r.keys != nil && r.keys.HasAccessKeys(name)
+}
+
+//resolve returns the named profile with its source_profile chain resolved
+func (r *profileResolver) resolve(name string) (*profile, error) {
+	if len(name) == 0 {
+		name = profileDefault
+	}
+
+	if !r.exists(name) {
+		return nil, &ProfileError{Profile: name, Reason: "is not defined in the AWS credentials or config file"}
+	}
+
+	return r.resolveChain(name, nil)
+}
+
+func (r *profileResolver) resolveChain(name string, chain []string) (*profile, error) {
+	p, sourceProfile, err := r.load(name)
+	if err != nil {
+		return nil, err
+	}
+	chain = append(chain, name)
+
+	p.StoredKeys = r.hasStoredKeys(name)
+
+	if !p.isRole() {
+		if err := r.validateKeys(p); err != nil {
+			return nil, err
+		}
+		return p, nil
+	}
+
+	if len(sourceProfile) == 0 {
+		return nil, r.profileError(name, configRoleARN, "has role_arn but no source_profile")
+	}
+
+	if sourceProfile == name && (len(p.AccessKeyID) != 0 || p.StoredKeys) {
+		p.Source = &profile{
+			Name:            name,
+			AccessKeyID:     p.AccessKeyID,
+			SecretAccessKey: p.SecretAccessKey,
+			Region:          p.Region,
+			StoredKeys:      p.StoredKeys,
+		}
+		if err := r.validateKeys(p.Source); err != nil {
+			return nil, err
+		}
+		return p, nil
+	}
+
+	for _, x := range chain {
+		if x == sourceProfile {
+			return nil, r.profileError(name, configSourceProfile,
+				"has a source_profile cycle "+strings.Join(append(chain, sourceProfile), " -> "))
+		}
+	}
+
+	if !r.exists(sourceProfile) {
+		return nil, r.profileError(name, configSourceProfile, "references missing source_profile "+sourceProfile)
+	}
+
+	p.Source, err = r.resolveChain(sourceProfile, chain)
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+//load merges the config and credentials file sections for profile, with the credentials file taking precedence
+func (r *profileResolver) load(name string) (*profile, string, error) {
+	values := map[string]string{}
+
+	if configSection, _ := r.configSection(name); configSection != nil {
+		for _, key := range configSection.Keys() {
+			values[key.Name()] = key.String()
+		}
+	}
+	if credentialsSection := r.credentials.section(name); credentialsSection != nil {
+		for _, key := range credentialsSection.Keys() {
+			values[key.Name()] = key.String()
+		}
+	}
+
+	p := &profile{
+		Name:            name,
+		AccessKeyID:     values[configAWSAccessKeyID],
+		SecretAccessKey: values[configAWSSecretAccessKey],
+		Region:          values[configRegion],
+		RoleARN:         values[configRoleARN],
+		MFASerial:       values[configMFASerial],
+		RoleSessionName: values[configRoleSessionName],
+		ExternalID:      values[configExternalID],
+
+		STSRegionalEndpoints: values[configSTSRegionalEndpoints],
+		STSEndpoint:          values[configSTSEndpoint],
+		IAMEndpoint:          values[configIAMEndpoint],
+	}
+
+	for key, setting := range map[string]*bool{configUseFIPSEndpoint: &p.UseFIPSEndpoint, configUseDualStackEndpoint: &p.UseDualStackEndpoint} {
+		if value, ok := values[key]; ok {
+			var err error
+			*setting, err = strconv.ParseBool(value)
+			if err != nil {
+				return nil, "", r.profileError(name, key, "has invalid "+key+" "+value)
+			}
+		}
+	}
+
+	if durationSeconds, ok := values[configDurationSeconds]; ok {
+		var err error
+		p.DurationSeconds, err = strconv.ParseInt(durationSeconds, 10, 64)
+		if err != nil {
+			return nil, "", r.profileError(name, configDurationSeconds, "has invalid duration_seconds "+durationSeconds)
+		}
+	}
+
+	return p, values[configSourceProfile], nil
+}
+
+//validateKeys checks a profile which does not assume a role holds long term keys
+func (r *profileResolver) validateKeys(p *profile) error {
+	if p.StoredKeys {
+		return nil
+	}
+	if len(p.AccessKeyID) == 0 {
+		return r.profileError(p.Name, "", "is missing "+configAWSAccessKeyID)
+	}
+	if len(p.SecretAccessKey) == 0 {
+		return r.profileError(p.Name, "", "is missing "+configAWSSecretAccessKey)
+	}
+	return nil
+}
+
+//profileError returns a ProfileError pointing at the line key is set on for profile
+func (r *profileResolver) profileError(name string, key string, reason string) error {
+	err := &ProfileError{
+		Profile: name,
+		Reason:  reason,
+	}
+
+	if section := r.credentials.section(name); section != nil && (len(key) == 0 || section.HasKey(key)) {
+		err.Path = r.credentials.path
+		err.Line = r.credentials.location(name, key)
+		return err
+	}
+
+	if _, sectionName := r.configSection(name); len(sectionName) != 0 {
+		err.Path = r.config.path
+		err.Line = r.config.location(sectionName, key)
+	}
+
+	return err
+}
@@ -0,0 +1,466 @@
+package aws
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+//mapAccessKeyStore is an AccessKeyStore holding keys in memory
+type mapAccessKeyStore map[string]*AccessKeys
+
+func (m mapAccessKeyStore) HasAccessKeys(profile string) bool {
+	_, ok := m[profile]
+	return ok
+}
+
+func (m mapAccessKeyStore) AccessKeys(profile string) (*AccessKeys, error) {
+	keys := m[profile]
+	if keys == nil {
+		return nil, errors.New("Incorrect vault passphrase")
+	}
+	return keys, nil
+}
+
+func (m mapAccessKeyStore) SetAccessKeys(profile string, keys *AccessKeys) error {
+	if _, ok := m[profile]; ok && m[profile] == nil {
+		return errors.New("Incorrect vault passphrase")
+	}
+	m[profile] = keys
+	return nil
+}
+
+func newTestProfileResolver(credentials string, config string) (*profileResolver, error) {
+	credentialsFile, err := loadProfileFile("/home/johnsmith/.aws/credentials", []byte(credentials), ErrInvalidAWSCredentialsFile)
+	if err != nil {
+		return nil, err
+	}
+
+	configFile, err := loadProfileFile("/home/johnsmith/.aws/config", []byte(config), ErrInvalidAWSConfigFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return &profileResolver{
+		credentials: credentialsFile,
+		config:      configFile,
+	}, nil
+}
+
+func Test_profileResolverResolve(t *testing.T) {
+	const credentials = `[default]
+aws_access_key_id = blahblah
+aws_secret_access_key = blahblah/blahblah
+
+[candycrush]
+aws_access_key_id = candycrush
+aws_secret_access_key = candycrush/candycrush
+region = ap-southeast-2
+
+[nosecret]
+aws_access_key_id = blahblah
+`
+
+	const config = `[default]
+region = us-east-1
+mfa_serial = arn:aws:iam::123456789012:mfa/johnsmith
+
+[profile candycrush]
+region = eu-west-1
+mfa_serial = arn:aws:iam::123456789012:mfa/candycrush
+
+[profile prod]
+role_arn = arn:aws:iam::210987654321:role/admin
+source_profile = default
+role_session_name = johnsmith
+duration_seconds = 3600
+external_id = blahblah
+
+[profile prod-readonly]
+role_arn = arn:aws:iam::210987654321:role/readonly
+source_profile = prod
+
+[profile missingsource]
+role_arn = arn:aws:iam::210987654321:role/admin
+source_profile = unknown
+
+[profile nosource]
+role_arn = arn:aws:iam::210987654321:role/admin
+
+[profile cycle-a]
+role_arn = arn:aws:iam::210987654321:role/a
+source_profile = cycle-b
+
+[profile cycle-b]
+role_arn = arn:aws:iam::210987654321:role/b
+source_profile = cycle-a
+
+[profile badduration]
+role_arn = arn:aws:iam::210987654321:role/admin
+source_profile = default
+duration_seconds = 1h
+
+[profile nokeys]
+region = us-east-1
+
+[candycrush-notaprofile]
+role_arn = arn:aws:iam::210987654321:role/admin
+source_profile = default
+
+[profile gov]
+role_arn = arn:aws-us-gov:iam::210987654321:role/admin
+source_profile = default
+region = us-gov-west-1
+sts_regional_endpoints = regional
+use_fips_endpoint = true
+use_dualstack_endpoint = false
+sts_endpoint = https://sts.example.com
+iam_endpoint = https://iam.example.com
+
+[profile badfips]
+role_arn = arn:aws:iam::210987654321:role/admin
+source_profile = default
+use_fips_endpoint = maybe
+`
+
+	defaultProfile := &profile{
+		Name:            "default",
+		AccessKeyID:     "blahblah",
+		SecretAccessKey: "blahblah/blahblah",
+		Region:          "us-east-1",
+		MFASerial:       "arn:aws:iam::123456789012:mfa/johnsmith",
+	}
+	prodProfile := &profile{
+		Name:            "prod",
+		RoleARN:         "arn:aws:iam::210987654321:role/admin",
+		RoleSessionName: "johnsmith",
+		ExternalID:      "blahblah",
+		DurationSeconds: 3600,
+		Source:          defaultProfile,
+	}
+
+	type args struct {
+		profile string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    *profile
+		wantErr string
+	}{
+		{
+			"Valid/NoProfileNameDefined",
+			args{
+				profile: "",
+			},
+			defaultProfile,
+			"",
+		},
+		{
+			"Valid/CredentialsTakePrecedence",
+			args{
+				profile: "candycrush",
+			},
+			&profile{
+				Name:            "candycrush",
+				AccessKeyID:     "candycrush",
+				SecretAccessKey: "candycrush/candycrush",
+				Region:          "ap-southeast-2",
+				MFASerial:       "arn:aws:iam::123456789012:mfa/candycrush",
+			},
+			"",
+		},
+		{
+			"Valid/Role",
+			args{
+				profile: "prod",
+			},
+			prodProfile,
+			"",
+		},
+		{
+			"Valid/RoleChain",
+			args{
+				profile: "prod-readonly",
+			},
+			&profile{
+				Name:    "prod-readonly",
+				RoleARN: "arn:aws:iam::210987654321:role/readonly",
+				Source:  prodProfile,
+			},
+			"",
+		},
+		{
+			"Invalid/UnknownProfile",
+			args{
+				profile: "blah",
+			},
+			nil,
+			"profile blah is not defined in the AWS credentials or config file",
+		},
+		{
+			"Invalid/ProfileWithoutPrefix",
+			args{
+				profile: "candycrush-notaprofile",
+			},
+			nil,
+			"profile candycrush-notaprofile is not defined in the AWS credentials or config file",
+		},
+		{
+			"Invalid/MissingSourceProfile",
+			args{
+				profile: "missingsource",
+			},
+			nil,
+			"profile missingsource references missing source_profile unknown at config:22",
+		},
+		{
+			"Invalid/NoSourceProfile",
+			args{
+				profile: "nosource",
+			},
+			nil,
+			"profile nosource has role_arn but no source_profile at config:25",
+		},
+		{
+			"Invalid/SourceProfileCycle",
+			args{
+				profile: "cycle-a",
+			},
+			nil,
+			"profile cycle-b has a source_profile cycle cycle-a -> cycle-b -> cycle-a at config:33",
+		},
+		{
+			"Invalid/DurationSeconds",
+			args{
+				profile: "badduration",
+			},
+			nil,
+			"profile badduration has invalid duration_seconds 1h at config:38",
+		},
+		{
+			"Invalid/MissingSecretKey",
+			args{
+				profile: "nosecret",
+			},
+			nil,
+			"profile nosecret is missing aws_secret_access_key at credentials:10",
+		},
+		{
+			"Invalid/NoKeys",
+			args{
+				profile: "nokeys",
+			},
+			nil,
+			"profile nokeys is missing aws_access_key_id at config:40",
+		},
+		{
+			"Valid/EndpointSettings",
+			args{
+				profile: "gov",
+			},
+			&profile{
+				Name:                 "gov",
+				Region:               "us-gov-west-1",
+				RoleARN:              "arn:aws-us-gov:iam::210987654321:role/admin",
+				STSRegionalEndpoints: "regional",
+				UseFIPSEndpoint:      true,
+				STSEndpoint:          "https://sts.example.com",
+				IAMEndpoint:          "https://iam.example.com",
+				Source:               defaultProfile,
+			},
+			"",
+		},
+		{
+			"Invalid/UseFIPSEndpoint",
+			args{
+				profile: "badfips",
+			},
+			nil,
+			"profile badfips has invalid use_fips_endpoint maybe at config:60",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver, err := newTestProfileResolver(credentials, config)
+			if err != nil {
+				t.Fatalf("newTestProfileResolver() error = %v", err)
+			}
+
+			got, err := resolver.resolve(tt.args.profile)
+			if (err != nil) != (len(tt.wantErr) != 0) || (err != nil && err.Error() != tt.wantErr) {
+				t.Errorf("resolve() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("resolve() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_profileResolverResolveCredentialsOnly(t *testing.T) {
+	type args struct {
+		credentials string
+		profile     string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{
+			"Valid/NoProfileNameDefined",
+			args{
+				credentials: `
+				[default]
+				aws_access_key_id = blahblah
+				aws_secret_access_key = blahblah/blahblah`,
+				profile: "",
+			},
+			false,
+		},
+		{
+			"Valid/NonDefaultProfileNameDefined",
+			args{
+				credentials: `
+				[candycrush]
+				aws_access_key_id = blahblah
+				aws_secret_access_key = blahblah/blahblah`,
+				profile: "candycrush",
+			},
+			false,
+		},
+		{
+			"Valid/SelfSourceProfile",
+			args{
+				credentials: `
+				[candycrush]
+				aws_access_key_id = blahblah
+				aws_secret_access_key = blahblah/blahblah
+				role_arn = arn:aws:iam::210987654321:role/admin
+				source_profile = candycrush`,
+				profile: "candycrush",
+			},
+			false,
+		},
+		{
+			"Invalid/InvalidCredentialsFile",
+			args{
+				credentials: `
+				-[default]
+				_aws_access_key_id = blahblah
+				a-ws_secret_access_key = blahblah/blahblah`,
+				profile: "",
+			},
+			true,
+		},
+		{
+			"Invalid/InvalidProfile",
+			args{
+				credentials: "",
+				profile:     "blah",
+			},
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver, err := newTestProfileResolver(tt.args.credentials, "")
+			if err == nil {
+				_, err = resolver.resolve(tt.args.profile)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("resolve() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_profileResolverResolveAccessKeyStore(t *testing.T) {
+	credentials := `
+[work]
+region = eu-west-1
+`
+	config := `
+[profile prod]
+role_arn = arn:aws:iam::210987654321:role/admin
+source_profile = home
+`
+	store := mapAccessKeyStore{
+		"work": {AccessKeyID: "AKIAWORK", SecretAccessKey: "work/work"},
+		"home": {AccessKeyID: "AKIAHOME", SecretAccessKey: "home/home"},
+	}
+
+	tests := []struct {
+		name    string
+		profile string
+		want    *profile
+		wantErr bool
+	}{
+		{
+			"Valid/StoredKeysWithFileSection",
+			"work",
+			&profile{Name: "work", Region: "eu-west-1", StoredKeys: true},
+			false,
+		},
+		{
+			"Valid/SourceProfileOnlyInStore",
+			"prod",
+			&profile{
+				Name:    "prod",
+				RoleARN: "arn:aws:iam::210987654321:role/admin",
+				Source:  &profile{Name: "home", StoredKeys: true},
+			},
+			false,
+		},
+		{
+			"Invalid/NotDefined",
+			"blah",
+			nil,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver, err := newTestProfileResolver(credentials, config)
+			if err != nil {
+				t.Fatalf("newTestProfileResolver() error = %v", err)
+			}
+			resolver.keys = store
+
+			got, err := resolver.resolve(tt.profile)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("resolve() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("resolve() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_profileRoles(t *testing.T) {
+	root := &profile{Name: "default"}
+	prod := &profile{Name: "prod", RoleARN: "arn:aws:iam::210987654321:role/admin", Source: root}
+	readonly := &profile{Name: "prod-readonly", RoleARN: "arn:aws:iam::210987654321:role/readonly", Source: prod, MFASerial: "readonly"}
+
+	if got := readonly.roles(); !reflect.DeepEqual(got, []*profile{prod, readonly}) {
+		t.Errorf("roles() = %v, want %v", got, []*profile{prod, readonly})
+	}
+	if got := readonly.root(); got != root {
+		t.Errorf("root() = %v, want %v", got, root)
+	}
+	if got := root.roles(); len(got) != 0 {
+		t.Errorf("roles() = %v, want none", got)
+	}
+
+	root.MFASerial = "root"
+	if got := prod.mfaSerial(); got != "root" {
+		t.Errorf("mfaSerial() = %v, want %v", got, "root")
+	}
+	if got := readonly.mfaSerial(); got != "readonly" {
+		t.Errorf("mfaSerial() = %v, want %v", got, "readonly")
+	}
+}
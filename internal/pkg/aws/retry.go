@@ -0,0 +1,71 @@
+package aws
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+const (
+	//DefaultMaxRetries is the number of times a throttled or transient failure is retried by default
+	DefaultMaxRetries int = client.DefaultRetryerMaxNumRetries
+)
+
+//RequestConfig limits how long calls to STS and IAM may take and how often their failures are retried
+type RequestConfig struct {
+	//Context cancels the calls in flight, such as when the user presses Ctrl-C. context.Background() when nil
+	Context context.Context
+
+	//Timeout is how long each call may take including its retries, no limit when zero
+	Timeout time.Duration
+
+	//MaxRetries is how many times a throttled or transient failure is retried
+	MaxRetries int
+
+	//Hold is called before calls undoing others and the func it returns once they are done, so the caller can hold off exiting
+	Hold func() (release func())
+}
+
+//context returns the context of a call, limited to Timeout. The returned func releases it
+func (c RequestConfig) context() (context.Context, context.CancelFunc) {
+	ctx := c.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if c.Timeout == 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, c.Timeout)
+}
+
+//detached returns c without its Context, for calls undoing others which must still be made when c is cancelled
+func (c RequestConfig) detached() RequestConfig {
+	c.Context = nil
+	return c
+}
+
+//hold calls Hold when set and returns the func releasing it
+func (c RequestConfig) hold() func() {
+	if c.Hold == nil {
+		return func() {}
+	}
+	return c.Hold()
+}
+
+//retryer returns the SDK retryer for MaxRetries, using the backoff delays of the SDK
+func (c RequestConfig) retryer() request.Retryer {
+	return client.DefaultRetryer{
+		NumMaxRetries:    c.MaxRetries,
+		MinRetryDelay:    client.DefaultRetryerMinRetryDelay,
+		MinThrottleDelay: client.DefaultRetryerMinThrottleDelay,
+		MaxRetryDelay:    client.DefaultRetryerMaxRetryDelay,
+		MaxThrottleDelay: client.DefaultRetryerMaxThrottleDelay,
+	}
+}
+
+//withoutRetries is the request option of calls carrying an MFA value, which STS and IAM only accept once
+func withoutRetries(r *request.Request) {
+	r.Retryer = client.NoOpRetryer{}
+}
@@ -0,0 +1,148 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+//newFakeSTS returns a fake STS failing the first failures calls with code and the number of calls it received
+func newFakeSTS(failures int32, code string, delay time.Duration) (*httptest.Server, *int32) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= failures {
+			status := http.StatusBadRequest
+			if code == "InternalFailure" {
+				status = http.StatusInternalServerError
+			}
+			w.WriteHeader(status)
+			fmt.Fprintf(w, `<ErrorResponse><Error><Type>Receiver</Type><Code>%s</Code><Message>Failed</Message></Error></ErrorResponse>`, code)
+			return
+		}
+
+		time.Sleep(delay)
+		if err := r.ParseForm(); err == nil && r.Form.Get("Action") == "GetSessionToken" {
+			fmt.Fprint(w, `<GetSessionTokenResponse><GetSessionTokenResult><Credentials>
+  <AccessKeyId>ASIAEXAMPLE</AccessKeyId><SecretAccessKey>blahblah</SecretAccessKey><SessionToken>token</SessionToken>
+  <Expiration>2020-08-01T12:00:00Z</Expiration>
+</Credentials></GetSessionTokenResult></GetSessionTokenResponse>`)
+			return
+		}
+		fmt.Fprint(w, `<GetCallerIdentityResponse><GetCallerIdentityResult>
+  <Arn>arn:aws:iam::123456789012:user/johnsmith</Arn><UserId>AIDAEXAMPLE</UserId><Account>123456789012</Account>
+</GetCallerIdentityResult></GetCallerIdentityResponse>`)
+	}))
+	return server, &calls
+}
+
+func newFakeSTSClient(server *httptest.Server, requests RequestConfig) *sts.STS {
+	return sts.New(newSession(credentials.NewStaticCredentials("AKIAEXAMPLE", "blahblah", ""),
+		EndpointConfig{Region: "us-east-1", STSEndpoint: server.URL}, requests))
+}
+
+func TestRequestConfigRetries(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name         string
+		requests     RequestConfig
+		failures     int32
+		code         string
+		delay        time.Duration
+		tokenCode    string
+		wantCalls    int32
+		wantCategory ErrorCategory
+	}{
+		{
+			"Valid/Retried",
+			RequestConfig{MaxRetries: 2},
+			2,
+			"InternalFailure",
+			0,
+			"",
+			3,
+			"",
+		},
+		{
+			"Invalid/RetriesExhausted",
+			RequestConfig{MaxRetries: 1},
+			2,
+			"InternalFailure",
+			0,
+			"",
+			2,
+			ErrorCategoryUnknown,
+		},
+		{
+			"Invalid/Throttled",
+			RequestConfig{},
+			1,
+			"Throttling",
+			0,
+			"",
+			1,
+			ErrorCategoryThrottling,
+		},
+		{
+			"Invalid/MFAValueNotRetried",
+			RequestConfig{MaxRetries: 3},
+			1,
+			"InternalFailure",
+			0,
+			"123456",
+			1,
+			ErrorCategoryUnknown,
+		},
+		{
+			"Invalid/Timeout",
+			RequestConfig{Timeout: 50 * time.Millisecond},
+			0,
+			"InternalFailure",
+			250 * time.Millisecond,
+			"",
+			1,
+			ErrorCategoryNetwork,
+		},
+		{
+			"Invalid/Cancelled",
+			RequestConfig{Context: cancelled, MaxRetries: 3},
+			0,
+			"InternalFailure",
+			0,
+			"",
+			0,
+			ErrorCategoryNetwork,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, calls := newFakeSTS(tt.failures, tt.code, tt.delay)
+			defer server.Close()
+
+			var err error
+			if len(tt.tokenCode) != 0 {
+				_, err = getSTSSessionToken(newFakeSTSClient(server, tt.requests), tt.requests, tt.tokenCode, "arn:aws:iam::123456789012:mfa/johnsmith", 0)
+			} else {
+				_, err = getSTSIdentity(newFakeSTSClient(server, tt.requests), tt.requests)
+			}
+
+			if (err != nil) != (len(tt.wantCategory) != 0) {
+				t.Fatalf("error = %v, want category %q", err, tt.wantCategory)
+			}
+			if err != nil && ErrorCategoryOf(err) != tt.wantCategory {
+				t.Errorf("ErrorCategoryOf() = %v, want %v for %v", ErrorCategoryOf(err), tt.wantCategory, err)
+			}
+			if got := atomic.LoadInt32(calls); got != tt.wantCalls {
+				t.Errorf("calls = %v, want %v", got, tt.wantCalls)
+			}
+		})
+	}
+}
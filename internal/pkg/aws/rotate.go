@@ -0,0 +1,232 @@
+package aws
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+)
+
+const (
+	accessKeyVerifyAttempts int = 10
+)
+
+var (
+	//accessKeyVerifyDelay is the wait between attempts to use a new access key
+	accessKeyVerifyDelay = 3 * time.Second
+)
+
+//AccessKeyRotation describes the access keys changed by RotateAccessKeys
+type AccessKeyRotation struct {
+	//UserName is the IAM user owning the keys
+	UserName string
+
+	//OldAccessKeyID is the deleted access key the profile used
+	OldAccessKeyID string
+
+	//NewAccessKeyID is the access key the profile now uses
+	NewAccessKeyID string
+
+	//DeletedAccessKeyID is an inactive access key deleted to stay within the limit of two keys per user
+	DeletedAccessKeyID string
+}
+
+//RotateAccessKeys replaces the long term access keys of the profile with a new pair
+func RotateAccessKeys(input *STSCredentialsInput) (*AccessKeyRotation, error) {
+
+	profileName := input.Profile
+	if len(profileName) == 0 {
+		profileName = profileDefault
+	}
+
+	user, err := newIAMUser(input.ProfileSource, profileName, input.SerialNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := user.session.Config.Credentials.Get()
+	if err != nil {
+		return nil, err
+	}
+	oldKeys := &AccessKeys{AccessKeyID: value.AccessKeyID, SecretAccessKey: value.SecretAccessKey}
+
+	//IAM only accepts session credentials which were issued with MFA, users without a device use their keys directly
+	iamSession := user.session
+	creds, err := GenerateSTSCredentials(input)
+	if err != nil && !errors.Is(err, ErrNoMFADeviceForUser) {
+		return nil, err
+	}
+	if creds != nil {
+		iamSession = user.session.Copy(&aws.Config{Credentials: credentials.NewStaticCredentials(creds.AWSAccessKeyID, creds.AWSSecretAccessKey, creds.AWSSessionToken)})
+	}
+
+	newSTS := func(keys *AccessKeys) stsiface.STSAPI {
+		return sts.New(user.session.Copy(&aws.Config{Credentials: credentials.NewStaticCredentials(keys.AccessKeyID, keys.SecretAccessKey, "")}))
+	}
+
+	save := func(keys *AccessKeys) error {
+		if user.profile.StoredKeys {
+			return input.AccessKeyStore.SetAccessKeys(user.profile.Name, keys)
+		}
+		return WriteCredentialsFileKeys(input.CredentialsFile, user.profile.Name, keys)
+	}
+
+	return rotateAccessKeys(iam.New(iamSession), newSTS, input.Requests, user.arn, user.name, oldKeys, save)
+}
+
+//rotateAccessKeys replaces oldKeys of userName with a new access key, undoing the steps taken when one fails
+func rotateAccessKeys(iamInstance iamiface.IAMAPI, newSTS func(keys *AccessKeys) stsiface.STSAPI, requests RequestConfig, userARN string, userName string,
+	oldKeys *AccessKeys, save func(keys *AccessKeys) error) (*AccessKeyRotation, error) {
+
+	rotation := &AccessKeyRotation{UserName: userName, OldAccessKeyID: oldKeys.AccessKeyID}
+
+	spare, err := spareAccessKey(iamInstance, requests, userName, oldKeys.AccessKeyID)
+	if err != nil {
+		return nil, err
+	}
+	if spare != nil {
+		if *spare.Status == iam.StatusTypeActive {
+			return nil, fmt.Errorf("User %s already has the limit of two access keys and %s is active, delete it before rotating %s",
+				userName, *spare.AccessKeyId, oldKeys.AccessKeyID)
+		}
+		//IAM refuses a third key, so the spare is deleted first and cannot be restored when rotating fails
+		if err := deleteAccessKey(iamInstance, requests, userName, *spare.AccessKeyId); err != nil {
+			return nil, err
+		}
+		rotation.DeletedAccessKeyID = *spare.AccessKeyId
+	}
+
+	//undoing is not cancelled and holds off exiting on an interrupt, as it keeps the user from being left without a working key
+	undoRequests := requests.detached()
+	var undo []func() error
+	rollback := func(err error) (*AccessKeyRotation, error) {
+		defer requests.hold()()
+		for i := len(undo) - 1; i >= 0; i-- {
+			if rerr := undo[i](); rerr != nil {
+				return nil, fmt.Errorf("%v, rolling back also failed - %v", err, rerr)
+			}
+		}
+		if len(rotation.DeletedAccessKeyID) != 0 {
+			return nil, fmt.Errorf("%w, inactive access key %s was deleted and is not restored", err, rotation.DeletedAccessKeyID)
+		}
+		return nil, err
+	}
+
+	ctx, cancel := requests.context()
+	output, err := iamInstance.CreateAccessKeyWithContext(ctx, &iam.CreateAccessKeyInput{UserName: &userName})
+	cancel()
+	if err != nil {
+		return nil, newRequestError("Unable to create access key", err)
+	}
+	newKeys := &AccessKeys{AccessKeyID: *output.AccessKey.AccessKeyId, SecretAccessKey: *output.AccessKey.SecretAccessKey}
+	rotation.NewAccessKeyID = newKeys.AccessKeyID
+	undo = append(undo, func() error { return deleteAccessKey(iamInstance, undoRequests, userName, newKeys.AccessKeyID) })
+
+	if err := verifyAccessKeys(newSTS(newKeys), requests, userARN); err != nil {
+		return rollback(err)
+	}
+
+	if err := save(newKeys); err != nil {
+		return rollback(err)
+	}
+	undo = append(undo, func() error { return save(oldKeys) })
+
+	if err := updateAccessKey(iamInstance, requests, userName, oldKeys.AccessKeyID, iam.StatusTypeInactive); err != nil {
+		return rollback(err)
+	}
+	undo = append(undo, func() error { return updateAccessKey(iamInstance, undoRequests, userName, oldKeys.AccessKeyID, iam.StatusTypeActive) })
+
+	if err := deleteAccessKey(iamInstance, requests, userName, oldKeys.AccessKeyID); err != nil {
+		return rollback(err)
+	}
+
+	return rotation, nil
+}
+
+//spareAccessKey returns the access key of userName other than accessKeyID, or nil
+func spareAccessKey(iamInstance iamiface.IAMAPI, requests RequestConfig, userName string, accessKeyID string) (*iam.AccessKeyMetadata, error) {
+	ctx, cancel := requests.context()
+	defer cancel()
+
+	output, err := iamInstance.ListAccessKeysWithContext(ctx, &iam.ListAccessKeysInput{UserName: &userName})
+	if err != nil {
+		return nil, newRequestError("Unable to list access keys", err)
+	}
+
+	var spare *iam.AccessKeyMetadata
+	found := false
+	for _, x := range output.AccessKeyMetadata {
+		if *x.AccessKeyId == accessKeyID {
+			found = true
+		} else {
+			spare = x
+		}
+	}
+
+	if !found {
+		return nil, fmt.Errorf("Access key %s does not belong to user %s", accessKeyID, userName)
+	}
+	return spare, nil
+}
+
+//verifyAccessKeys checks the client authenticates as userARN while the new access key propagates
+func verifyAccessKeys(stsInstance stsiface.STSAPI, requests RequestConfig, userARN string) error {
+	var done <-chan struct{}
+	if requests.Context != nil {
+		done = requests.Context.Done()
+	}
+
+	var err error
+	for i := 0; i < accessKeyVerifyAttempts; i++ {
+		if i > 0 {
+			select {
+			case <-done:
+			case <-time.After(accessKeyVerifyDelay):
+			}
+		}
+		if requests.Context != nil && requests.Context.Err() != nil {
+			err = requests.Context.Err()
+			break
+		}
+
+		var identity *STSIdentity
+		identity, err = getSTSIdentity(stsInstance, requests)
+		if err != nil {
+			continue
+		}
+		if identity.ARN != userARN {
+			return fmt.Errorf("New access key authenticates as %s instead of %s", identity.ARN, userARN)
+		}
+		return nil
+	}
+	return fmt.Errorf("New access key could not be used - %w", err)
+}
+
+func updateAccessKey(iamInstance iamiface.IAMAPI, requests RequestConfig, userName string, accessKeyID string, status string) error {
+	ctx, cancel := requests.context()
+	defer cancel()
+
+	_, err := iamInstance.UpdateAccessKeyWithContext(ctx, &iam.UpdateAccessKeyInput{UserName: &userName, AccessKeyId: &accessKeyID, Status: &status})
+	if err != nil {
+		return newRequestError(fmt.Sprintf("Unable to set access key %s %s", accessKeyID, strings.ToLower(status)), err)
+	}
+	return nil
+}
+
+func deleteAccessKey(iamInstance iamiface.IAMAPI, requests RequestConfig, userName string, accessKeyID string) error {
+	ctx, cancel := requests.context()
+	defer cancel()
+
+	_, err := iamInstance.DeleteAccessKeyWithContext(ctx, &iam.DeleteAccessKeyInput{UserName: &userName, AccessKeyId: &accessKeyID})
+	if err != nil {
+		return newRequestError(fmt.Sprintf("Unable to delete access key %s", accessKeyID), err)
+	}
+	return nil
+}
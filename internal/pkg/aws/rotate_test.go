@@ -0,0 +1,335 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+)
+
+//fakeAccessKeys holds the access keys of a single IAM user, failing the named IAM call when asked to
+type fakeAccessKeys struct {
+	status map[string]string
+	fail   string
+}
+
+func (f *fakeAccessKeys) iam() *IAMAPIMock {
+	failure := func(call string) error {
+		if f.fail == call {
+			return awserr.New(iam.ErrCodeServiceFailureException, call+" failed", nil)
+		}
+		return nil
+	}
+
+	return &IAMAPIMock{
+		ListAccessKeysWithContextFunc: func(ctx context.Context, in1 *iam.ListAccessKeysInput, opts ...request.Option) (*iam.ListAccessKeysOutput, error) {
+			output := &iam.ListAccessKeysOutput{}
+			for id, status := range f.status {
+				id, status := id, status
+				output.AccessKeyMetadata = append(output.AccessKeyMetadata, &iam.AccessKeyMetadata{AccessKeyId: &id, Status: &status})
+			}
+			return output, failure("ListAccessKeys")
+		},
+		CreateAccessKeyWithContextFunc: func(ctx context.Context, in1 *iam.CreateAccessKeyInput, opts ...request.Option) (*iam.CreateAccessKeyOutput, error) {
+			if err := failure("CreateAccessKey"); err != nil {
+				return nil, err
+			}
+			if len(f.status) >= 2 {
+				return nil, awserr.New(iam.ErrCodeLimitExceededException, "Cannot exceed quota for AccessKeysPerUser: 2", nil)
+			}
+			id, secret := "AKIANEW", "newsecret"
+			f.status[id] = iam.StatusTypeActive
+			return &iam.CreateAccessKeyOutput{AccessKey: &iam.AccessKey{AccessKeyId: &id, SecretAccessKey: &secret}}, nil
+		},
+		UpdateAccessKeyWithContextFunc: func(ctx context.Context, in1 *iam.UpdateAccessKeyInput, opts ...request.Option) (*iam.UpdateAccessKeyOutput, error) {
+			if err := failure("UpdateAccessKey" + *in1.Status); err != nil {
+				return nil, err
+			}
+			f.status[*in1.AccessKeyId] = *in1.Status
+			return &iam.UpdateAccessKeyOutput{}, nil
+		},
+		DeleteAccessKeyWithContextFunc: func(ctx context.Context, in1 *iam.DeleteAccessKeyInput, opts ...request.Option) (*iam.DeleteAccessKeyOutput, error) {
+			if err := failure("DeleteAccessKey" + *in1.AccessKeyId); err != nil {
+				return nil, err
+			}
+			delete(f.status, *in1.AccessKeyId)
+			return &iam.DeleteAccessKeyOutput{}, nil
+		},
+	}
+}
+
+func Test_rotateAccessKeys(t *testing.T) {
+	defer func(delay time.Duration) { accessKeyVerifyDelay = delay }(accessKeyVerifyDelay)
+	accessKeyVerifyDelay = 0
+
+	const userARN = "arn:aws:iam::123456789012:user/alice"
+	oldKeys := &AccessKeys{AccessKeyID: "AKIAOLD", SecretAccessKey: "oldsecret"}
+
+	identity := func(principalARN string) func(*AccessKeys) stsiface.STSAPI {
+		return func(keys *AccessKeys) stsiface.STSAPI {
+			return &STSAPIMock{
+				GetCallerIdentityWithContextFunc: func(ctx context.Context, in1 *sts.GetCallerIdentityInput, opts ...request.Option) (*sts.GetCallerIdentityOutput, error) {
+					account, userID := "123456789012", "AIDAALICE"
+					return &sts.GetCallerIdentityOutput{Account: &account, Arn: &principalARN, UserId: &userID}, nil
+				},
+			}
+		}
+	}
+
+	tests := []struct {
+		name      string
+		status    map[string]string
+		fail      string
+		newSTS    func(*AccessKeys) stsiface.STSAPI
+		saveErr   error
+		want      *AccessKeyRotation
+		wantKeys  []string
+		wantSaved *AccessKeys
+		wantErr   bool
+	}{
+		{
+			"Valid/Rotated",
+			map[string]string{"AKIAOLD": iam.StatusTypeActive},
+			"",
+			identity(userARN),
+			nil,
+			&AccessKeyRotation{UserName: "alice", OldAccessKeyID: "AKIAOLD", NewAccessKeyID: "AKIANEW"},
+			[]string{"AKIANEW"},
+			&AccessKeys{AccessKeyID: "AKIANEW", SecretAccessKey: "newsecret"},
+			false,
+		},
+		{
+			"Valid/InactiveKeyDeleted",
+			map[string]string{"AKIAOLD": iam.StatusTypeActive, "AKIASPARE": iam.StatusTypeInactive},
+			"",
+			identity(userARN),
+			nil,
+			&AccessKeyRotation{UserName: "alice", OldAccessKeyID: "AKIAOLD", NewAccessKeyID: "AKIANEW", DeletedAccessKeyID: "AKIASPARE"},
+			[]string{"AKIANEW"},
+			&AccessKeys{AccessKeyID: "AKIANEW", SecretAccessKey: "newsecret"},
+			false,
+		},
+		{
+			"Invalid/ActiveKeyLimit",
+			map[string]string{"AKIAOLD": iam.StatusTypeActive, "AKIASPARE": iam.StatusTypeActive},
+			"",
+			identity(userARN),
+			nil,
+			nil,
+			[]string{"AKIAOLD", "AKIASPARE"},
+			oldKeys,
+			true,
+		},
+		{
+			"Invalid/KeyOfAnotherUser",
+			map[string]string{"AKIAOTHER": iam.StatusTypeActive},
+			"",
+			identity(userARN),
+			nil,
+			nil,
+			[]string{"AKIAOTHER"},
+			oldKeys,
+			true,
+		},
+		{
+			"Invalid/CreateFailed",
+			map[string]string{"AKIAOLD": iam.StatusTypeActive},
+			"CreateAccessKey",
+			identity(userARN),
+			nil,
+			nil,
+			[]string{"AKIAOLD"},
+			oldKeys,
+			true,
+		},
+		{
+			"Invalid/NewKeyWrongUser",
+			map[string]string{"AKIAOLD": iam.StatusTypeActive},
+			"",
+			identity("arn:aws:iam::123456789012:user/bob"),
+			nil,
+			nil,
+			[]string{"AKIAOLD"},
+			oldKeys,
+			true,
+		},
+		{
+			"Invalid/SaveFailed",
+			map[string]string{"AKIAOLD": iam.StatusTypeActive},
+			"",
+			identity(userARN),
+			errors.New("Incorrect vault passphrase"),
+			nil,
+			[]string{"AKIAOLD"},
+			oldKeys,
+			true,
+		},
+		{
+			"Invalid/DeactivateFailed",
+			map[string]string{"AKIAOLD": iam.StatusTypeActive},
+			"UpdateAccessKey" + iam.StatusTypeInactive,
+			identity(userARN),
+			nil,
+			nil,
+			[]string{"AKIAOLD"},
+			oldKeys,
+			true,
+		},
+		{
+			"Invalid/DeleteFailed",
+			map[string]string{"AKIAOLD": iam.StatusTypeActive},
+			"DeleteAccessKeyAKIAOLD",
+			identity(userARN),
+			nil,
+			nil,
+			[]string{"AKIAOLD"},
+			oldKeys,
+			true,
+		},
+		{
+			"Invalid/RollbackFailed",
+			map[string]string{"AKIAOLD": iam.StatusTypeActive},
+			"DeleteAccessKeyAKIANEW",
+			identity("arn:aws:iam::123456789012:user/bob"),
+			nil,
+			nil,
+			[]string{"AKIANEW", "AKIAOLD"},
+			oldKeys,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &fakeAccessKeys{status: tt.status, fail: tt.fail}
+			saved := oldKeys
+			save := func(keys *AccessKeys) error {
+				if tt.saveErr != nil {
+					return tt.saveErr
+				}
+				saved = keys
+				return nil
+			}
+
+			got, err := rotateAccessKeys(f.iam(), tt.newSTS, RequestConfig{}, userARN, "alice", oldKeys, save)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("rotateAccessKeys() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rotateAccessKeys() = %v, want %v", got, tt.want)
+			}
+
+			var keys []string
+			for id, status := range f.status {
+				if status != iam.StatusTypeActive {
+					t.Errorf("rotateAccessKeys() left access key %s %s", id, status)
+				}
+				keys = append(keys, id)
+			}
+			sort.Strings(keys)
+			if !reflect.DeepEqual(keys, tt.wantKeys) {
+				t.Errorf("rotateAccessKeys() access keys = %v, want %v", keys, tt.wantKeys)
+			}
+			if *saved != *tt.wantSaved {
+				t.Errorf("rotateAccessKeys() saved = %v, want %v", saved, tt.wantSaved)
+			}
+		})
+	}
+}
+
+func Test_rotateAccessKeysInterrupted(t *testing.T) {
+	const userARN = "arn:aws:iam::123456789012:user/alice"
+	oldKeys := &AccessKeys{AccessKeyID: "AKIAOLD", SecretAccessKey: "oldsecret"}
+
+	f := &fakeAccessKeys{status: map[string]string{"AKIAOLD": iam.StatusTypeActive}}
+	mock := f.iam()
+	update, remove := mock.UpdateAccessKeyWithContextFunc, mock.DeleteAccessKeyWithContextFunc
+	mock.UpdateAccessKeyWithContextFunc = func(ctx context.Context, in1 *iam.UpdateAccessKeyInput, opts ...request.Option) (*iam.UpdateAccessKeyOutput, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, awserr.New(request.CanceledErrorCode, "request context canceled", err)
+		}
+		return update(ctx, in1, opts...)
+	}
+	held := false
+	hold := func() func() {
+		held = true
+		return func() { held = false }
+	}
+	mock.DeleteAccessKeyWithContextFunc = func(ctx context.Context, in1 *iam.DeleteAccessKeyInput, opts ...request.Option) (*iam.DeleteAccessKeyOutput, error) {
+		if *in1.AccessKeyId == "AKIANEW" && !held {
+			t.Errorf("rotateAccessKeys() deleted the new key without holding off exiting")
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, awserr.New(request.CanceledErrorCode, "request context canceled", err)
+		}
+		return remove(ctx, in1, opts...)
+	}
+
+	newSTS := func(keys *AccessKeys) stsiface.STSAPI {
+		return &STSAPIMock{
+			GetCallerIdentityWithContextFunc: func(ctx context.Context, in1 *sts.GetCallerIdentityInput, opts ...request.Option) (*sts.GetCallerIdentityOutput, error) {
+				account, arn, userID := "123456789012", userARN, "AIDAALICE"
+				return &sts.GetCallerIdentityOutput{Account: &account, Arn: &arn, UserId: &userID}, nil
+			},
+		}
+	}
+
+	//Ctrl-C once the new key is saved must still delete the new key
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	saved := oldKeys
+	save := func(keys *AccessKeys) error {
+		saved = keys
+		cancel()
+		return nil
+	}
+
+	if _, err := rotateAccessKeys(mock, newSTS, RequestConfig{Context: ctx, Hold: hold}, userARN, "alice", oldKeys, save); err == nil {
+		t.Fatalf("rotateAccessKeys() expected an error")
+	}
+	if held {
+		t.Errorf("rotateAccessKeys() did not release the hold on exiting")
+	}
+	if !reflect.DeepEqual(f.status, map[string]string{"AKIAOLD": iam.StatusTypeActive}) {
+		t.Errorf("rotateAccessKeys() access keys = %v, want only the old key active", f.status)
+	}
+	if *saved != *oldKeys {
+		t.Errorf("rotateAccessKeys() saved = %v, want %v", saved, oldKeys)
+	}
+}
+
+func Test_verifyAccessKeysInterrupted(t *testing.T) {
+	defer func(delay time.Duration) { accessKeyVerifyDelay = delay }(accessKeyVerifyDelay)
+	accessKeyVerifyDelay = time.Hour
+
+	//Ctrl-C while the new key propagates must not wait out the delay between attempts
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mock := &STSAPIMock{
+		GetCallerIdentityWithContextFunc: func(ctx context.Context, in1 *sts.GetCallerIdentityInput, opts ...request.Option) (*sts.GetCallerIdentityOutput, error) {
+			time.AfterFunc(10*time.Millisecond, cancel)
+			return nil, awserr.New("InvalidClientTokenId", "The security token included in the request is invalid.", nil)
+		},
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- verifyAccessKeys(mock, RequestConfig{Context: ctx}, "arn:aws:iam::123456789012:user/alice")
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("verifyAccessKeys() error = %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("verifyAccessKeys() did not return once cancelled")
+	}
+}
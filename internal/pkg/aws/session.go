@@ -2,48 +2,25 @@ package aws
 
 import (
 	"bytes"
+	"mfa4aws/internal/pkg/appfs"
+	"os"
 	"os/user"
 	"path/filepath"
 
-	"gopkg.in/ini.v1"
-
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/spf13/afero"
-)
-
-var (
-	appFs = afero.NewOsFs()
 )
 
-func validateProfile(file []byte, profile string) error {
-	const (
-		profileDefault string = "default"
-
-		credentialsAWSAccessKeyID     string = "aws_access_key_id"
-		credentialsAWSSecretAccessKey string = "aws_secret_access_key"
-	)
-
-	if len(profile) == 0 {
-		profile = profileDefault
-	}
-
-	creds, err := ini.Load(file)
-	if err != nil {
-		return ErrInvalidAWSCredentialsFile
-	}
-
-	if !creds.Section(profile).HasKey(credentialsAWSAccessKeyID) ||
-		!creds.Section(profile).HasKey(credentialsAWSSecretAccessKey) {
-		return ErrInvalidAWSCredentialsFile
-	}
+const (
+	profileDefault string = "default"
 
-	return nil
-}
+	envSharedCredentialsFile string = "AWS_SHARED_CREDENTIALS_FILE"
+	envConfigFile            string = "AWS_CONFIG_FILE"
+)
 
 func openFile(path string) ([]byte, error) {
-	f, err := appFs.Open(path)
+	f, err := appfs.Fs.Open(path)
 	if err != nil {
 		return nil, err
 	}
@@ -57,34 +34,113 @@ func openFile(path string) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
-func createSession(path string, profile string) (*session.Session, error) {
+//credentialsFilePath returns path, or else $AWS_SHARED_CREDENTIALS_FILE or $HOME/.aws/credentials when path is empty
+func credentialsFilePath(path string) (string, error) {
 	const (
 		awsCredentialsFolder string = ".aws"
 		awsCredentialsFile   string = "credentials"
 	)
 
-	if len(path) == 0 {
-		user, err := user.Current()
-		if err != nil {
-			return nil, err
-		}
-		path = filepath.Join(user.HomeDir, awsCredentialsFolder, awsCredentialsFile)
+	if len(path) != 0 {
+		return path, nil
+	}
+	if path := os.Getenv(envSharedCredentialsFile); len(path) != 0 {
+		return path, nil
 	}
 
-	f, err := openFile(path)
+	user, err := user.Current()
 	if err != nil {
-		return nil, ErrAWSCredentialsFileNotFound
+		return "", err
 	}
+	return filepath.Join(user.HomeDir, awsCredentialsFolder, awsCredentialsFile), nil
+}
 
-	if err := validateProfile(f, profile); err != nil {
-		return nil, err
+//ProfileFiles returns the absolute paths of the credentials and config files
+func ProfileFiles(credentialsPath string, configPath string) (string, string, error) {
+	credentialsPath, err := credentialsFilePath(credentialsPath)
+	if err != nil {
+		return "", "", err
+	}
+	if credentialsPath, err = filepath.Abs(credentialsPath); err != nil {
+		return "", "", err
 	}
 
-	awsSession := session.Must(session.NewSessionWithOptions(session.Options{
-		Config: aws.Config{
-			Credentials: credentials.NewSharedCredentials(path, profile),
-		},
-	}))
+	configPath, err = configFilePath(configPath)
+	if err != nil {
+		return "", "", err
+	}
+	if configPath, err = filepath.Abs(configPath); err != nil {
+		return "", "", err
+	}
+
+	return credentialsPath, configPath, nil
+}
 
-	return awsSession, nil
+//ProfileSource is where the long term keys of a profile are read from and how AWS is reached with them
+type ProfileSource struct {
+	//AccessKeyStore holds long term keys which take precedence over those in the credentials file
+	AccessKeyStore AccessKeyStore
+
+	//CredentialsFile is the AWS credentials file, $AWS_SHARED_CREDENTIALS_FILE or $HOME/.aws/credentials when empty
+	CredentialsFile string
+
+	//ConfigFile is the AWS config file, $AWS_CONFIG_FILE or $HOME/.aws/config when empty
+	ConfigFile string
+
+	//Endpoints selects the region and endpoints, overriding those of the profile
+	Endpoints EndpointConfig
+
+	//Requests limits how long calls to STS and IAM may take and how often they are retried
+	Requests RequestConfig
+}
+
+//createSession returns a session authenticated with the long term keys at the end of the source_profile chain of profileName
+func createSession(source ProfileSource, profileName string, arns ...string) (*session.Session, *profile, error) {
+	resolver, err := newProfileResolver(source.CredentialsFile, source.ConfigFile, source.AccessKeyStore)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	p, err := resolver.resolve(profileName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	config, err := sessionEndpoints(source.Endpoints, p, arns...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	root := p.root()
+	if root.StoredKeys {
+		return newSession(credentials.NewCredentials(&accessKeyStoreProvider{keys: source.AccessKeyStore, profile: root.Name}), config, source.Requests), p, nil
+	}
+	return newSession(credentials.NewStaticCredentials(root.AccessKeyID, root.SecretAccessKey, ""), config, source.Requests), p, nil
+}
+
+//sessionEndpoints returns override with the settings it leaves empty taken from p
+func sessionEndpoints(override EndpointConfig, p *profile, arns ...string) (EndpointConfig, error) {
+	arns = append(arns, p.mfaSerial())
+	for _, role := range p.roles() {
+		arns = append(arns, role.RoleARN)
+	}
+
+	config := override.Merge(p.endpoints()).withPartitionRegion(arns...)
+	return config, config.validate()
+}
+
+func newSession(creds *credentials.Credentials, endpoints EndpointConfig, requests RequestConfig) *session.Session {
+	config := aws.Config{
+		Credentials:         creds,
+		EndpointResolver:    endpointsResolver(endpoints),
+		STSRegionalEndpoint: endpoints.stsRegionalEndpoint(),
+		Retryer:             requests.retryer(),
+	}
+	if len(endpoints.Region) != 0 {
+		config.Region = &endpoints.Region
+	}
+
+	return session.Must(session.NewSessionWithOptions(session.Options{
+		Config: config,
+	}))
 }
@@ -1,71 +1,13 @@
 package aws
 
 import (
+	"os"
+	"os/user"
+	"path/filepath"
 	"reflect"
 	"testing"
 )
 
-func Test_validateProfile(t *testing.T) {
-	type args struct {
-		file    []byte
-		profile string
-	}
-	tests := []struct {
-		name    string
-		args    args
-		wantErr bool
-	}{
-		{
-			"Valid/NoProfileNameDefined",
-			args{
-				file: []byte(`
-				[default]
-				aws_access_key_id = blahblah
-				aws_secret_access_key = blahblah/blahblah`),
-				profile: "",
-			},
-			false,
-		},
-		{
-			"Valid/NonDefaultProfileNameDefined",
-			args{
-				file: []byte(`
-				[candycrush]
-				aws_access_key_id = blahblah
-				aws_secret_access_key = blahblah/blahblah`),
-				profile: "candycrush",
-			},
-			false,
-		},
-		{
-			"Invalid/InvalidCredentialsFile",
-			args{
-				file: []byte(`
-				-[default]
-				_aws_access_key_id = blahblah
-				a-ws_secret_access_key = blahblah/blahblah`),
-				profile: "",
-			},
-			true,
-		},
-		{
-			"Invalid/InvalidProfile",
-			args{
-				file:    []byte(""),
-				profile: "blah",
-			},
-			true,
-		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			if err := validateProfile(tt.args.file, tt.args.profile); (err != nil) != tt.wantErr {
-				t.Errorf("validateProfile() error = %v, wantErr %v", err, tt.wantErr)
-			}
-		})
-	}
-}
-
 func Test_openFile(t *testing.T) {
 
 	type args struct {
@@ -170,7 +112,7 @@ func Test_createSessions(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := createSession(tt.args.path, tt.args.profile)
+			_, _, err := createSession(ProfileSource{CredentialsFile: tt.args.path}, tt.args.profile)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("createSession() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -178,3 +120,122 @@ func Test_createSessions(t *testing.T) {
 		})
 	}
 }
+
+func Test_createSessionFileNotFound(t *testing.T) {
+	//a credentials file which was asked for must exist even when the keys are held elsewhere
+	for _, keys := range []AccessKeyStore{nil, mapAccessKeyStore{"default": {AccessKeyID: "AKIASTORED", SecretAccessKey: "blahblah"}}} {
+		_, _, err := createSession(ProfileSource{AccessKeyStore: keys, CredentialsFile: "/shhss/ssjjss"}, "default")
+		if ferr, ok := err.(*FileError); !ok || ferr.Path != "/shhss/ssjjss" || ferr.Err != ErrAWSCredentialsFileNotFound {
+			t.Fatalf("createSession() error = %v, want ErrAWSCredentialsFileNotFound at /shhss/ssjjss", err)
+		}
+		if want := "AWS Credentials file not found at /shhss/ssjjss"; err.Error() != want {
+			t.Errorf("createSession() error = %v, want %v", err, want)
+		}
+	}
+}
+
+func Test_sharedFilePaths(t *testing.T) {
+	user, err := user.Current()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		pathFunc func(string) (string, error)
+		envName  string
+		path     string
+		env      string
+		want     string
+	}{
+		{
+			"Valid/CredentialsFileDefault",
+			credentialsFilePath,
+			envSharedCredentialsFile,
+			"",
+			"",
+			filepath.Join(user.HomeDir, ".aws", "credentials"),
+		},
+		{
+			"Valid/CredentialsFileEnvironment",
+			credentialsFilePath,
+			envSharedCredentialsFile,
+			"",
+			"/ci/credentials",
+			"/ci/credentials",
+		},
+		{
+			"Valid/CredentialsFilePathOverridesEnvironment",
+			credentialsFilePath,
+			envSharedCredentialsFile,
+			"/flag/credentials",
+			"/ci/credentials",
+			"/flag/credentials",
+		},
+		{
+			"Valid/ConfigFileDefault",
+			configFilePath,
+			envConfigFile,
+			"",
+			"",
+			filepath.Join(user.HomeDir, ".aws", "config"),
+		},
+		{
+			"Valid/ConfigFileEnvironment",
+			configFilePath,
+			envConfigFile,
+			"",
+			"/ci/config",
+			"/ci/config",
+		},
+		{
+			"Valid/ConfigFilePathOverridesEnvironment",
+			configFilePath,
+			envConfigFile,
+			"/flag/config",
+			"/ci/config",
+			"/flag/config",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer restoreEnv(tt.envName)()
+			os.Setenv(tt.envName, tt.env)
+
+			got, err := tt.pathFunc(tt.path)
+			if err != nil {
+				t.Fatalf("path error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("path = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProfileFiles(t *testing.T) {
+	defer restoreEnv(envSharedCredentialsFile)()
+	defer restoreEnv(envConfigFile)()
+	os.Setenv(envSharedCredentialsFile, "ci/credentials")
+	os.Setenv(envConfigFile, "")
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := user.Current()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	credentialsPath, configPath, err := ProfileFiles("", "")
+	if err != nil {
+		t.Fatalf("ProfileFiles() error = %v", err)
+	}
+	if want := filepath.Join(wd, "ci", "credentials"); credentialsPath != want {
+		t.Errorf("ProfileFiles() credentials = %v, want %v", credentialsPath, want)
+	}
+	if want := filepath.Join(user.HomeDir, ".aws", "config"); configPath != want {
+		t.Errorf("ProfileFiles() config = %v, want %v", configPath, want)
+	}
+}
@@ -0,0 +1,130 @@
+package aws
+
+import (
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+)
+
+const (
+	//SessionSourceEnvironment is the Source of a session read from the AWS_* environment variables
+	SessionSourceEnvironment string = "environment"
+	//SessionSourceCache is the Source of a session read from the session cache
+	SessionSourceCache string = "cache"
+)
+
+var (
+	//ErrNoSession is returned when neither the environment nor the session cache hold a session
+	ErrNoSession = errors.New("No session found in the environment or the session cache for the profile")
+)
+
+//SessionStatusInput represents where the session to describe is looked for
+type SessionStatusInput struct {
+	//Credentials are those set in the environment, nil when there are none
+	Credentials *Credentials
+
+	//Profile is the profile whose cached session is described when Credentials is nil
+	Profile string
+
+	//Endpoints selects the region and endpoints STS and IAM are called on
+	Endpoints EndpointConfig
+
+	//Requests limits how long calls to STS and IAM may take and how often they are retried
+	Requests RequestConfig
+}
+
+//SessionStatus describes the identity and remaining lifetime of a session
+type SessionStatus struct {
+	Source           string     `json:"source"`
+	Profile          string     `json:"profile,omitempty"`
+	PrincipalARN     string     `json:"principal_arn,omitempty"`
+	AccountID        string     `json:"account_id,omitempty"`
+	AccountAlias     string     `json:"account_alias,omitempty"`
+	MFAAuthenticated bool       `json:"mfa_authenticated"`
+	MFASerialNumber  string     `json:"mfa_serial_number,omitempty"`
+	Expiration       *time.Time `json:"expiration,omitempty"`
+	Valid            bool       `json:"valid"`
+	Error            string     `json:"error,omitempty"`
+}
+
+//Remaining returns the lifetime left at now, or zero when the expiration is unknown or has passed
+func (s *SessionStatus) Remaining(now time.Time) time.Duration {
+	if s.Expiration == nil || now.After(*s.Expiration) {
+		return 0
+	}
+	return s.Expiration.Sub(now)
+}
+
+//GetSessionStatus describes the session in the environment, or else the cached session of the profile
+func GetSessionStatus(input *SessionStatusInput) (*SessionStatus, error) {
+	creds := input.Credentials
+	status := &SessionStatus{Source: SessionSourceEnvironment}
+
+	var cached *cachedSession
+	var err error
+	if creds != nil {
+		status.PrincipalARN = creds.PrincipalARN
+		cached, err = findCachedSession(func(session *cachedSession) bool {
+			return session.Credentials.AWSAccessKeyID == creds.AWSAccessKeyID
+		})
+	} else {
+		profileName := input.Profile
+		if len(profileName) == 0 {
+			profileName = profileDefault
+		}
+		status.Source, status.Profile = SessionSourceCache, profileName
+		cached, err = findCachedSession(func(session *cachedSession) bool {
+			return session.Profile == profileName
+		})
+		if err == nil && cached == nil {
+			return nil, ErrNoSession
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if cached != nil {
+		creds = cached.Credentials
+		expiration := cached.Credentials.Expiration
+		status.Profile, status.PrincipalARN, status.Expiration = cached.Profile, cached.Credentials.PrincipalARN, &expiration
+		status.MFAAuthenticated, status.MFASerialNumber = true, cached.SerialNumber
+	}
+
+	endpoints := input.Endpoints.withPartitionRegion(status.PrincipalARN, status.MFASerialNumber)
+	if err := endpoints.validate(); err != nil {
+		return nil, err
+	}
+
+	awsSession := newSession(credentials.NewStaticCredentials(creds.AWSAccessKeyID, creds.AWSSecretAccessKey, creds.AWSSessionToken), endpoints, input.Requests)
+	checkSessionStatus(status, sts.New(awsSession), iam.New(awsSession), input.Requests, time.Now())
+	return status, nil
+}
+
+//checkSessionStatus sets Valid when the session has not expired at now and STS accepts it
+func checkSessionStatus(status *SessionStatus, stsInstance stsiface.STSAPI, iamInstance iamiface.IAMAPI, requests RequestConfig, now time.Time) {
+	if status.Expiration != nil && !now.Before(*status.Expiration) {
+		status.Error = ErrTokenHasExpired.Error()
+		return
+	}
+
+	identity, err := getSTSIdentity(stsInstance, requests)
+	if err != nil {
+		status.Error = err.Error()
+		return
+	}
+	status.Valid, status.PrincipalARN, status.AccountID = true, identity.ARN, identity.Account
+
+	ctx, cancel := requests.context()
+	defer cancel()
+
+	aliases, err := iamInstance.ListAccountAliasesWithContext(ctx, &iam.ListAccountAliasesInput{})
+	if err == nil && len(aliases.AccountAliases) != 0 {
+		status.AccountAlias = *aliases.AccountAliases[0]
+	}
+}
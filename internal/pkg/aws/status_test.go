@@ -0,0 +1,126 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+func Test_checkSessionStatus(t *testing.T) {
+	now := time.Now()
+	expired, valid := now.Add(-time.Minute), now.Add(time.Hour)
+
+	identity := func(ctx context.Context, in1 *sts.GetCallerIdentityInput, opts ...request.Option) (*sts.GetCallerIdentityOutput, error) {
+		account, arn, userID := "123456789012", "arn:aws:iam::123456789012:user/johnsmith", "AIDAJOHNSMITH"
+		return &sts.GetCallerIdentityOutput{Account: &account, Arn: &arn, UserId: &userID}, nil
+	}
+	aliases := func(err error, aliases ...string) func(context.Context, *iam.ListAccountAliasesInput, ...request.Option) (*iam.ListAccountAliasesOutput, error) {
+		return func(ctx context.Context, in1 *iam.ListAccountAliasesInput, opts ...request.Option) (*iam.ListAccountAliasesOutput, error) {
+			output := &iam.ListAccountAliasesOutput{}
+			for i := range aliases {
+				output.AccountAliases = append(output.AccountAliases, &aliases[i])
+			}
+			return output, err
+		}
+	}
+
+	tests := []struct {
+		name       string
+		expiration *time.Time
+		identity   func(context.Context, *sts.GetCallerIdentityInput, ...request.Option) (*sts.GetCallerIdentityOutput, error)
+		aliases    func(context.Context, *iam.ListAccountAliasesInput, ...request.Option) (*iam.ListAccountAliasesOutput, error)
+		wantValid  bool
+		wantAlias  string
+	}{
+		{"Valid/WithAlias", &valid, identity, aliases(nil, "acme-prod"), true, "acme-prod"},
+		{"Valid/UnknownExpiration", nil, identity, aliases(nil), true, ""},
+		{"Valid/AliasDenied", &valid, identity, aliases(awserr.New("AccessDenied", "not authorized", nil)), true, ""},
+		{"Invalid/Expired", &expired, identity, aliases(nil, "acme-prod"), false, ""},
+		{
+			"Invalid/Rejected",
+			&valid,
+			func(ctx context.Context, in1 *sts.GetCallerIdentityInput, opts ...request.Option) (*sts.GetCallerIdentityOutput, error) {
+				return nil, awserr.New("ExpiredToken", "The security token included in the request is expired", errors.New("blah"))
+			},
+			aliases(nil, "acme-prod"),
+			false,
+			"",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := &SessionStatus{Expiration: tt.expiration}
+			checkSessionStatus(status, &STSAPIMock{GetCallerIdentityWithContextFunc: tt.identity}, &IAMAPIMock{ListAccountAliasesWithContextFunc: tt.aliases}, RequestConfig{}, now)
+
+			if status.Valid != tt.wantValid {
+				t.Errorf("checkSessionStatus() Valid = %v, want %v, error %v", status.Valid, tt.wantValid, status.Error)
+			}
+			if status.Valid == (len(status.Error) != 0) {
+				t.Errorf("checkSessionStatus() Error = %q with Valid %v", status.Error, status.Valid)
+			}
+			if status.AccountAlias != tt.wantAlias {
+				t.Errorf("checkSessionStatus() AccountAlias = %v, want %v", status.AccountAlias, tt.wantAlias)
+			}
+			if status.Valid && status.AccountID != "123456789012" {
+				t.Errorf("checkSessionStatus() AccountID = %v", status.AccountID)
+			}
+		})
+	}
+}
+
+func Test_findCachedSession(t *testing.T) {
+	first := &Credentials{AWSAccessKeyID: "ASIAFIRST", Expiration: time.Now().Add(time.Hour).UTC().Round(time.Second)}
+	last := &Credentials{AWSAccessKeyID: "ASIALAST", Expiration: time.Now().Add(2 * time.Hour).UTC().Round(time.Second)}
+
+	if err := writeCachedCredentials(sessionKey{Profile: "find-session", SerialNumber: "arn:aws:iam::123456789012:mfa/phone"}, first); err != nil {
+		t.Fatalf("writeCachedCredentials() error = %v", err)
+	}
+	if err := writeCachedCredentials(sessionKey{Profile: "find-session", SerialNumber: "arn:aws:iam::123456789012:mfa/backup"}, last); err != nil {
+		t.Fatalf("writeCachedCredentials() error = %v", err)
+	}
+
+	got, err := findCachedSession(func(session *cachedSession) bool { return session.Profile == "find-session" })
+	if err != nil {
+		t.Fatalf("findCachedSession() error = %v", err)
+	}
+	if got == nil || got.Credentials.AWSAccessKeyID != "ASIALAST" || got.SerialNumber != "arn:aws:iam::123456789012:mfa/backup" {
+		t.Errorf("findCachedSession() = %+v, want the session expiring last", got)
+	}
+
+	got, err = findCachedSession(func(session *cachedSession) bool { return session.Credentials.AWSAccessKeyID == "ASIAFIRST" })
+	if err != nil || got == nil || got.SerialNumber != "arn:aws:iam::123456789012:mfa/phone" {
+		t.Errorf("findCachedSession() = %+v, %v, want the session with the access key", got, err)
+	}
+
+	got, err = findCachedSession(func(session *cachedSession) bool { return session.Profile == "find-missing" })
+	if err != nil || got != nil {
+		t.Errorf("findCachedSession() = %+v, %v, want nil", got, err)
+	}
+}
+
+func TestGetSessionStatus(t *testing.T) {
+	if _, err := GetSessionStatus(&SessionStatusInput{Profile: "status-missing"}); err != ErrNoSession {
+		t.Errorf("GetSessionStatus() error = %v, want %v", err, ErrNoSession)
+	}
+}
+
+func TestSessionStatusRemaining(t *testing.T) {
+	now := time.Now()
+	expiration := now.Add(90 * time.Minute)
+
+	if got := (&SessionStatus{Expiration: &expiration}).Remaining(now); got != 90*time.Minute {
+		t.Errorf("Remaining() = %v, want %v", got, 90*time.Minute)
+	}
+	if got := (&SessionStatus{Expiration: &expiration}).Remaining(expiration.Add(time.Second)); got != 0 {
+		t.Errorf("Remaining() after expiry = %v, want 0", got)
+	}
+	if got := (&SessionStatus{}).Remaining(now); got != 0 {
+		t.Errorf("Remaining() without expiration = %v, want 0", got)
+	}
+}
@@ -3,8 +3,11 @@ package aws
 import (
 	"fmt"
 	"regexp"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
 
 	"github.com/aws/aws-sdk-go/service/sts"
 	"github.com/aws/aws-sdk-go/service/sts/stsiface"
@@ -12,6 +15,11 @@ import (
 
 const (
 	tokenValidationRegex string = "^[0-9]+$"
+
+	roleSessionNamePrefix string = "mfa4aws"
+
+	errCodeAccessDenied string = "AccessDenied"
+	mfaFailedMessage    string = "MultiFactorAuthentication failed"
 )
 
 var (
@@ -25,7 +33,8 @@ type STSIdentity struct {
 	UserID  string
 }
 
-func validateToken(token string) error {
+//ValidateToken returns ErrInvalidToken when token is not a well formed MFA value
+func ValidateToken(token string) error {
 	if len(token) <= 5 {
 		return ErrInvalidToken
 	}
@@ -36,40 +45,55 @@ func validateToken(token string) error {
 	return nil
 }
 
-func getSTSSessionToken(stsInstance stsiface.STSAPI, tokenCode string, mfaDeviceSerialNumber string) (*sts.Credentials, error) {
+//getSTSSessionToken requests a session for the IAM user lasting durationSeconds, or the STS default when zero
+func getSTSSessionToken(stsInstance stsiface.STSAPI, requests RequestConfig, tokenCode string, mfaDeviceSerialNumber string,
+	durationSeconds int64) (*sts.Credentials, error) {
 
-	if err := validateToken(tokenCode); err != nil {
+	if err := ValidateToken(tokenCode); err != nil {
 		return nil, err
 	}
 
-	stsSession, err := stsInstance.GetSessionToken(&sts.GetSessionTokenInput{
+	input := &sts.GetSessionTokenInput{
 		TokenCode:    &tokenCode,
 		SerialNumber: &mfaDeviceSerialNumber,
-	})
+	}
+	if durationSeconds != 0 {
+		input.DurationSeconds = &durationSeconds
+	}
+
+	ctx, cancel := requests.context()
+	defer cancel()
+
+	stsSession, err := stsInstance.GetSessionTokenWithContext(ctx, input, withoutRetries)
 	if err != nil {
 		if aerr, ok := err.(awserr.Error); ok {
-			switch aerr.Code() {
-			case sts.ErrCodeExpiredTokenException:
-				return nil, ErrTokenHasExpired
-			case sts.ErrCodeInvalidIdentityTokenException:
-				return nil, ErrInvalidToken
-			default:
-				return nil, fmt.Errorf("%v For device %s", aerr.Message(), mfaDeviceSerialNumber)
+			switch {
+			case aerr.Code() == sts.ErrCodeExpiredTokenException:
+				return nil, reportedRequestError(ErrTokenHasExpired, ErrorCategoryAuth, "", err)
+			case aerr.Code() == sts.ErrCodeInvalidIdentityTokenException:
+				return nil, reportedRequestError(ErrInvalidToken, ErrorCategoryMFA, "", err)
+			case aerr.Code() == errCodeAccessDenied && isMFAValueRejected(aerr):
+				return nil, reportedRequestError(ErrInvalidToken, ErrorCategoryMFA, "", err)
 			}
 		}
-		return nil, fmt.Errorf("unknown error occurred - %v For device %s", err, mfaDeviceSerialNumber)
+		return nil, newRequestError(fmt.Sprintf("Unable to get a session token for device %s", mfaDeviceSerialNumber), err)
 	}
 
 	return stsSession.Credentials, nil
 }
 
-func getSTSIdentity(stsInstance stsiface.STSAPI) (*STSIdentity, error) {
-	identity, err := stsInstance.GetCallerIdentity(&sts.GetCallerIdentityInput{})
+//isMFAValueRejected returns true when STS denied a request because of the MFA value given with it
+func isMFAValueRejected(aerr awserr.Error) bool {
+	return strings.Contains(aerr.Message(), mfaFailedMessage)
+}
+
+func getSTSIdentity(stsInstance stsiface.STSAPI, requests RequestConfig) (*STSIdentity, error) {
+	ctx, cancel := requests.context()
+	defer cancel()
+
+	identity, err := stsInstance.GetCallerIdentityWithContext(ctx, &sts.GetCallerIdentityInput{})
 	if err != nil {
-		if aerr, ok := err.(awserr.Error); ok {
-			return nil, fmt.Errorf("Unable to retrive user - %v", aerr.Message())
-		}
-		return nil, fmt.Errorf("unknown error occurred, %v", err)
+		return nil, newRequestError("Unable to retrieve user", err)
 	}
 
 	return &STSIdentity{
@@ -78,3 +102,54 @@ func getSTSIdentity(stsInstance stsiface.STSAPI) (*STSIdentity, error) {
 		UserID:  *identity.UserId,
 	}, nil
 }
+
+//assumeRole assumes the role of profile p, with MFA when mfaDeviceSerialNumber is set
+func assumeRole(stsInstance stsiface.STSAPI, requests RequestConfig, p *profile, tokenCode string, mfaDeviceSerialNumber string,
+	durationSeconds int64) (*sts.Credentials, *sts.AssumedRoleUser, error) {
+
+	roleSessionName := p.RoleSessionName
+	if len(roleSessionName) == 0 {
+		roleSessionName = fmt.Sprintf("%s-%d", roleSessionNamePrefix, time.Now().Unix())
+	}
+
+	input := &sts.AssumeRoleInput{
+		RoleArn:         &p.RoleARN,
+		RoleSessionName: &roleSessionName,
+	}
+	var opts []request.Option
+	if len(mfaDeviceSerialNumber) != 0 {
+		if err := ValidateToken(tokenCode); err != nil {
+			return nil, nil, err
+		}
+		input.TokenCode = &tokenCode
+		input.SerialNumber = &mfaDeviceSerialNumber
+		opts = append(opts, withoutRetries)
+	}
+	if durationSeconds != 0 {
+		input.DurationSeconds = &durationSeconds
+	}
+	if len(p.ExternalID) != 0 {
+		input.ExternalId = &p.ExternalID
+	}
+
+	ctx, cancel := requests.context()
+	defer cancel()
+
+	role, err := stsInstance.AssumeRoleWithContext(ctx, input, opts...)
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok {
+			switch {
+			case aerr.Code() == sts.ErrCodeExpiredTokenException:
+				return nil, nil, reportedRequestError(ErrTokenHasExpired, ErrorCategoryAuth, "", err)
+			case aerr.Code() == errCodeAccessDenied && len(mfaDeviceSerialNumber) != 0 && isMFAValueRejected(aerr):
+				return nil, nil, reportedRequestError(ErrInvalidToken, ErrorCategoryMFA, "", err)
+			}
+		}
+		if len(mfaDeviceSerialNumber) != 0 {
+			return nil, nil, newRequestError(fmt.Sprintf("Unable to assume role %s with device %s", p.RoleARN, mfaDeviceSerialNumber), err)
+		}
+		return nil, nil, newRequestError(fmt.Sprintf("Unable to assume role %s", p.RoleARN), err)
+	}
+
+	return role.Credentials, role.AssumedRoleUser, nil
+}
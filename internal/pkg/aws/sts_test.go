@@ -3,10 +3,12 @@ package aws
 //go:generate go run -tags tools github.com/matryer/moq -pkg aws -out sts_test_mock.go $GOPATH/pkg/mod/github.com/aws/aws-sdk-go@v1.34.0/service/sts/stsiface STSAPI
 
 import (
+	"context"
 	"reflect"
 	"testing"
 
 	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
 
 	"github.com/aws/aws-sdk-go/service/sts"
 	"github.com/aws/aws-sdk-go/service/sts/stsiface"
@@ -14,7 +16,7 @@ import (
 	"errors"
 )
 
-func Test_validateToken(t *testing.T) {
+func TestValidateToken(t *testing.T) {
 	type args struct {
 		token string
 	}
@@ -61,8 +63,8 @@ func Test_validateToken(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if err := validateToken(tt.args.token); (err != nil) != tt.wantErr {
-				t.Errorf("validateToken() error = %v, wantErr %v", err, tt.wantErr)
+			if err := ValidateToken(tt.args.token); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateToken() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
 	}
@@ -73,6 +75,7 @@ func Test_getSTSSessionToken(t *testing.T) {
 		stsInstance           stsiface.STSAPI
 		tokenCode             string
 		mfaDeviceSerialNumber string
+		durationSeconds       int64
 	}
 	tests := []struct {
 		name    string
@@ -84,7 +87,10 @@ func Test_getSTSSessionToken(t *testing.T) {
 			"Vaild/EmptyResult",
 			args{
 				stsInstance: &STSAPIMock{
-					GetSessionTokenFunc: func(in1 *sts.GetSessionTokenInput) (*sts.GetSessionTokenOutput, error) {
+					GetSessionTokenWithContextFunc: func(ctx context.Context, in1 *sts.GetSessionTokenInput, opts ...request.Option) (*sts.GetSessionTokenOutput, error) {
+						if in1.DurationSeconds != nil {
+							return nil, errors.New("unexpected input")
+						}
 						return &sts.GetSessionTokenOutput{}, nil
 					},
 				},
@@ -94,11 +100,29 @@ func Test_getSTSSessionToken(t *testing.T) {
 			nil,
 			false,
 		},
+		{
+			"Vaild/Duration",
+			args{
+				stsInstance: &STSAPIMock{
+					GetSessionTokenWithContextFunc: func(ctx context.Context, in1 *sts.GetSessionTokenInput, opts ...request.Option) (*sts.GetSessionTokenOutput, error) {
+						if in1.DurationSeconds == nil || *in1.DurationSeconds != 14400 {
+							return nil, errors.New("unexpected input")
+						}
+						return &sts.GetSessionTokenOutput{}, nil
+					},
+				},
+				tokenCode:             "123456",
+				mfaDeviceSerialNumber: "sfagstfey",
+				durationSeconds:       14400,
+			},
+			nil,
+			false,
+		},
 		{
 			"Invaild/awserrError",
 			args{
 				stsInstance: &STSAPIMock{
-					GetSessionTokenFunc: func(in1 *sts.GetSessionTokenInput) (*sts.GetSessionTokenOutput, error) {
+					GetSessionTokenWithContextFunc: func(ctx context.Context, in1 *sts.GetSessionTokenInput, opts ...request.Option) (*sts.GetSessionTokenOutput, error) {
 						return nil, awserr.New("5000", "blah", errors.New("blah"))
 					},
 				},
@@ -112,7 +136,7 @@ func Test_getSTSSessionToken(t *testing.T) {
 			"Invaild/awserrError/ErrCodeExpiredTokenException",
 			args{
 				stsInstance: &STSAPIMock{
-					GetSessionTokenFunc: func(in1 *sts.GetSessionTokenInput) (*sts.GetSessionTokenOutput, error) {
+					GetSessionTokenWithContextFunc: func(ctx context.Context, in1 *sts.GetSessionTokenInput, opts ...request.Option) (*sts.GetSessionTokenOutput, error) {
 						return nil, awserr.New(sts.ErrCodeExpiredTokenException, "Blah", errors.New("blah"))
 					},
 				},
@@ -126,7 +150,7 @@ func Test_getSTSSessionToken(t *testing.T) {
 			"Invaild/awserrError/ErrCodeInvalidIdentityTokenException",
 			args{
 				stsInstance: &STSAPIMock{
-					GetSessionTokenFunc: func(in1 *sts.GetSessionTokenInput) (*sts.GetSessionTokenOutput, error) {
+					GetSessionTokenWithContextFunc: func(ctx context.Context, in1 *sts.GetSessionTokenInput, opts ...request.Option) (*sts.GetSessionTokenOutput, error) {
 						return nil, awserr.New(sts.ErrCodeInvalidIdentityTokenException, "Blah", errors.New("blah"))
 					},
 				},
@@ -136,11 +160,25 @@ func Test_getSTSSessionToken(t *testing.T) {
 			nil,
 			true,
 		},
+		{
+			"Invaild/awserrError/MFAValueRejected",
+			args{
+				stsInstance: &STSAPIMock{
+					GetSessionTokenWithContextFunc: func(ctx context.Context, in1 *sts.GetSessionTokenInput, opts ...request.Option) (*sts.GetSessionTokenOutput, error) {
+						return nil, awserr.New("AccessDenied", "MultiFactorAuthentication failed with invalid MFA one time pass code.", nil)
+					},
+				},
+				tokenCode:             "123456",
+				mfaDeviceSerialNumber: "sfagstfey",
+			},
+			nil,
+			true,
+		},
 		{
 			"Invaild/Error",
 			args{
 				stsInstance: &STSAPIMock{
-					GetSessionTokenFunc: func(in1 *sts.GetSessionTokenInput) (*sts.GetSessionTokenOutput, error) {
+					GetSessionTokenWithContextFunc: func(ctx context.Context, in1 *sts.GetSessionTokenInput, opts ...request.Option) (*sts.GetSessionTokenOutput, error) {
 						return nil, errors.New("blah")
 					},
 				},
@@ -153,7 +191,7 @@ func Test_getSTSSessionToken(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := getSTSSessionToken(tt.args.stsInstance, tt.args.tokenCode, tt.args.mfaDeviceSerialNumber)
+			got, err := getSTSSessionToken(tt.args.stsInstance, RequestConfig{}, tt.args.tokenCode, tt.args.mfaDeviceSerialNumber, tt.args.durationSeconds)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("getSTSSessionToken() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -179,7 +217,7 @@ func Test_getSTSIdentity(t *testing.T) {
 			"Valid/User",
 			args{
 				stsInstance: &STSAPIMock{
-					GetCallerIdentityFunc: func(in1 *sts.GetCallerIdentityInput) (*sts.GetCallerIdentityOutput, error) {
+					GetCallerIdentityWithContextFunc: func(ctx context.Context, in1 *sts.GetCallerIdentityInput, opts ...request.Option) (*sts.GetCallerIdentityOutput, error) {
 
 						account := "342563637373"
 						arn := "ashgajsdhgajsdg"
@@ -204,7 +242,7 @@ func Test_getSTSIdentity(t *testing.T) {
 			"Invaild/Error",
 			args{
 				stsInstance: &STSAPIMock{
-					GetCallerIdentityFunc: func(in1 *sts.GetCallerIdentityInput) (*sts.GetCallerIdentityOutput, error) {
+					GetCallerIdentityWithContextFunc: func(ctx context.Context, in1 *sts.GetCallerIdentityInput, opts ...request.Option) (*sts.GetCallerIdentityOutput, error) {
 						return nil, errors.New("blah")
 					},
 				},
@@ -216,7 +254,7 @@ func Test_getSTSIdentity(t *testing.T) {
 			"Invaild/awserrError",
 			args{
 				stsInstance: &STSAPIMock{
-					GetCallerIdentityFunc: func(in1 *sts.GetCallerIdentityInput) (*sts.GetCallerIdentityOutput, error) {
+					GetCallerIdentityWithContextFunc: func(ctx context.Context, in1 *sts.GetCallerIdentityInput, opts ...request.Option) (*sts.GetCallerIdentityOutput, error) {
 						return nil, awserr.New("askjdhaksjhd", "Blah", errors.New("blah"))
 					},
 				},
@@ -227,7 +265,7 @@ func Test_getSTSIdentity(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := getSTSIdentity(tt.args.stsInstance)
+			got, err := getSTSIdentity(tt.args.stsInstance, RequestConfig{})
 			if (err != nil) != tt.wantErr {
 				t.Errorf("getSTSIdentity() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -238,3 +276,155 @@ func Test_getSTSIdentity(t *testing.T) {
 		})
 	}
 }
+
+func Test_assumeRole(t *testing.T) {
+	roleARN := "arn:aws:sts::210987654321:assumed-role/admin/johnsmith"
+
+	type args struct {
+		stsInstance           stsiface.STSAPI
+		p                     *profile
+		tokenCode             string
+		mfaDeviceSerialNumber string
+		durationSeconds       int64
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    *sts.AssumedRoleUser
+		wantErr bool
+	}{
+		{
+			"Valid/AssumedRole",
+			args{
+				stsInstance: &STSAPIMock{
+					AssumeRoleWithContextFunc: func(ctx context.Context, in1 *sts.AssumeRoleInput, opts ...request.Option) (*sts.AssumeRoleOutput, error) {
+						if *in1.RoleArn != "arn:aws:iam::210987654321:role/admin" ||
+							*in1.SerialNumber != "sfagstfey" ||
+							*in1.TokenCode != "123456" ||
+							*in1.RoleSessionName != "johnsmith" ||
+							*in1.DurationSeconds != 3600 ||
+							*in1.ExternalId != "blahblah" {
+							return nil, errors.New("unexpected input")
+						}
+						return &sts.AssumeRoleOutput{
+							AssumedRoleUser: &sts.AssumedRoleUser{Arn: &roleARN},
+						}, nil
+					},
+				},
+				p: &profile{
+					RoleARN:         "arn:aws:iam::210987654321:role/admin",
+					RoleSessionName: "johnsmith",
+					ExternalID:      "blahblah",
+				},
+				tokenCode:             "123456",
+				mfaDeviceSerialNumber: "sfagstfey",
+				durationSeconds:       3600,
+			},
+			&sts.AssumedRoleUser{Arn: &roleARN},
+			false,
+		},
+		{
+			"Valid/DefaultSessionName",
+			args{
+				stsInstance: &STSAPIMock{
+					AssumeRoleWithContextFunc: func(ctx context.Context, in1 *sts.AssumeRoleInput, opts ...request.Option) (*sts.AssumeRoleOutput, error) {
+						if len(*in1.RoleSessionName) == 0 || in1.DurationSeconds != nil || in1.ExternalId != nil {
+							return nil, errors.New("unexpected input")
+						}
+						return &sts.AssumeRoleOutput{
+							AssumedRoleUser: &sts.AssumedRoleUser{Arn: &roleARN},
+						}, nil
+					},
+				},
+				p: &profile{
+					RoleARN: "arn:aws:iam::210987654321:role/admin",
+				},
+				tokenCode:             "123456",
+				mfaDeviceSerialNumber: "sfagstfey",
+			},
+			&sts.AssumedRoleUser{Arn: &roleARN},
+			false,
+		},
+		{
+			"Valid/WithoutMFA",
+			args{
+				stsInstance: &STSAPIMock{
+					AssumeRoleWithContextFunc: func(ctx context.Context, in1 *sts.AssumeRoleInput, opts ...request.Option) (*sts.AssumeRoleOutput, error) {
+						if in1.SerialNumber != nil || in1.TokenCode != nil {
+							return nil, errors.New("unexpected input")
+						}
+						return &sts.AssumeRoleOutput{
+							AssumedRoleUser: &sts.AssumedRoleUser{Arn: &roleARN},
+						}, nil
+					},
+				},
+				p: &profile{
+					RoleARN: "arn:aws:iam::210987654321:role/admin",
+				},
+				tokenCode:             "",
+				mfaDeviceSerialNumber: "",
+			},
+			&sts.AssumedRoleUser{Arn: &roleARN},
+			false,
+		},
+		{
+			"Invaild/InvalidToken",
+			args{
+				stsInstance: &STSAPIMock{},
+				p: &profile{
+					RoleARN: "arn:aws:iam::210987654321:role/admin",
+				},
+				tokenCode:             "12",
+				mfaDeviceSerialNumber: "sfagstfey",
+			},
+			nil,
+			true,
+		},
+		{
+			"Invaild/awserrError/ErrCodeExpiredTokenException",
+			args{
+				stsInstance: &STSAPIMock{
+					AssumeRoleWithContextFunc: func(ctx context.Context, in1 *sts.AssumeRoleInput, opts ...request.Option) (*sts.AssumeRoleOutput, error) {
+						return nil, awserr.New(sts.ErrCodeExpiredTokenException, "Blah", errors.New("blah"))
+					},
+				},
+				p: &profile{
+					RoleARN: "arn:aws:iam::210987654321:role/admin",
+				},
+				tokenCode:             "123456",
+				mfaDeviceSerialNumber: "sfagstfey",
+			},
+			nil,
+			true,
+		},
+		{
+			"Invaild/Error",
+			args{
+				stsInstance: &STSAPIMock{
+					AssumeRoleWithContextFunc: func(ctx context.Context, in1 *sts.AssumeRoleInput, opts ...request.Option) (*sts.AssumeRoleOutput, error) {
+						return nil, errors.New("blah")
+					},
+				},
+				p: &profile{
+					RoleARN: "arn:aws:iam::210987654321:role/admin",
+				},
+				tokenCode:             "123456",
+				mfaDeviceSerialNumber: "sfagstfey",
+			},
+			nil,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, got, err := assumeRole(tt.args.stsInstance, RequestConfig{}, tt.args.p, tt.args.tokenCode, tt.args.mfaDeviceSerialNumber, tt.args.durationSeconds)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("assumeRole() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("assumeRole() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"fmt"
+	"mfa4aws/internal/pkg/agent"
+	"mfa4aws/internal/pkg/aws"
+	"mfa4aws/internal/pkg/shell"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	agentSocket string
+)
+
+func init() {
+	rootCmd.AddCommand(agentCmd)
+
+	agentCmd.Flags().StringVar(&agentSocket, "socket", "", "Path of the Unix socket to listen on (default $HOME/.aws/mfa4aws/agent.sock)")
+	addEndpointFlags(agentCmd.Flags())
+	addRequestFlags(agentCmd.Flags())
+}
+
+var agentCmd = &cobra.Command{
+	Use:   "agent",
+	Short: "Holds AWS STS sessions in memory for other mfa4aws commands, which find it through " + agent.EnvNameAuthSock,
+	Run: func(cmd *cobra.Command, args []string) {
+		path := agentSocket
+		if len(path) == 0 {
+			var err error
+			if path, err = agent.DefaultSocketPath(); err != nil {
+				exitWithError(os.Stderr, err)
+			}
+		}
+
+		listener, err := agent.Listen(path)
+		if err != nil {
+			exitWithError(os.Stderr, err)
+		}
+
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+		go func() {
+			<-signals
+			listener.Close()
+		}()
+
+		vars := []shell.EnvVar{{Name: agent.EnvNameAuthSock, Value: path}}
+		statements, err := shell.DetectDialect(os.Getenv(envNameShell)).Export(vars)
+		if err != nil {
+			exitWithError(os.Stderr, err)
+		}
+		shell.PrintVars(os.Stdout, statements)
+		fmt.Fprintf(os.Stderr, "Agent listening on %s\n", path)
+
+		//the vault stays unlocked and empty files and endpoints are those of the agent
+		keys := &vaultAccessKeyStore{}
+		agent.New(func(input *aws.STSCredentialsInput) (*aws.Credentials, error) {
+			input.AccessKeyStore, input.Requests = keys, requestConfig
+			if len(input.CredentialsFile) == 0 {
+				input.CredentialsFile = credentialsFile
+			}
+			if len(input.ConfigFile) == 0 {
+				input.ConfigFile = configFile
+			}
+			input.Endpoints = input.Endpoints.Merge(endpointConfig)
+			return aws.GenerateSTSCredentials(input)
+		}).Serve(listener)
+	},
+}
+
+//generateCredentials asks the agent for the credentials when one is running
+func generateCredentials(input *aws.STSCredentialsInput) (*aws.Credentials, error) {
+	path := os.Getenv(agent.EnvNameAuthSock)
+	if len(path) == 0 {
+		return aws.GenerateSTSCredentials(input)
+	}
+
+	client, err := agent.Dial(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to reach the agent at %s, generating credentials without it - %v\n", path, err)
+		return aws.GenerateSTSCredentials(input)
+	}
+	defer client.Close()
+
+	return client.Credentials(input)
+}
+
+//forgetAgentSessions drops the sessions the agent holds for input when one is running and returns how many it held
+func forgetAgentSessions(input *aws.LogoutInput) int {
+	path := os.Getenv(agent.EnvNameAuthSock)
+	if len(path) == 0 {
+		return 0
+	}
+
+	client, err := agent.Dial(path)
+	if err == nil {
+		defer client.Close()
+		var sessions int
+		if sessions, err = client.Forget(&agent.ForgetRequest{Profile: input.Profile, AccessKeyID: input.AccessKeyID, All: input.All}); err == nil {
+			return sessions
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Unable to remove the sessions held by the agent at %s - %v\n", path, err)
+	return 0
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	//interruptGracePeriod is how long cancelled calls have to return before the command exits
+	interruptGracePeriod = 2 * time.Second
+)
+
+var (
+	//undoing is read locked while calls undoing others are made, the forced exit after an interrupt waits for it
+	undoing sync.RWMutex
+)
+
+//holdExit keeps an interrupt from exiting the command until the returned func is called
+func holdExit() func() {
+	undoing.RLock()
+	return undoing.RUnlock
+}
+
+//interruptContext returns a context cancelled by an interrupt or SIGTERM and a func to stop watching
+func interruptContext() (context.Context, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig, ok := <-signals
+		if !ok {
+			return
+		}
+		cancel()
+		exit := func() {
+			os.Exit(128 + int(sig.(syscall.Signal)))
+		}
+		time.AfterFunc(interruptGracePeriod, func() {
+			undoing.Lock()
+			exit()
+		})
+
+		//a second interrupt exits without waiting for undoing to finish
+		if _, ok := <-signals; ok {
+			exit()
+		}
+	}()
+
+	var once sync.Once
+	return ctx, func() {
+		once.Do(func() {
+			signal.Stop(signals)
+			close(signals)
+			cancel()
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mfa4aws/internal/pkg/aws"
+	"os"
+)
+
+const (
+	errorFormatText string = "text"
+	errorFormatJSON string = "json"
+)
+
+//exitCodes is the exit code of each category of failure, see Exit codes in the README
+var exitCodes = map[aws.ErrorCategory]int{
+	aws.ErrorCategoryUnknown:    1,
+	aws.ErrorCategoryConfig:     2,
+	aws.ErrorCategoryAuth:       3,
+	aws.ErrorCategoryMFA:        4,
+	aws.ErrorCategoryNetwork:    5,
+	aws.ErrorCategoryThrottling: 6,
+}
+
+//errorOutput is an error written with --error-format json
+type errorOutput struct {
+	Error errorDetail `json:"error"`
+}
+
+type errorDetail struct {
+	Category aws.ErrorCategory `json:"category"`
+	Code     string            `json:"code,omitempty"`
+	Message  string            `json:"message"`
+	ExitCode int               `json:"exit_code"`
+}
+
+//errorExitCode returns the exit code of the category of err
+func errorExitCode(err error) int {
+	if code, ok := exitCodes[aws.ErrorCategoryOf(err)]; ok {
+		return code
+	}
+	return exitCodes[aws.ErrorCategoryUnknown]
+}
+
+//writeError writes err to out in format
+func writeError(out io.Writer, err error, format string) {
+	if format != errorFormatJSON {
+		fmt.Fprintln(out, err)
+		return
+	}
+
+	category := aws.ErrorCategoryOf(err)
+	if _, ok := exitCodes[category]; !ok {
+		category = aws.ErrorCategoryUnknown
+	}
+	data, _ := json.Marshal(&errorOutput{Error: errorDetail{
+		Category: category,
+		Code:     aws.ErrorCode(err),
+		Message:  err.Error(),
+		ExitCode: errorExitCode(err),
+	}})
+	fmt.Fprintln(out, string(data))
+}
+
+//exitWithError writes err to out in the format given with --error-format and exits with the code of its category
+func exitWithError(out io.Writer, err error) {
+	writeError(out, err, errorFormat)
+	os.Exit(errorExitCode(err))
+}
+
+//configError returns err reported as an invalid flag or setting
+func configError(err error) error {
+	return aws.WithErrorCategory(err, aws.ErrorCategoryConfig)
+}
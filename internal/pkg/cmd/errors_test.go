@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"mfa4aws/internal/pkg/agent"
+	"mfa4aws/internal/pkg/aws"
+	"testing"
+)
+
+func Test_writeError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		category aws.ErrorCategory
+		exitCode int
+	}{
+		{
+			"Valid/Unknown",
+			errors.New("blah"),
+			aws.ErrorCategoryUnknown,
+			1,
+		},
+		{
+			"Valid/Config",
+			configError(errEmptyProfileSuffix),
+			aws.ErrorCategoryConfig,
+			2,
+		},
+		{
+			"Valid/Auth",
+			aws.ErrTokenHasExpired,
+			aws.ErrorCategoryAuth,
+			3,
+		},
+		{
+			"Valid/MFA",
+			aws.ErrInvalidToken,
+			aws.ErrorCategoryMFA,
+			4,
+		},
+		{
+			"Valid/AgentNetwork",
+			&agent.Error{Code: agent.ErrCodeFailed, Message: "Unable to retrieve user", Category: string(aws.ErrorCategoryNetwork)},
+			aws.ErrorCategoryNetwork,
+			5,
+		},
+		{
+			"Valid/AgentThrottling",
+			&agent.Error{Code: agent.ErrCodeFailed, Message: "Rate exceeded", Category: string(aws.ErrorCategoryThrottling)},
+			aws.ErrorCategoryThrottling,
+			6,
+		},
+		{
+			"Valid/AgentUnknownCategory",
+			&agent.Error{Code: agent.ErrCodeFailed, Message: "blah", Category: "quota"},
+			aws.ErrorCategoryUnknown,
+			1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errorExitCode(tt.err); got != tt.exitCode {
+				t.Errorf("errorExitCode() = %v, want %v", got, tt.exitCode)
+			}
+
+			out := bytes.NewBuffer(nil)
+			writeError(out, tt.err, errorFormatJSON)
+			var got errorOutput
+			if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			want := errorDetail{Category: tt.category, Message: tt.err.Error(), ExitCode: tt.exitCode}
+			if got.Error != want {
+				t.Errorf("writeError() = %+v, want %+v", got.Error, want)
+			}
+
+			out.Reset()
+			writeError(out, tt.err, errorFormatText)
+			if out.String() != tt.err.Error()+"\n" {
+				t.Errorf("writeError() = %q, want the message alone", out.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"mfa4aws/internal/pkg/shell"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	execFormat string
+)
+
+func init() {
+	rootCmd.AddCommand(execCmd)
+	addCredentialFlags(execCmd)
+	addFormatFlag(execCmd, &execFormat, shell.FormatDotenv, "Format to write the variables to stdout in when no command is given")
+
+	//flags after the command name are the command's own, so -- is optional
+	execCmd.Flags().SetInterspersed(false)
+}
+
+var execCmd = &cobra.Command{
+	Use:   "exec [flags] [--] [command [args...]]",
+	Short: "Executes a command with AWS STS access keys set in its environment",
+	Run: func(cmd *cobra.Command, args []string) {
+		formatter, err := lookupFormatter(execFormat)
+		if err != nil {
+			exitWithError(os.Stderr, err)
+		}
+
+		ctx, stop := interruptContext()
+		defer stop()
+		requestConfig.Context = ctx
+
+		input, err := credentialsInput()
+		if err != nil {
+			exitWithError(os.Stderr, err)
+		}
+
+		creds, err := generateCredentials(input)
+		if err != nil {
+			exitWithError(os.Stderr, err)
+		}
+
+		if len(args) == 0 {
+			if err := formatter.Format(os.Stdout, awsProfile, creds); err != nil {
+				exitWithError(os.Stderr, err)
+			}
+			return
+		}
+
+		//signals are forwarded to the command from here on
+		stop()
+		exitCode, err := runCommand(args[0], args[1:], shell.BuildExecEnv(os.Environ(), creds))
+		if err != nil {
+			exitWithError(os.Stderr, err)
+		}
+
+		os.Exit(exitCode)
+	},
+}
+
+//runCommand starts name with env, forwards any signals received to it and returns its exit code
+func runCommand(name string, args []string, env []string) (int, error) {
+	child := exec.Command(name, args...)
+	child.Env = env
+	child.Stdin = os.Stdin
+	child.Stdout = os.Stdout
+	child.Stderr = os.Stderr
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT)
+
+	if err := child.Start(); err != nil {
+		signal.Stop(signals)
+		return 0, err
+	}
+
+	go func() {
+		for sig := range signals {
+			_ = child.Process.Signal(sig)
+		}
+	}()
+
+	err := child.Wait()
+	signal.Stop(signals)
+	close(signals)
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+				return 128 + int(status.Signal()), nil
+			}
+			return exitErr.ExitCode(), nil
+		}
+		return 0, err
+	}
+
+	return 0, nil
+}
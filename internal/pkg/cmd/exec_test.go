@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_runCommand(t *testing.T) {
+	type args struct {
+		name string
+		args []string
+		env  []string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    int
+		wantErr bool
+	}{
+		{
+			"Valid/ExitZero",
+			args{
+				name: "sh",
+				args: []string{"-c", "exit 0"},
+			},
+			0,
+			false,
+		},
+		{
+			"Valid/ExitNonZero",
+			args{
+				name: "sh",
+				args: []string{"-c", "exit 3"},
+			},
+			3,
+			false,
+		},
+		{
+			"Valid/EnvPassedToChild",
+			args{
+				name: "sh",
+				args: []string{"-c", `test "$AWS_ACCESS_KEY_ID" = "blahblah"`},
+				env:  []string{"AWS_ACCESS_KEY_ID=blahblah"},
+			},
+			0,
+			false,
+		},
+		{
+			"Invalid/UnknownCommand",
+			args{
+				name: "/some/unknown/command",
+			},
+			0,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := runCommand(tt.args.name, tt.args.args, tt.args.env)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("runCommand() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("runCommand() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_execCmdFlags(t *testing.T) {
+	defer func(profile string) { awsProfile = profile }(awsProfile)
+
+	if err := execCmd.ParseFlags([]string{"-p", "work", "aws", "s3", "ls", "--profile", "other"}); err != nil {
+		t.Fatalf("ParseFlags() error = %v", err)
+	}
+	if awsProfile != "work" {
+		t.Errorf("ParseFlags() profile = %v, want work", awsProfile)
+	}
+	if got, want := execCmd.Flags().Args(), []string{"aws", "s3", "ls", "--profile", "other"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseFlags() args = %v, want %v", got, want)
+	}
+}
+
+func Test_lookupFormatter(t *testing.T) {
+	for _, format := range []string{execCmd.Flags().Lookup("format").DefValue, "json", "shell"} {
+		if _, err := lookupFormatter(format); err != nil {
+			t.Errorf("lookupFormatter(%s) error = %v", format, err)
+		}
+	}
+	if _, err := lookupFormatter("xml"); err == nil {
+		t.Errorf("lookupFormatter(xml) error = nil, want an unsupported format")
+	}
+	if loginCmd.Flags().Lookup("format") == nil {
+		t.Errorf("login has no --format")
+	}
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"fmt"
+	"mfa4aws/internal/pkg/aws"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	defaultMinimumLifetime = 5 * time.Minute
+	defaultRequestTimeout  = 30 * time.Second
+)
+
+var (
+	awsProfile      string
+	mfaToken        string
+	tokenCommand    string
+	mfaSerial       string
+	forceRefresh    bool
+	minimumLifetime time.Duration
+	sessionDuration time.Duration
+	sessionUntil    string
+	endpointConfig  aws.EndpointConfig
+	requestConfig   aws.RequestConfig
+)
+
+//untilLayouts are the accepted formats of --until, either a time of day or a full timestamp
+var untilLayouts = []string{"15:04", "15:04:05", time.RFC3339}
+
+//addCredentialFlags registers the flags required to generate STS credentials on cmd
+func addCredentialFlags(cmd *cobra.Command) {
+	persistentFlags := cmd.PersistentFlags()
+	persistentFlags.StringVarP(&awsProfile, "profile", "p", "default", "AWS Profile name in the AWS credentials or config file, $AWS_PROFILE when not given")
+	persistentFlags.StringVarP(&mfaToken, "token", "t", "", "Current MFA value to use for STS generation, or - to read it from stdin (prompted for when not given)")
+	persistentFlags.StringVar(&tokenCommand, "token-command", "", "Command whose output is used as the MFA value when --token is not given")
+	persistentFlags.StringVar(&mfaSerial, "serial", "", "Serial number or ARN of the MFA device to use, overriding mfa_serial in the profile")
+	persistentFlags.BoolVarP(&forceRefresh, "force", "f", false, "Ignore any cached session and generate new STS credentials")
+	persistentFlags.DurationVar(&minimumLifetime, "min-lifetime", defaultMinimumLifetime, "Minimum remaining lifetime of a cached session for it to be reused")
+	persistentFlags.DurationVar(&sessionDuration, "duration", 0, "Lifetime of a new session such as 4h or 90m, overriding duration_seconds in the profile")
+	persistentFlags.StringVar(&sessionUntil, "until", "", "Time of day such as 18:00, or RFC3339 timestamp, a new session should last until")
+	addEndpointFlags(persistentFlags)
+	addRequestFlags(persistentFlags)
+}
+
+//addEndpointFlags registers the flags selecting the region and the STS and IAM endpoints on flags
+func addEndpointFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&endpointConfig.Region, "region", "", "AWS region to call STS and IAM in, overriding region in the profile")
+	flags.StringVar(&endpointConfig.STSRegionalEndpoints, "sts-regional-endpoints", "", "Use the "+aws.STSRegionalEndpointsRegional+" or "+
+		aws.STSRegionalEndpointsLegacy+" global STS endpoint, overriding sts_regional_endpoints in the profile")
+	flags.BoolVar(&endpointConfig.UseFIPSEndpoint, "use-fips-endpoint", false, "Call the FIPS endpoints of STS and IAM")
+	flags.BoolVar(&endpointConfig.UseDualStackEndpoint, "use-dualstack-endpoint", false, "Call the dual-stack endpoint of STS")
+	flags.StringVar(&endpointConfig.STSEndpoint, "sts-endpoint", "", "URL of the STS endpoint, overriding sts_endpoint in the profile")
+	flags.StringVar(&endpointConfig.IAMEndpoint, "iam-endpoint", "", "URL of the IAM endpoint, overriding iam_endpoint in the profile")
+}
+
+//addRequestFlags registers the timeout and retry flags on flags
+func addRequestFlags(flags *pflag.FlagSet) {
+	flags.DurationVar(&requestConfig.Timeout, "timeout", defaultRequestTimeout, "Time each call to STS and IAM may take including its retries, 0 for no limit")
+	flags.IntVar(&requestConfig.MaxRetries, "max-retries", aws.DefaultMaxRetries, "Times a throttled or failed call to STS and IAM is retried, calls with an MFA value never are")
+}
+
+//credentialsInput builds the STS credentials request from the command line flags
+func credentialsInput() (*aws.STSCredentialsInput, error) {
+	duration, err := requestedDuration(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	input := &aws.STSCredentialsInput{
+		Profile:                awsProfile,
+		TokenProvider:          tokenProvider(),
+		GeneratedTokenProvider: generatedTokenProvider(),
+		SerialNumber:           mfaSerial,
+		SelectMFADevice:        selectMFADevice,
+		Force:                  forceRefresh,
+		MinimumLifetime:        minimumLifetime,
+		Duration:               duration,
+		ProfileSource:          profileSource(),
+	}
+	if mfaToken != stdinToken {
+		input.TokenCode = mfaToken
+	}
+	return input, nil
+}
+
+//profileSource returns where the long term keys are read from and how AWS is reached, from the command line flags
+func profileSource() aws.ProfileSource {
+	return aws.ProfileSource{
+		AccessKeyStore:  &vaultAccessKeyStore{},
+		CredentialsFile: credentialsFile,
+		ConfigFile:      configFile,
+		Endpoints:       endpointConfig,
+		Requests:        requestConfig,
+	}
+}
+
+//requestedDuration returns the session lifetime given by --duration or --until, or zero when neither is set
+func requestedDuration(now time.Time) (time.Duration, error) {
+	if len(sessionUntil) == 0 {
+		return sessionDuration, nil
+	}
+	if sessionDuration != 0 {
+		return 0, configError(fmt.Errorf("Only one of --duration and --until can be given"))
+	}
+	return parseUntil(sessionUntil, now)
+}
+
+//parseUntil returns the time from now until value, a time of day being its next occurrence after now
+func parseUntil(value string, now time.Time) (time.Duration, error) {
+	for _, layout := range untilLayouts {
+		until, err := time.ParseInLocation(layout, value, now.Location())
+		if err != nil {
+			continue
+		}
+
+		if layout != time.RFC3339 {
+			until = time.Date(now.Year(), now.Month(), now.Day(), until.Hour(), until.Minute(), until.Second(), 0, now.Location())
+			if !until.After(now) {
+				until = until.AddDate(0, 0, 1)
+			}
+		}
+
+		if !until.After(now) {
+			return 0, configError(fmt.Errorf("--until %s is in the past", value))
+		}
+		return until.Sub(now).Truncate(time.Second), nil
+	}
+	return 0, configError(fmt.Errorf("Invalid --until %s, must be a time of day such as 18:00 or an RFC3339 timestamp", value))
+}
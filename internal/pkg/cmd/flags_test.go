@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_parseUntil(t *testing.T) {
+	now := time.Date(2020, 1, 2, 9, 30, 0, 0, time.UTC)
+
+	type args struct {
+		value string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    time.Duration
+		wantErr bool
+	}{
+		{
+			"Valid/TimeOfDay",
+			args{
+				value: "18:00",
+			},
+			8*time.Hour + 30*time.Minute,
+			false,
+		},
+		{
+			"Valid/TimeOfDayWithSeconds",
+			args{
+				value: "09:45:30",
+			},
+			15*time.Minute + 30*time.Second,
+			false,
+		},
+		{
+			"Valid/TimeOfDayTomorrow",
+			args{
+				value: "08:00",
+			},
+			22*time.Hour + 30*time.Minute,
+			false,
+		},
+		{
+			"Valid/Timestamp",
+			args{
+				value: "2020-01-03T09:30:00Z",
+			},
+			24 * time.Hour,
+			false,
+		},
+		{
+			"Invalid/TimestampInPast",
+			args{
+				value: "2020-01-01T09:30:00Z",
+			},
+			0,
+			true,
+		},
+		{
+			"Invalid/Format",
+			args{
+				value: "6pm",
+			},
+			0,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseUntil(tt.args.value, now)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseUntil() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("parseUntil() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_requestedDuration(t *testing.T) {
+	defer func(duration time.Duration, until string) {
+		sessionDuration, sessionUntil = duration, until
+	}(sessionDuration, sessionUntil)
+
+	now := time.Date(2020, 1, 2, 9, 30, 0, 0, time.UTC)
+
+	sessionDuration, sessionUntil = 4*time.Hour, ""
+	if got, err := requestedDuration(now); err != nil || got != 4*time.Hour {
+		t.Errorf("requestedDuration() = %v, %v, want %v", got, err, 4*time.Hour)
+	}
+
+	sessionDuration, sessionUntil = 0, "10:30"
+	if got, err := requestedDuration(now); err != nil || got != time.Hour {
+		t.Errorf("requestedDuration() = %v, %v, want %v", got, err, time.Hour)
+	}
+
+	sessionDuration, sessionUntil = 4*time.Hour, "10:30"
+	if _, err := requestedDuration(now); err == nil {
+		t.Errorf("requestedDuration() expected an error when both --duration and --until are set")
+	}
+}
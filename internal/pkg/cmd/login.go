@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"mfa4aws/internal/pkg/aws"
+	"mfa4aws/internal/pkg/shell"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	defaultProfileSuffix string = "-mfa"
+)
+
+var (
+	profileSuffix string
+	loginFormat   string
+
+	errEmptyProfileSuffix = errors.New("Profile suffix must not be empty")
+)
+
+func init() {
+	rootCmd.AddCommand(loginCmd)
+	addCredentialFlags(loginCmd)
+
+	loginCmd.Flags().StringVar(&profileSuffix, "suffix", defaultProfileSuffix, "Suffix appended to the profile name for the derived MFA profile")
+	addFormatFlag(loginCmd, &loginFormat, "", "Format to also write the credentials to stdout in")
+}
+
+var loginCmd = &cobra.Command{
+	Use:   "login",
+	Short: "Writes AWS STS access keys into a derived profile in $HOME/.aws/credentials",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(profileSuffix) == 0 {
+			exitWithError(os.Stderr, configError(errEmptyProfileSuffix))
+		}
+
+		var formatter shell.Formatter
+		if len(loginFormat) != 0 {
+			var err error
+			if formatter, err = lookupFormatter(loginFormat); err != nil {
+				exitWithError(os.Stderr, err)
+			}
+		}
+
+		ctx, stop := interruptContext()
+		defer stop()
+		requestConfig.Context = ctx
+
+		input, err := credentialsInput()
+		if err != nil {
+			exitWithError(os.Stderr, err)
+		}
+
+		creds, err := generateCredentials(input)
+		if err != nil {
+			exitWithError(os.Stderr, err)
+		}
+
+		derivedProfile := awsProfile + profileSuffix
+		if err := aws.WriteCredentialsProfile(credentialsFile, derivedProfile, creds); err != nil {
+			exitWithError(os.Stderr, err)
+		}
+
+		//the credentials own stdout when they are written to it
+		if formatter == nil {
+			fmt.Printf("Wrote credentials to profile %s, valid until %s\n", derivedProfile, creds.Expiration.Local().Format(time.RFC1123))
+			return
+		}
+		fmt.Fprintf(os.Stderr, "Wrote credentials to profile %s, valid until %s\n", derivedProfile, creds.Expiration.Local().Format(time.RFC1123))
+		if err := formatter.Format(os.Stdout, awsProfile, creds); err != nil {
+			exitWithError(os.Stderr, err)
+		}
+	},
+}
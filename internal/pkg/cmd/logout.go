@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+	"mfa4aws/internal/pkg/aws"
+	"mfa4aws/internal/pkg/shell"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	logoutProfile string
+	logoutAll     bool
+)
+
+func init() {
+	rootCmd.AddCommand(logoutCmd)
+
+	logoutCmd.Flags().StringVarP(&logoutProfile, "profile", "p", "", "AWS Profile name whose sessions are removed (default the profile of the session in the environment, else $AWS_PROFILE or \"default\")")
+	logoutCmd.Flags().BoolVar(&logoutAll, "all", false, "Remove the cached sessions and derived profiles of every profile")
+	logoutCmd.Flags().StringVar(&shellDialect, "shell", "", "Shell to output statements for, one of "+strings.Join(shell.DialectNames(), ", ")+" (default detected from $SHELL)")
+	logoutCmd.Flags().StringVar(&profileSuffix, "suffix", defaultProfileSuffix, "Suffix appended to the profile name for the derived MFA profile written by login")
+}
+
+var logoutCmd = &cobra.Command{
+	Use:   "logout",
+	Short: "Unsets AWS STS access keys on the shell by wrapping the result in eval, and removes cached sessions",
+	Run: func(cmd *cobra.Command, args []string) {
+		dialect, err := lookupShellDialect()
+		if err != nil {
+			exitWithError(os.Stderr, err)
+		}
+
+		environ := os.Environ()
+		input := &aws.LogoutInput{Profile: logoutProfile, DerivedProfileSuffix: profileSuffix, All: logoutAll, CredentialsFile: credentialsFile}
+		if creds := shell.EnvCredentials(environ); creds != nil {
+			input.AccessKeyID = creds.AWSAccessKeyID
+		} else if len(input.Profile) == 0 {
+			input.Profile = shell.EnvProfile(environ)
+			if len(input.Profile) == 0 {
+				input.Profile = "default"
+			}
+		}
+
+		output, err := aws.Logout(input)
+		if err != nil {
+			exitWithError(os.Stderr, err)
+		}
+
+		agentSessions := forgetAgentSessions(input)
+
+		shell.PrintVars(os.Stdout, shell.BuildDialectUnsetVars(dialect))
+
+		fmt.Fprintf(os.Stderr, "Removed %d cached sessions", output.Sessions)
+		if agentSessions != 0 {
+			fmt.Fprintf(os.Stderr, ", %d sessions held by the agent", agentSessions)
+		}
+		if len(output.Profiles) != 0 {
+			fmt.Fprintf(os.Stderr, " and profiles %s", strings.Join(output.Profiles, ", "))
+		}
+		fmt.Fprintln(os.Stderr)
+	},
+}
@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"mfa4aws/internal/pkg/aws"
+	"mfa4aws/internal/pkg/qrcode"
+	"mfa4aws/internal/pkg/totp"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	mfaDeviceIssuer string = "Amazon Web Services"
+)
+
+var (
+	mfaDeviceName string
+	storeMFASeed  bool
+	invertQRCode  bool
+)
+
+func init() {
+	rootCmd.AddCommand(mfaCmd)
+	mfaCmd.AddCommand(mfaEnrollCmd, mfaResyncCmd)
+
+	mfaCmd.PersistentFlags().StringVarP(&awsProfile, "profile", "p", "default", "AWS Profile name holding the IAM user's long term keys, $AWS_PROFILE when not given")
+	addEndpointFlags(mfaCmd.PersistentFlags())
+	addRequestFlags(mfaCmd.PersistentFlags())
+
+	mfaEnrollCmd.Flags().StringVar(&mfaDeviceName, "device-name", "", "Name of the new virtual MFA device (default the IAM user name)")
+	mfaEnrollCmd.Flags().BoolVar(&storeMFASeed, "store-seed", false, "Store the seed in the encrypted vault so mfa4aws generates the MFA value itself")
+	mfaEnrollCmd.Flags().BoolVar(&invertQRCode, "invert", false, "Draw the QR code for a terminal with a light background")
+
+	mfaResyncCmd.Flags().StringVar(&mfaSerial, "serial", "", "Serial number or ARN of the MFA device to resync, overriding mfa_serial in the profile")
+}
+
+var mfaCmd = &cobra.Command{
+	Use:   "mfa",
+	Short: "Manages the IAM user's MFA devices",
+}
+
+var mfaEnrollCmd = &cobra.Command{
+	Use:   "enroll",
+	Short: "Creates and enables a virtual MFA device, showing its QR code on the terminal",
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, stop := interruptContext()
+		defer stop()
+		requestConfig.Context, requestConfig.Hold = ctx, holdExit
+
+		device, err := aws.EnrollMFADevice(&aws.MFAEnrollmentInput{
+			Profile:       awsProfile,
+			DeviceName:    mfaDeviceName,
+			Codes:         terminalMFACodes,
+			ProfileSource: profileSource(),
+		})
+		if err != nil {
+			exitWithError(os.Stderr, err)
+		}
+
+		fmt.Printf("Enabled MFA device %s for user %s\n", device.SerialNumber, device.UserName)
+
+		if storeMFASeed {
+			key, err := totp.ParseSecret(device.Seed)
+			if err != nil {
+				exitWithError(os.Stderr, err)
+			}
+			key.Issuer = mfaDeviceIssuer
+			key.Account = fmt.Sprintf("%s@%s", device.UserName, device.AccountID)
+
+			if err := storeTOTPKey(awsProfile, key); err != nil {
+				exitWithError(os.Stderr, err)
+			}
+			fmt.Printf("Stored TOTP seed for profile %s\n", awsProfile)
+		}
+	},
+}
+
+var mfaResyncCmd = &cobra.Command{
+	Use:   "resync",
+	Short: "Resynchronises an MFA device whose codes are rejected because its clock has drifted",
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, stop := interruptContext()
+		defer stop()
+		requestConfig.Context = ctx
+
+		serialNumber, err := aws.ResyncMFADevice(&aws.MFAResyncInput{
+			Profile:         awsProfile,
+			SerialNumber:    mfaSerial,
+			SelectMFADevice: selectMFADevice,
+			Codes:           terminalResyncCodes,
+			ProfileSource:   profileSource(),
+		})
+		if err != nil {
+			exitWithError(os.Stderr, err)
+		}
+
+		fmt.Printf("Resynced MFA device %s\n", serialNumber)
+	},
+}
+
+//terminalMFACodes shows the new device on the terminal and asks for two consecutive MFA values from it
+func terminalMFACodes(device *aws.VirtualMFADevice) (string, string, error) {
+	in, out, closeTerminal := openTerminal()
+	defer closeTerminal()
+
+	return promptMFACodes(requestConfig.Context, in, out, device, invertQRCode)
+}
+
+//promptMFACodes draws the QR code of device on out and reads two consecutive MFA values from in
+func promptMFACodes(ctx context.Context, in io.Reader, out io.Writer, device *aws.VirtualMFADevice, invert bool) (string, string, error) {
+	code, err := qrcode.DecodePNG(device.QRCodePNG)
+	if err == nil {
+		err = code.Write(out, invert)
+	}
+	if err != nil {
+		fmt.Fprintf(out, "Unable to draw the QR code - %v\n", err)
+	}
+	fmt.Fprintf(out, "Scan the QR code with your authenticator app, or enter the secret key %s\n", device.Seed)
+
+	return readMFACodes(ctx, in, out)
+}
+
+//terminalResyncCodes asks on the terminal for two consecutive MFA values from the device being resynced
+func terminalResyncCodes(serialNumber string) (string, string, error) {
+	in, out, closeTerminal := openTerminal()
+	defer closeTerminal()
+
+	fmt.Fprintf(out, "Resyncing MFA device %s\n", serialNumber)
+	return readMFACodes(requestConfig.Context, in, out)
+}
+
+//readMFACodes reads two consecutive MFA values from in, returning ctx.Err() once ctx is cancelled
+func readMFACodes(ctx context.Context, in io.Reader, out io.Writer) (string, string, error) {
+	type mfaCodes struct {
+		code1, code2 string
+		err          error
+	}
+
+	//reading the terminal cannot be interrupted, the read is abandoned instead
+	result := make(chan mfaCodes, 1)
+	go func() {
+		code1, code2, err := readMFACodePair(in, out)
+		result <- mfaCodes{code1, code2, err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return "", "", ctx.Err()
+	case r := <-result:
+		return r.code1, r.code2, r.err
+	}
+}
+
+//readMFACodePair reads two consecutive MFA values from in
+func readMFACodePair(in io.Reader, out io.Writer) (string, string, error) {
+	reader := bufio.NewReader(in)
+	code1, err := readToken(reader, out, "First MFA code")
+	if err != nil {
+		return "", "", err
+	}
+
+	code2, err := readToken(reader, out, "Next MFA code, once the first has changed")
+	if err != nil {
+		return "", "", err
+	}
+	return code1, code2, nil
+}
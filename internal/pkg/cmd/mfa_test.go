@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"mfa4aws/internal/pkg/aws"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_promptMFACodes(t *testing.T) {
+	device := &aws.VirtualMFADevice{SerialNumber: "arn:aws:iam::123456789012:mfa/alice", Seed: "JBSWY3DPEHPK3PXP", QRCodePNG: []byte("not a png")}
+
+	tests := []struct {
+		name      string
+		input     string
+		wantCode1 string
+		wantCode2 string
+		wantErr   bool
+	}{
+		{"Valid/TwoCodes", "123456\n654321\n", "123456", "654321", false},
+		{"Valid/Retry", "12\n123456\n654321\n", "123456", "654321", false},
+		{"Invalid/OneCode", "123456\n", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := bytes.NewBuffer(nil)
+			code1, code2, err := promptMFACodes(context.Background(), strings.NewReader(tt.input), out, device, false)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("promptMFACodes() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if code1 != tt.wantCode1 || code2 != tt.wantCode2 {
+				t.Errorf("promptMFACodes() = %v, %v, want %v, %v", code1, code2, tt.wantCode1, tt.wantCode2)
+			}
+			if !strings.Contains(out.String(), device.Seed) {
+				t.Errorf("promptMFACodes() output %q does not show the seed", out.String())
+			}
+		})
+	}
+}
+
+func Test_promptMFACodesCancelled(t *testing.T) {
+	device := &aws.VirtualMFADevice{SerialNumber: "arn:aws:iam::123456789012:mfa/alice", Seed: "JBSWY3DPEHPK3PXP", QRCodePNG: []byte("not a png")}
+
+	//Ctrl-C while waiting for the codes must return so the device can be deleted
+	in, w := io.Pipe()
+	defer w.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := promptMFACodes(ctx, in, bytes.NewBuffer(nil), device, false)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("promptMFACodes() error = %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("promptMFACodes() did not return once cancelled")
+	}
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"mfa4aws/internal/pkg/shell"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(processCmd)
+	addCredentialFlags(processCmd)
+}
+
+var processCmd = &cobra.Command{
+	Use:   "process",
+	Short: "Generates AWS STS access keys in the format expected by credential_process in $HOME/.aws/config",
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, stop := interruptContext()
+		defer stop()
+		requestConfig.Context = ctx
+
+		input, err := credentialsInput()
+		if err != nil {
+			exitWithError(os.Stderr, err)
+		}
+
+		creds, err := generateCredentials(input)
+		if err != nil {
+			exitWithError(os.Stderr, err)
+		}
+
+		if err := shell.PrintCredentialProcess(os.Stdout, creds); err != nil {
+			exitWithError(os.Stderr, err)
+		}
+	},
+}
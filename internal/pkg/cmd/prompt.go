@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"mfa4aws/internal/pkg/vault"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+const (
+	terminalDevice string = "/dev/tty"
+
+	envNameVaultPassphrase string = "MFA4AWS_VAULT_PASSPHRASE"
+)
+
+var (
+	errInvalidSelection = errors.New("Invalid selection")
+)
+
+//openTerminal returns the controlling terminal for prompting and a func releasing it
+func openTerminal() (io.Reader, io.Writer, func()) {
+	tty, err := os.OpenFile(terminalDevice, os.O_RDWR, 0)
+	if err != nil {
+		return os.Stdin, os.Stderr, func() {}
+	}
+	return tty, tty, func() { tty.Close() }
+}
+
+//selectMFADevice asks on the terminal which of several MFA devices to use
+func selectMFADevice(serialNumbers []string) (string, error) {
+	in, out, closeTerminal := openTerminal()
+	defer closeTerminal()
+
+	return promptSelection(in, out, "Multiple MFA devices are registered, select one", serialNumbers)
+}
+
+//promptSelection writes the numbered options to out and reads the chosen number from in
+func promptSelection(in io.Reader, out io.Writer, title string, options []string) (string, error) {
+	fmt.Fprintf(out, "%s:\n", title)
+	for i, x := range options {
+		fmt.Fprintf(out, "  %d) %s\n", i+1, x)
+	}
+	fmt.Fprintf(out, "Enter a number [1-%d]: ", len(options))
+
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && len(line) == 0 {
+		return "", err
+	}
+
+	n, err := strconv.Atoi(strings.TrimSpace(line))
+	if err != nil || n < 1 || n > len(options) {
+		return "", errInvalidSelection
+	}
+
+	return options[n-1], nil
+}
+
+//readSecret asks for a value on the terminal without echoing it, reading a line from stdin when there is no terminal
+func readSecret(prompt string) (string, error) {
+	tty, err := os.OpenFile(terminalDevice, os.O_RDWR, 0)
+	if err != nil {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && len(line) == 0 {
+			return "", err
+		}
+		return strings.TrimSpace(line), nil
+	}
+	defer tty.Close()
+
+	fmt.Fprint(tty, prompt)
+	value, err := term.ReadPassword(int(tty.Fd()))
+	fmt.Fprintln(tty)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(value)), nil
+}
+
+//unlockVault unlocks v with the passphrase in $MFA4AWS_VAULT_PASSPHRASE, or asks for it on the terminal
+func unlockVault(v *vault.Vault) error {
+	passphrase, ok := os.LookupEnv(envNameVaultPassphrase)
+	if !ok {
+		var err error
+		passphrase, err = readSecret("Vault passphrase: ")
+		if err != nil {
+			return err
+		}
+	}
+
+	return v.Unlock([]byte(passphrase))
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func Test_promptSelection(t *testing.T) {
+	type args struct {
+		in      string
+		options []string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    string
+		wantErr bool
+	}{
+		{
+			"Valid/FirstOption",
+			args{
+				in:      "1\n",
+				options: []string{"phone", "backup"},
+			},
+			"phone",
+			false,
+		},
+		{
+			"Valid/SecondOptionNoNewline",
+			args{
+				in:      " 2 ",
+				options: []string{"phone", "backup"},
+			},
+			"backup",
+			false,
+		},
+		{
+			"Invalid/OutOfRange",
+			args{
+				in:      "3\n",
+				options: []string{"phone", "backup"},
+			},
+			"",
+			true,
+		},
+		{
+			"Invalid/NotANumber",
+			args{
+				in:      "phone\n",
+				options: []string{"phone", "backup"},
+			},
+			"",
+			true,
+		},
+		{
+			"Invalid/NoInput",
+			args{
+				in:      "",
+				options: []string{"phone", "backup"},
+			},
+			"",
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			got, err := promptSelection(strings.NewReader(tt.args.in), out, "Select", tt.args.options)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("promptSelection() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("promptSelection() = %v, want %v", got, tt.want)
+			}
+			if !strings.Contains(out.String(), "  2) backup\n") {
+				t.Errorf("promptSelection() output = %v, missing options", out.String())
+			}
+		})
+	}
+}
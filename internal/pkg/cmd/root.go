@@ -2,23 +2,78 @@ package cmd
 
 import (
 	"fmt"
+	"mfa4aws/internal/pkg/shell"
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 )
 
 var (
-	releaseVersion string
+	releaseVersion  string
+	credentialsFile string
+	configFile      string
+	errorFormat     string
 )
 
-var rootCmd = &cobra.Command{Use: "shell"}
+var rootCmd = &cobra.Command{Use: "shell", PersistentPreRun: persistentPreRun, SilenceErrors: true, SilenceUsage: true}
+
+func init() {
+	persistentFlags := rootCmd.PersistentFlags()
+	persistentFlags.StringVar(&credentialsFile, "credentials-file", "", "AWS credentials file (default $AWS_SHARED_CREDENTIALS_FILE or $HOME/.aws/credentials)")
+	persistentFlags.StringVar(&configFile, "config-file", "", "AWS config file (default $AWS_CONFIG_FILE or $HOME/.aws/config)")
+	persistentFlags.StringVar(&errorFormat, "error-format", errorFormatText, "Format errors are written in, one of "+errorFormatJSON+", "+errorFormatText)
+}
+
+//persistentPreRun checks --error-format and applies $AWS_PROFILE before any command runs
+func persistentPreRun(cmd *cobra.Command, args []string) {
+	if errorFormat != errorFormatText && errorFormat != errorFormatJSON {
+		err := configError(fmt.Errorf("Unsupported error format %s, use one of %s, %s", errorFormat, errorFormatJSON, errorFormatText))
+		errorFormat = errorFormatText
+		exitWithError(os.Stderr, err)
+	}
+	applyEnvProfile(cmd, args)
+}
+
+//applyEnvProfile sets --profile from $AWS_PROFILE when the flag is not given
+func applyEnvProfile(cmd *cobra.Command, args []string) {
+	flag := cmd.Flags().Lookup("profile")
+	if flag == nil || flag.Changed || len(flag.DefValue) == 0 {
+		return
+	}
+	if profile := shell.EnvProfile(os.Environ()); len(profile) != 0 {
+		_ = flag.Value.Set(profile)
+	}
+}
 
 // Execute is the entry point for the MFA command
 func Execute(version string) {
 	releaseVersion = version
 
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+	//only flag and argument errors reach here, commands exit with the code of their own errors
+	if cmd, err := rootCmd.ExecuteC(); err != nil {
+		errorFormat = argsErrorFormat(os.Args[1:], errorFormat)
+		err = configError(err)
+		if errorFormat != errorFormatJSON {
+			cmd.PrintErrln("Error:", err.Error())
+			cmd.PrintErrln(cmd.UsageString())
+			os.Exit(errorExitCode(err))
+		}
+		exitWithError(os.Stderr, err)
+	}
+}
+
+//argsErrorFormat returns the --error-format given in args, or format when none is
+func argsErrorFormat(args []string, format string) string {
+	for i, arg := range args {
+		switch {
+		case arg == "--":
+			return format
+		case arg == "--error-format" && i+1 < len(args):
+			format = args[i+1]
+		case strings.HasPrefix(arg, "--error-format="):
+			format = strings.TrimPrefix(arg, "--error-format=")
+		}
 	}
+	return format
 }
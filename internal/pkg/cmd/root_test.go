@@ -29,3 +29,39 @@ func TestRootExecute(t *testing.T) {
 		})
 	}
 }
+
+func Test_argsErrorFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{
+			"Valid/NotGiven",
+			[]string{"login", "--bogus"},
+			errorFormatText,
+		},
+		{
+			"Valid/AfterInvalidFlag",
+			[]string{"login", "--bogus", "--error-format", "json"},
+			errorFormatJSON,
+		},
+		{
+			"Valid/Equals",
+			[]string{"--error-format=json", "login", "--bogus"},
+			errorFormatJSON,
+		},
+		{
+			"Valid/AfterDashDash",
+			[]string{"exec", "--bogus", "--", "aws", "--error-format", "json"},
+			errorFormatText,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := argsErrorFormat(tt.args, errorFormatText); got != tt.want {
+				t.Errorf("argsErrorFormat() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
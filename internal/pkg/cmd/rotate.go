@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"fmt"
+	"mfa4aws/internal/pkg/aws"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(rotateCmd)
+	addCredentialFlags(rotateCmd)
+}
+
+var rotateCmd = &cobra.Command{
+	Use:   "rotate",
+	Short: "Replaces the long term IAM access keys of a profile with a new pair and deletes the old key",
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, stop := interruptContext()
+		defer stop()
+		requestConfig.Context, requestConfig.Hold = ctx, holdExit
+
+		input, err := credentialsInput()
+		if err != nil {
+			exitWithError(os.Stderr, err)
+		}
+
+		rotation, err := aws.RotateAccessKeys(input)
+		if err != nil {
+			exitWithError(os.Stderr, err)
+		}
+
+		if len(rotation.DeletedAccessKeyID) != 0 {
+			fmt.Printf("Deleted inactive access key %s of user %s\n", rotation.DeletedAccessKeyID, rotation.UserName)
+		}
+		fmt.Printf("Rotated access key %s to %s for profile %s\n", rotation.OldAccessKeyID, rotation.NewAccessKeyID, awsProfile)
+	},
+}
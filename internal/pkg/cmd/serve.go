@@ -0,0 +1,219 @@
+package cmd
+
+import (
+	"fmt"
+	"mfa4aws/internal/pkg/aws"
+	"mfa4aws/internal/pkg/server"
+	"mfa4aws/internal/pkg/shell"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	defaultIMDSListenAddress string = "127.0.0.1:9099"
+	defaultECSListenAddress  string = "127.0.0.1:9098"
+
+	//imdsAddress is the address of the instance metadata service, which may be aliased on the loopback interface
+	imdsAddress string = "169.254.169.254"
+)
+
+var (
+	imdsListenAddress string
+	imdsRoleName      string
+	ecsListenAddress  string
+
+	//containerCommands are the commands whose run sub-command is given the container credentials variables
+	containerCommands = []string{"docker", "podman"}
+)
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+	serveCmd.AddCommand(serveIMDSCmd, serveECSCmd)
+	addCredentialFlags(serveCmd)
+
+	serveIMDSCmd.Flags().StringVar(&imdsListenAddress, "listen", defaultIMDSListenAddress, "Loopback address to listen on, or "+imdsAddress+" aliased on the loopback interface")
+	serveIMDSCmd.Flags().StringVar(&imdsRoleName, "role-name", "", "Instance role name the credentials are served as (default the profile name)")
+
+	serveECSCmd.Flags().StringVar(&ecsListenAddress, "listen", defaultECSListenAddress, "Loopback address to listen on")
+}
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serves AWS STS access keys over the HTTP endpoints SDKs read credentials from",
+}
+
+var serveIMDSCmd = &cobra.Command{
+	Use:   "imds",
+	Short: "Serves AWS STS access keys as an EC2 instance metadata service (IMDSv2) role",
+	Run: func(cmd *cobra.Command, args []string) {
+		roleName := imdsRoleName
+		if len(roleName) == 0 {
+			roleName = awsProfile
+		}
+
+		if err := checkListenAddress(imdsListenAddress, imdsAddress); err != nil {
+			exitWithError(os.Stderr, err)
+		}
+
+		source, err := newServeSource()
+		if err != nil {
+			exitWithError(os.Stderr, err)
+		}
+
+		listener, err := listen(imdsListenAddress, "EC2 instance metadata")
+		if err != nil {
+			exitWithError(os.Stderr, err)
+		}
+
+		if err := http.Serve(listener, server.NewIMDSHandler(source, roleName)); err != nil {
+			exitWithError(os.Stderr, err)
+		}
+	},
+}
+
+var serveECSCmd = &cobra.Command{
+	Use:   "ecs [flags] [-- command [args...]]",
+	Short: "Serves AWS STS access keys as an ECS container credentials endpoint, optionally for the lifetime of a command",
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := checkListenAddress(ecsListenAddress); err != nil {
+			exitWithError(os.Stderr, err)
+		}
+
+		source, err := newServeSource()
+		if err != nil {
+			exitWithError(os.Stderr, err)
+		}
+
+		authorizationToken, err := server.NewAuthorizationToken()
+		if err != nil {
+			exitWithError(os.Stderr, err)
+		}
+
+		listener, err := listen(ecsListenAddress, "ECS container")
+		if err != nil {
+			exitWithError(os.Stderr, err)
+		}
+
+		uri := fmt.Sprintf("http://%s%s", listener.Addr(), server.ECSCredentialsPath)
+		handler := server.NewECSHandler(source, authorizationToken)
+
+		//the server keeps running, so the statements are shown for the user to copy rather than written for eval
+		if len(args) == 0 {
+			statements, err := shell.DetectDialect(os.Getenv(envNameShell)).Export(shell.ContainerEnvVars(uri, authorizationToken))
+			if err != nil {
+				exitWithError(os.Stderr, err)
+			}
+			fmt.Fprintln(os.Stderr, "Set these variables in the shell running your tools:")
+			shell.PrintVars(os.Stderr, statements)
+			if err := http.Serve(listener, handler); err != nil {
+				exitWithError(os.Stderr, err)
+			}
+			return
+		}
+
+		go http.Serve(listener, handler)
+
+		containerArgs := containerRunArgs(args)
+		if runtime.GOOS != "linux" && len(containerArgs) != len(args) && !hasNetworkArg(args) {
+			fmt.Fprintln(os.Stderr, "The container gets --network host, which does not reach the endpoint on Docker Desktop, pass the credentials with mfa4aws exec --format docker-env instead")
+		}
+		args = containerArgs
+		exitCode, err := runCommand(args[0], args[1:], shell.BuildContainerEnv(os.Environ(), uri, authorizationToken))
+		if err != nil {
+			exitWithError(os.Stderr, err)
+		}
+
+		os.Exit(exitCode)
+	},
+}
+
+//newServeSource returns the Source of the credentials served, generating the first session straight away
+func newServeSource() (*server.Source, error) {
+	source := server.NewSource(func() (*aws.Credentials, error) {
+		input, err := credentialsInput()
+		if err != nil {
+			return nil, err
+		}
+		//an MFA value given on the command line or stdin can only be used once
+		mfaToken = ""
+		return generateCredentials(input)
+	}, minimumLifetime)
+
+	if _, err := source.Credentials(); err != nil {
+		return nil, err
+	}
+	return source, nil
+}
+
+//listen listens on address and reports where the description credentials are served
+func listen(address string, description string) (net.Listener, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(os.Stderr, "Serving %s credentials for profile %s on http://%s\n", description, awsProfile, listener.Addr())
+	return listener, nil
+}
+
+//checkListenAddress returns an error unless the host of address is loopback or one of allowed
+func checkListenAddress(address string, allowed ...string) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return configError(fmt.Errorf("Invalid --listen %s - %v", address, err))
+	}
+
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	for _, x := range allowed {
+		if host == x {
+			return nil
+		}
+	}
+	return configError(fmt.Errorf("Invalid --listen %s, credentials are only served on a loopback address", address))
+}
+
+//containerRunArgs passes the container credentials variables through to a docker or podman container
+func containerRunArgs(args []string) []string {
+	if len(args) < 2 || args[1] != "run" {
+		return args
+	}
+
+	name := filepath.Base(args[0])
+	isContainerCommand := false
+	for _, x := range containerCommands {
+		if name == x || name == x+".exe" {
+			isContainerCommand = true
+		}
+	}
+	if !isContainerCommand {
+		return args
+	}
+
+	runArgs := []string{"--env", shell.EnvNameContainerCredentialsFullURI, "--env", shell.EnvNameContainerAuthorizationToken}
+	//the host network only reaches the loopback listener on Linux, Docker Desktop runs containers in a virtual machine
+	if !hasNetworkArg(args[2:]) {
+		runArgs = append(runArgs, "--network", "host")
+	}
+
+	return append(append(append([]string{}, args[:2]...), runArgs...), args[2:]...)
+}
+
+func hasNetworkArg(args []string) bool {
+	for _, x := range args {
+		if x == "--network" || x == "--net" || strings.HasPrefix(x, "--network=") || strings.HasPrefix(x, "--net=") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_containerRunArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{
+			"Valid/DockerRun",
+			[]string{"docker", "run", "--rm", "amazon/aws-cli", "sts", "get-caller-identity"},
+			[]string{"docker", "run", "--env", "AWS_CONTAINER_CREDENTIALS_FULL_URI", "--env", "AWS_CONTAINER_AUTHORIZATION_TOKEN", "--network", "host", "--rm", "amazon/aws-cli", "sts", "get-caller-identity"},
+		},
+		{
+			"Valid/PodmanRunWithNetwork",
+			[]string{"/usr/bin/podman", "run", "--network=bridge", "amazon/aws-cli"},
+			[]string{"/usr/bin/podman", "run", "--env", "AWS_CONTAINER_CREDENTIALS_FULL_URI", "--env", "AWS_CONTAINER_AUTHORIZATION_TOKEN", "--network=bridge", "amazon/aws-cli"},
+		},
+		{
+			"Valid/DockerOtherCommand",
+			[]string{"docker", "ps"},
+			[]string{"docker", "ps"},
+		},
+		{
+			"Valid/OtherCommand",
+			[]string{"aws", "run"},
+			[]string{"aws", "run"},
+		},
+		{
+			"Valid/NoArgs",
+			[]string{"docker"},
+			[]string{"docker"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := containerRunArgs(tt.args); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("containerRunArgs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_checkListenAddress(t *testing.T) {
+	tests := []struct {
+		name    string
+		address string
+		allowed []string
+		wantErr bool
+	}{
+		{
+			"Valid/IPv4Loopback",
+			"127.0.0.1:9098",
+			nil,
+			false,
+		},
+		{
+			"Valid/IPv6Loopback",
+			"[::1]:9098",
+			nil,
+			false,
+		},
+		{
+			"Valid/Localhost",
+			"localhost:0",
+			nil,
+			false,
+		},
+		{
+			"Valid/Allowed",
+			"169.254.169.254:80",
+			[]string{"169.254.169.254"},
+			false,
+		},
+		{
+			"Invalid/AllInterfaces",
+			":9098",
+			nil,
+			true,
+		},
+		{
+			"Invalid/Unspecified",
+			"0.0.0.0:9098",
+			nil,
+			true,
+		},
+		{
+			"Invalid/NotLoopback",
+			"192.168.1.10:9098",
+			nil,
+			true,
+		},
+		{
+			"Invalid/NotAllowed",
+			"169.254.169.254:80",
+			nil,
+			true,
+		},
+		{
+			"Invalid/NoPort",
+			"127.0.0.1",
+			nil,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkListenAddress(tt.address, tt.allowed...); (err != nil) != tt.wantErr {
+				t.Errorf("checkListenAddress() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -1,42 +1,77 @@
 package cmd
 
 import (
-	"fmt"
-	"mfa4aws/internal/pkg/aws"
 	"mfa4aws/internal/pkg/shell"
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 )
 
+const (
+	envNameShell string = "SHELL"
+)
+
 var (
-	awsProfile string
-	mfaToken   string
+	shellDialect string
+	outputFormat string
 )
 
 func init() {
 	rootCmd.AddCommand(shellCmd)
+	addCredentialFlags(shellCmd)
 
-	persistentFlags := shellCmd.PersistentFlags()
-	persistentFlags.StringVarP(&awsProfile, "profile", "p", "default", "AWS Profile name in $HOME/.aws/credentials")
-	persistentFlags.StringVarP(&mfaToken, "token", "t", "", "Current MFA value to use for STS generation")
-	err := cobra.MarkFlagRequired(persistentFlags, "token")
-	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
-	}
+	shellCmd.Flags().StringVar(&shellDialect, "shell", "", "Shell to output statements for, one of "+strings.Join(shell.DialectNames(), ", ")+" (default detected from $SHELL)")
+	addFormatFlag(shellCmd, &outputFormat, shell.FormatShell, "Output format")
 }
 
 var shellCmd = &cobra.Command{
 	Use:   "shell",
 	Short: "Generates AWS STS access keys for use on the shell by wrapping the result in eval",
 	Run: func(cmd *cobra.Command, args []string) {
-		creds, err := aws.GenerateSTSCredentials(awsProfile, mfaToken)
+		formatter, err := lookupFormatter(outputFormat)
+		if err != nil {
+			exitWithError(os.Stderr, err)
+		}
+
+		ctx, stop := interruptContext()
+		defer stop()
+		requestConfig.Context = ctx
+
+		input, err := credentialsInput()
+		if err != nil {
+			exitWithError(os.Stderr, err)
+		}
+
+		creds, err := generateCredentials(input)
 		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
+			exitWithError(os.Stderr, err)
 		}
 
-		shell.PrintVars(os.Stdout, shell.BuildEnvVars(creds))
+		if err := formatter.Format(os.Stdout, awsProfile, creds); err != nil {
+			exitWithError(os.Stderr, err)
+		}
 	},
 }
+
+//addFormatFlag registers --format on cmd, which selects the format credentials are written to stdout in
+func addFormatFlag(cmd *cobra.Command, format *string, defaultFormat string, usage string) {
+	cmd.Flags().StringVar(format, "format", defaultFormat, usage+", one of "+strings.Join(shell.FormatNames(), ", "))
+}
+
+//lookupFormatter returns the Formatter named by format, the shell format using the dialect of --shell or $SHELL
+func lookupFormatter(format string) (shell.Formatter, error) {
+	dialect, err := lookupShellDialect()
+	if err != nil {
+		return nil, err
+	}
+	return shell.LookupFormatter(format, dialect)
+}
+
+//lookupShellDialect returns the Dialect named by --shell, or the one detected from $SHELL
+func lookupShellDialect() (shell.Dialect, error) {
+	if len(shellDialect) == 0 {
+		return shell.DetectDialect(os.Getenv(envNameShell)), nil
+	}
+	return shell.LookupDialect(shellDialect)
+}
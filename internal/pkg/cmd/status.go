@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mfa4aws/internal/pkg/aws"
+	"mfa4aws/internal/pkg/shell"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	statusFormatText string = "text"
+	statusFormatJSON string = "json"
+)
+
+var (
+	statusProfile string
+	statusFormat  string
+)
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+
+	statusCmd.Flags().StringVarP(&statusProfile, "profile", "p", "", "AWS Profile name whose cached session is shown when the environment holds none (default $AWS_PROFILE or \"default\")")
+	statusCmd.Flags().StringVar(&statusFormat, "format", statusFormatText, "Output format, one of "+statusFormatJSON+", "+statusFormatText)
+	addEndpointFlags(statusCmd.Flags())
+	addRequestFlags(statusCmd.Flags())
+}
+
+var statusCmd = &cobra.Command{
+	Use:     "status",
+	Aliases: []string{"whoami"},
+	Short:   "Shows the identity and remaining lifetime of the session in the environment or the session cache",
+	Run: func(cmd *cobra.Command, args []string) {
+		if statusFormat != statusFormatText && statusFormat != statusFormatJSON {
+			exitWithError(os.Stderr, configError(fmt.Errorf("Unsupported format %s, use one of %s, %s", statusFormat, statusFormatJSON, statusFormatText)))
+		}
+
+		environ := os.Environ()
+		profile := statusProfile
+		if len(profile) == 0 {
+			profile = shell.EnvProfile(environ)
+		}
+		if len(profile) == 0 {
+			profile = "default"
+		}
+
+		ctx, stop := interruptContext()
+		defer stop()
+		requestConfig.Context = ctx
+
+		status, err := aws.GetSessionStatus(&aws.SessionStatusInput{Credentials: shell.EnvCredentials(environ), Profile: profile, Endpoints: endpointConfig,
+			Requests: requestConfig})
+		if errors.Is(err, aws.ErrNoSession) {
+			status = &aws.SessionStatus{Source: aws.SessionSourceCache, Profile: profile, Error: err.Error()}
+		} else if err != nil {
+			exitWithError(os.Stderr, err)
+		}
+
+		if err := writeSessionStatus(os.Stdout, status, statusFormat, time.Now()); err != nil {
+			exitWithError(os.Stderr, err)
+		}
+
+		//an invalid session is reported in the status itself, not as an error of its category
+		if !status.Valid {
+			os.Exit(1)
+		}
+	},
+}
+
+//writeSessionStatus writes status to out as a JSON document or as aligned lines of text
+func writeSessionStatus(out io.Writer, status *aws.SessionStatus, format string, now time.Time) error {
+	if format == statusFormatJSON {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(status)
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	line := func(name string, value string) {
+		if len(value) != 0 {
+			fmt.Fprintf(w, "%s:\t%s\n", name, value)
+		}
+	}
+
+	account := status.AccountID
+	if len(status.AccountAlias) != 0 {
+		account = fmt.Sprintf("%s (%s)", account, status.AccountAlias)
+	}
+
+	mfa := "no"
+	if status.MFAAuthenticated {
+		mfa = strings.TrimSpace("yes " + status.MFASerialNumber)
+	}
+
+	expires := "unknown"
+	if status.Expiration != nil {
+		expires = fmt.Sprintf("%s (in %v)", status.Expiration.Local().Format(time.RFC1123), status.Remaining(now).Round(time.Second))
+	}
+
+	valid := "valid"
+	if !status.Valid {
+		valid = "invalid - " + status.Error
+	}
+
+	line("Principal", status.PrincipalARN)
+	line("Account", account)
+	line("Profile", status.Profile)
+	if status.Valid || status.Expiration != nil || len(status.PrincipalARN) != 0 {
+		line("MFA", mfa)
+		line("Expires", expires)
+	}
+	line("Source", status.Source)
+	line("Session", valid)
+	return w.Flush()
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"mfa4aws/internal/pkg/aws"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_writeSessionStatus(t *testing.T) {
+	now := time.Date(2020, 8, 1, 12, 0, 0, 0, time.UTC)
+	expiration := now.Add(3*time.Hour + 12*time.Minute)
+
+	status := &aws.SessionStatus{
+		Source:           aws.SessionSourceEnvironment,
+		Profile:          "work",
+		PrincipalARN:     "arn:aws:iam::123456789012:user/johnsmith",
+		AccountID:        "123456789012",
+		AccountAlias:     "acme-prod",
+		MFAAuthenticated: true,
+		MFASerialNumber:  "arn:aws:iam::123456789012:mfa/johnsmith",
+		Expiration:       &expiration,
+		Valid:            true,
+	}
+
+	t.Run("Valid/Text", func(t *testing.T) {
+		out := bytes.NewBuffer(nil)
+		if err := writeSessionStatus(out, status, statusFormatText, now); err != nil {
+			t.Fatalf("writeSessionStatus() error = %v", err)
+		}
+		for _, want := range []string{
+			"Principal:  arn:aws:iam::123456789012:user/johnsmith\n",
+			"Account:    123456789012 (acme-prod)\n",
+			"MFA:        yes arn:aws:iam::123456789012:mfa/johnsmith\n",
+			"(in 3h12m0s)\n",
+			"Session:    valid\n",
+		} {
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("writeSessionStatus() = %q, want it to contain %q", out.String(), want)
+			}
+		}
+	})
+
+	t.Run("Valid/JSON", func(t *testing.T) {
+		out := bytes.NewBuffer(nil)
+		if err := writeSessionStatus(out, status, statusFormatJSON, now); err != nil {
+			t.Fatalf("writeSessionStatus() error = %v", err)
+		}
+		var got map[string]interface{}
+		if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+			t.Fatalf("Unmarshal() error = %v", err)
+		}
+		if got["account_alias"] != "acme-prod" || got["mfa_authenticated"] != true || got["valid"] != true {
+			t.Errorf("writeSessionStatus() = %s", out.String())
+		}
+	})
+
+	t.Run("Invalid/NoSession", func(t *testing.T) {
+		out := bytes.NewBuffer(nil)
+		noSession := &aws.SessionStatus{Source: aws.SessionSourceCache, Profile: "work", Error: aws.ErrNoSession.Error()}
+		if err := writeSessionStatus(out, noSession, statusFormatText, now); err != nil {
+			t.Fatalf("writeSessionStatus() error = %v", err)
+		}
+		if want := "Session:  invalid - " + aws.ErrNoSession.Error(); !strings.Contains(out.String(), want) {
+			t.Errorf("writeSessionStatus() = %q, want it to contain %q", out.String(), want)
+		}
+	})
+}
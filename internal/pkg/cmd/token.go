@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"mfa4aws/internal/pkg/aws"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+const (
+	stdinToken       string = "-"
+	maxTokenAttempts int    = 3
+)
+
+//tokenProvider returns the TokenProvider for the command line flags
+func tokenProvider() aws.TokenProvider {
+	if mfaToken == stdinToken {
+		return readerTokenProvider(os.Stdin)
+	}
+	if len(tokenCommand) != 0 {
+		return commandTokenProvider(tokenCommand)
+	}
+	return promptTokenProvider(awsProfile)
+}
+
+//generatedTokenProvider returns the TokenProvider for a stored TOTP seed, or nil when --token - or --token-command is given
+func generatedTokenProvider() aws.TokenProvider {
+	if mfaToken == stdinToken || len(tokenCommand) != 0 {
+		return nil
+	}
+	return storedTOTPTokenProvider(awsProfile)
+}
+
+//readerTokenProvider returns a TokenProvider which reads the MFA value from the first line of in
+func readerTokenProvider(in io.Reader) aws.TokenProvider {
+	return func() (string, error) {
+		line, err := bufio.NewReader(in).ReadString('\n')
+		if err != nil && err != io.EOF {
+			return "", err
+		}
+		return strings.TrimSpace(line), nil
+	}
+}
+
+//promptTokenProvider returns a TokenProvider which asks for the MFA value on the terminal
+func promptTokenProvider(profile string) aws.TokenProvider {
+	return func() (string, error) {
+		in, out, closeTerminal := openTerminal()
+		defer closeTerminal()
+
+		return promptToken(in, out, profile)
+	}
+}
+
+//promptToken asks for the MFA value until a well formed one is entered, giving up after maxTokenAttempts
+func promptToken(in io.Reader, out io.Writer, profile string) (string, error) {
+	return readToken(bufio.NewReader(in), out, fmt.Sprintf("MFA token for profile %s", profile))
+}
+
+//readToken asks for an MFA value with label until a well formed one is entered, giving up after maxTokenAttempts
+func readToken(reader *bufio.Reader, out io.Writer, label string) (string, error) {
+	for attempt := 1; ; attempt++ {
+		fmt.Fprintf(out, "%s: ", label)
+
+		line, readErr := reader.ReadString('\n')
+		token := strings.TrimSpace(line)
+		if err := aws.ValidateToken(token); err == nil {
+			return token, nil
+		}
+
+		if readErr != nil {
+			fmt.Fprintln(out)
+			return "", aws.ErrInvalidToken
+		}
+		if attempt == maxTokenAttempts {
+			return "", aws.ErrInvalidToken
+		}
+		fmt.Fprintf(out, "%v, try again\n", aws.ErrInvalidToken)
+	}
+}
+
+//commandTokenProvider returns a TokenProvider reading the MFA value from the output of command
+func commandTokenProvider(command string) aws.TokenProvider {
+	return func() (string, error) {
+		var child *exec.Cmd
+		if runtime.GOOS == "windows" {
+			child = exec.Command("cmd", "/C", command)
+		} else {
+			child = exec.Command("sh", "-c", command)
+		}
+
+		out := bytes.NewBuffer(nil)
+		child.Stdout = out
+		child.Stderr = os.Stderr
+
+		if err := child.Run(); err != nil {
+			return "", fmt.Errorf("Unable to retrieve MFA value from token command - %v", err)
+		}
+
+		return strings.TrimSpace(out.String()), nil
+	}
+}
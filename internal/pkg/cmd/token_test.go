@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func Test_commandTokenProvider(t *testing.T) {
+	type args struct {
+		command string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    string
+		wantErr bool
+	}{
+		{
+			"Valid/Token",
+			args{
+				command: "echo 123456",
+			},
+			"123456",
+			false,
+		},
+		{
+			"Valid/TokenWithWhitespace",
+			args{
+				command: "printf '  654321 \n'",
+			},
+			"654321",
+			false,
+		},
+		{
+			"Invalid/CommandFails",
+			args{
+				command: "exit 1",
+			},
+			"",
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := commandTokenProvider(tt.args.command)()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("commandTokenProvider() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("commandTokenProvider() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_promptToken(t *testing.T) {
+	type args struct {
+		in string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    string
+		wantErr bool
+	}{
+		{
+			"Valid/Token",
+			args{
+				in: "123456\n",
+			},
+			"123456",
+			false,
+		},
+		{
+			"Valid/TokenNoNewline",
+			args{
+				in: "123456",
+			},
+			"123456",
+			false,
+		},
+		{
+			"Valid/RepromptAfterMalformedToken",
+			args{
+				in: "12a456\n123\n654321\n",
+			},
+			"654321",
+			false,
+		},
+		{
+			"Invalid/TooManyAttempts",
+			args{
+				in: "1\n2\n3\n123456\n",
+			},
+			"",
+			true,
+		},
+		{
+			"Invalid/NoInput",
+			args{
+				in: "",
+			},
+			"",
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			got, err := promptToken(strings.NewReader(tt.args.in), out, "default")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("promptToken() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("promptToken() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_readerTokenProvider(t *testing.T) {
+	got, err := readerTokenProvider(strings.NewReader("123456\n654321\n"))()
+	if err != nil || got != "123456" {
+		t.Errorf("readerTokenProvider() = %v, %v, want %v", got, err, "123456")
+	}
+}
+
+func Test_generatedTokenProvider(t *testing.T) {
+	defer func(token, command string) { mfaToken, tokenCommand = token, command }(mfaToken, tokenCommand)
+
+	tests := []struct {
+		name    string
+		token   string
+		command string
+		wantNil bool
+	}{
+		{"Valid/StoredSeed", "", "", false},
+		{"Valid/Stdin", "-", "", true},
+		{"Valid/TokenCommand", "", "ykman oath accounts code", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mfaToken, tokenCommand = tt.token, tt.command
+			if got := generatedTokenProvider(); (got == nil) != tt.wantNil {
+				t.Errorf("generatedTokenProvider() = %v, want nil %v", got != nil, tt.wantNil)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"fmt"
+	"mfa4aws/internal/pkg/aws"
+	"mfa4aws/internal/pkg/totp"
+	"mfa4aws/internal/pkg/vault"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	totpSecretPrefix string = "totp/"
+)
+
+func init() {
+	rootCmd.AddCommand(totpCmd)
+	totpCmd.AddCommand(totpAddCmd, totpRemoveCmd, totpCodeCmd)
+
+	totpCmd.PersistentFlags().StringVarP(&awsProfile, "profile", "p", "default", "AWS Profile name the TOTP seed is used for, $AWS_PROFILE when not given")
+}
+
+var totpCmd = &cobra.Command{
+	Use:   "totp",
+	Short: "Manages virtual MFA seeds used to generate the MFA value instead of --token",
+}
+
+var totpAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Stores a base32 TOTP secret or otpauth:// URI for the profile in the encrypted vault",
+	Run: func(cmd *cobra.Command, args []string) {
+		seed, err := readSecret("TOTP secret or otpauth:// URI: ")
+		if err != nil {
+			exitWithError(os.Stderr, err)
+		}
+
+		key, err := totp.Parse(seed)
+		if err != nil {
+			exitWithError(os.Stderr, err)
+		}
+
+		if err := storeTOTPKey(awsProfile, key); err != nil {
+			exitWithError(os.Stderr, err)
+		}
+
+		code, err := key.Generate(time.Now())
+		if err != nil {
+			exitWithError(os.Stderr, err)
+		}
+
+		fmt.Printf("Stored TOTP seed for profile %s, current code is %s\n", awsProfile, code)
+	},
+}
+
+var totpRemoveCmd = &cobra.Command{
+	Use:   "remove",
+	Short: "Removes the TOTP seed for the profile from the encrypted vault",
+	Run: func(cmd *cobra.Command, args []string) {
+		v, err := vault.Open("")
+		if err != nil {
+			exitWithError(os.Stderr, err)
+		}
+
+		if !v.Has(totpSecretPrefix + awsProfile) {
+			exitWithError(os.Stderr, configError(vault.ErrSecretNotFound))
+		}
+
+		v.Delete(totpSecretPrefix + awsProfile)
+		if err := v.Save(); err != nil {
+			exitWithError(os.Stderr, err)
+		}
+	},
+}
+
+var totpCodeCmd = &cobra.Command{
+	Use:   "code",
+	Short: "Displays the current MFA value generated from the profile's TOTP seed",
+	Run: func(cmd *cobra.Command, args []string) {
+		code, err := storedTOTPTokenProvider(awsProfile)()
+		if err != nil {
+			exitWithError(os.Stderr, err)
+		}
+		if len(code) == 0 {
+			exitWithError(os.Stderr, configError(vault.ErrSecretNotFound))
+		}
+
+		fmt.Println(code)
+	},
+}
+
+//storeTOTPKey stores key for profile in the vault
+func storeTOTPKey(profile string, key *totp.Key) error {
+	v, err := vault.Open("")
+	if err != nil {
+		return err
+	}
+
+	if err := unlockVault(v); err != nil {
+		return err
+	}
+
+	if err := v.Set(totpSecretPrefix+profile, key.URI()); err != nil {
+		return err
+	}
+
+	return v.Save()
+}
+
+//storedTOTPTokenProvider returns a TokenProvider generating the MFA value from the profile's stored TOTP seed
+func storedTOTPTokenProvider(profile string) aws.TokenProvider {
+	return func() (string, error) {
+		v, err := vault.Open("")
+		if err != nil {
+			return "", err
+		}
+
+		if !v.Has(totpSecretPrefix + profile) {
+			return "", nil
+		}
+
+		if err := unlockVault(v); err != nil {
+			return "", err
+		}
+
+		uri, err := v.Get(totpSecretPrefix + profile)
+		if err != nil {
+			return "", err
+		}
+
+		key, err := totp.ParseURI(uri)
+		if err != nil {
+			return "", err
+		}
+
+		return key.Generate(time.Now())
+	}
+}
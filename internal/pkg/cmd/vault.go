@@ -0,0 +1,208 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"mfa4aws/internal/pkg/aws"
+	"mfa4aws/internal/pkg/vault"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	accessKeysSecretPrefix string = "aws/"
+)
+
+func init() {
+	rootCmd.AddCommand(vaultCmd)
+	vaultCmd.AddCommand(vaultAddCmd, vaultListCmd, vaultRemoveCmd, vaultImportCmd)
+
+	vaultCmd.PersistentFlags().StringVarP(&awsProfile, "profile", "p", "default", "AWS Profile name the long term keys are used for, $AWS_PROFILE when not given")
+}
+
+var vaultCmd = &cobra.Command{
+	Use:   "vault",
+	Short: "Manages long term IAM access keys held in the encrypted vault instead of $HOME/.aws/credentials",
+}
+
+var vaultAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Stores long term access keys for the profile in the encrypted vault",
+	Run: func(cmd *cobra.Command, args []string) {
+		accessKeyID, err := readSecret("Access key ID: ")
+		if err != nil {
+			exitWithError(os.Stderr, err)
+		}
+
+		secretAccessKey, err := readSecret("Secret access key: ")
+		if err != nil {
+			exitWithError(os.Stderr, err)
+		}
+
+		if err := storeAccessKeys(awsProfile, &aws.AccessKeys{AccessKeyID: accessKeyID, SecretAccessKey: secretAccessKey}); err != nil {
+			exitWithError(os.Stderr, err)
+		}
+
+		fmt.Printf("Stored access key %s for profile %s\n", accessKeyID, awsProfile)
+	},
+}
+
+var vaultListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the profiles with long term access keys in the encrypted vault",
+	Run: func(cmd *cobra.Command, args []string) {
+		v, err := vault.Open("")
+		if err != nil {
+			exitWithError(os.Stderr, err)
+		}
+
+		for _, name := range v.Names() {
+			if strings.HasPrefix(name, accessKeysSecretPrefix) {
+				fmt.Println(strings.TrimPrefix(name, accessKeysSecretPrefix))
+			}
+		}
+	},
+}
+
+var vaultRemoveCmd = &cobra.Command{
+	Use:   "remove",
+	Short: "Removes the long term access keys for the profile from the encrypted vault",
+	Run: func(cmd *cobra.Command, args []string) {
+		v, err := vault.Open("")
+		if err != nil {
+			exitWithError(os.Stderr, err)
+		}
+
+		if !v.Has(accessKeysSecretPrefix + awsProfile) {
+			exitWithError(os.Stderr, configError(vault.ErrSecretNotFound))
+		}
+
+		v.Delete(accessKeysSecretPrefix + awsProfile)
+		if err := v.Save(); err != nil {
+			exitWithError(os.Stderr, err)
+		}
+	},
+}
+
+var vaultImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Moves the long term access keys for the profile from $HOME/.aws/credentials into the encrypted vault",
+	Run: func(cmd *cobra.Command, args []string) {
+		keys, err := aws.ReadCredentialsFileKeys(credentialsFile, awsProfile)
+		if err != nil {
+			exitWithError(os.Stderr, err)
+		}
+
+		if err := storeAccessKeys(awsProfile, keys); err != nil {
+			exitWithError(os.Stderr, err)
+		}
+
+		if err := aws.RemoveCredentialsFileKeys(credentialsFile, awsProfile); err != nil {
+			exitWithError(os.Stderr, err)
+		}
+
+		fmt.Printf("Moved access key %s for profile %s into the vault\n", keys.AccessKeyID, awsProfile)
+	},
+}
+
+//storeAccessKeys stores keys for profile in the vault
+func storeAccessKeys(profile string, keys *aws.AccessKeys) error {
+	value, err := json.Marshal(keys)
+	if err != nil {
+		return err
+	}
+
+	v, err := vault.Open("")
+	if err != nil {
+		return err
+	}
+
+	if err := unlockVault(v); err != nil {
+		return err
+	}
+
+	if err := v.Set(accessKeysSecretPrefix+profile, string(value)); err != nil {
+		return err
+	}
+
+	return v.Save()
+}
+
+//vaultAccessKeyStore reads long term access keys from the encrypted vault
+type vaultAccessKeyStore struct {
+	vault    *vault.Vault
+	unlocked bool
+}
+
+//HasAccessKeys returns true when the vault holds keys for profile. An unreadable vault holds none
+func (s *vaultAccessKeyStore) HasAccessKeys(profile string) bool {
+	if err := s.open(); err != nil {
+		return false
+	}
+	return s.vault.Has(accessKeysSecretPrefix + profile)
+}
+
+//AccessKeys returns the keys the vault holds for profile
+func (s *vaultAccessKeyStore) AccessKeys(profile string) (*aws.AccessKeys, error) {
+	if err := s.unlock(); err != nil {
+		return nil, err
+	}
+
+	value, err := s.vault.Get(accessKeysSecretPrefix + profile)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := &aws.AccessKeys{}
+	if err := json.Unmarshal([]byte(value), keys); err != nil {
+		return nil, vault.ErrInvalidVaultFile
+	}
+	return keys, nil
+}
+
+//SetAccessKeys stores keys for profile in the vault, replacing any keys it already holds
+func (s *vaultAccessKeyStore) SetAccessKeys(profile string, keys *aws.AccessKeys) error {
+	value, err := json.Marshal(keys)
+	if err != nil {
+		return err
+	}
+
+	if err := s.unlock(); err != nil {
+		return err
+	}
+
+	if err := s.vault.Set(accessKeysSecretPrefix+profile, string(value)); err != nil {
+		return err
+	}
+
+	return s.vault.Save()
+}
+
+func (s *vaultAccessKeyStore) unlock() error {
+	if err := s.open(); err != nil {
+		return err
+	}
+
+	if !s.unlocked {
+		if err := unlockVault(s.vault); err != nil {
+			return err
+		}
+		s.unlocked = true
+	}
+	return nil
+}
+
+func (s *vaultAccessKeyStore) open() error {
+	if s.vault != nil {
+		return nil
+	}
+
+	v, err := vault.Open("")
+	if err != nil {
+		return err
+	}
+	s.vault = v
+	return nil
+}
@@ -0,0 +1,123 @@
+//Package qrcode draws QR code images on the terminal
+package qrcode
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/png"
+	"io"
+	"math"
+)
+
+const (
+	finderModules int = 7
+	minModules    int = 21
+	maxModules    int = 177
+	quietZone     int = 2
+)
+
+var (
+	//ErrUnrecognisedImage is returned when no QR code can be found in an image
+	ErrUnrecognisedImage = errors.New("Image does not contain a QR code")
+)
+
+//Code is a QR code as its grid of modules, true for dark modules
+type Code [][]bool
+
+//DecodePNG returns the Code drawn in a PNG image, such as the one returned for a virtual MFA device
+func DecodePNG(data []byte) (Code, error) {
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return FromImage(img)
+}
+
+//FromImage samples the modules of the QR code drawn in img
+func FromImage(img image.Image) (Code, error) {
+	bounds := img.Bounds()
+	dark := func(x int, y int) bool {
+		r, g, b, _ := img.At(x, y).RGBA()
+		return r+g+b < 3*0x8000
+	}
+
+	minX, minY, maxX, maxY := bounds.Max.X, bounds.Max.Y, bounds.Min.X-1, bounds.Min.Y-1
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if dark(x, y) {
+				minX, minY = minInt(minX, x), minInt(minY, y)
+				maxX, maxY = maxInt(maxX, x), maxInt(maxY, y)
+			}
+		}
+	}
+	if maxX < minX {
+		return nil, ErrUnrecognisedImage
+	}
+
+	run := 0
+	for x := minX; x <= maxX && dark(x, minY); x++ {
+		run++
+	}
+
+	size := float64(run) / float64(finderModules)
+	modules := int(math.Round(float64(maxX-minX+1) / size))
+	if modules < minModules || modules > maxModules || (modules-minModules)%4 != 0 ||
+		int(math.Round(float64(maxY-minY+1)/size)) != modules {
+		return nil, ErrUnrecognisedImage
+	}
+
+	code := make(Code, modules)
+	for row := range code {
+		code[row] = make([]bool, modules)
+		for col := range code[row] {
+			code[row][col] = dark(minX+int((float64(col)+0.5)*size), minY+int((float64(row)+0.5)*size))
+		}
+	}
+	return code, nil
+}
+
+//Write draws the Code on out with half block characters
+func (c Code) Write(out io.Writer, invert bool) error {
+	size := len(c) + 2*quietZone
+	filled := func(row int, col int) bool {
+		row, col = row-quietZone, col-quietZone
+		light := row < 0 || col < 0 || row >= len(c) || col >= len(c) || !c[row][col]
+		return light != invert
+	}
+
+	buf := bytes.NewBuffer(nil)
+	for row := 0; row < size; row += 2 {
+		for col := 0; col < size; col++ {
+			top, bottom := filled(row, col), row+1 < size && filled(row+1, col)
+			switch {
+			case top && bottom:
+				buf.WriteString("█")
+			case top:
+				buf.WriteString("▀")
+			case bottom:
+				buf.WriteString("▄")
+			default:
+				buf.WriteString(" ")
+			}
+		}
+		buf.WriteString("\n")
+	}
+
+	_, err := buf.WriteTo(out)
+	return err
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a int, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,116 @@
+package qrcode
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+//testCode returns a 21 module Code with the three finder patterns and a few data modules
+func testCode() Code {
+	code := make(Code, minModules)
+	for row := range code {
+		code[row] = make([]bool, minModules)
+	}
+
+	finder := func(top int, left int) {
+		for row := 0; row < finderModules; row++ {
+			for col := 0; col < finderModules; col++ {
+				ring := row == 0 || col == 0 || row == finderModules-1 || col == finderModules-1
+				centre := row >= 2 && row <= 4 && col >= 2 && col <= 4
+				code[top+row][left+col] = ring || centre
+			}
+		}
+	}
+	finder(0, 0)
+	finder(0, minModules-finderModules)
+	finder(minModules-finderModules, 0)
+
+	code[10][10], code[12][15], code[20][20] = true, true, true
+	return code
+}
+
+//drawCode draws code with a quiet zone of four modules, each module being scale pixels
+func drawCode(code Code, scale int) image.Image {
+	size := (len(code) + 8) * scale
+	img := image.NewGray(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			row, col := y/scale-4, x/scale-4
+			if row >= 0 && col >= 0 && row < len(code) && col < len(code) && code[row][col] {
+				img.SetGray(x, y, color.Gray{Y: 0})
+			} else {
+				img.SetGray(x, y, color.Gray{Y: 255})
+			}
+		}
+	}
+	return img
+}
+
+func TestDecodePNG(t *testing.T) {
+	want := testCode()
+
+	for _, scale := range []int{1, 4, 7} {
+		buf := bytes.NewBuffer(nil)
+		if err := png.Encode(buf, drawCode(want, scale)); err != nil {
+			t.Fatalf("Encode() error = %v", err)
+		}
+
+		got, err := DecodePNG(buf.Bytes())
+		if err != nil {
+			t.Errorf("DecodePNG() scale %d error = %v", scale, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("DecodePNG() scale %d = %v, want %v", scale, got, want)
+		}
+	}
+}
+
+func TestFromImage(t *testing.T) {
+	tests := []struct {
+		name string
+		img  image.Image
+	}{
+		{"Invalid/Blank", drawCode(Code{}, 4)},
+		{"Invalid/NotSquare", image.NewGray(image.Rect(0, 0, 40, 80))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := FromImage(tt.img); err != ErrUnrecognisedImage {
+				t.Errorf("FromImage() error = %v, want %v", err, ErrUnrecognisedImage)
+			}
+		})
+	}
+}
+
+func TestCodeWrite(t *testing.T) {
+	code := Code{{true, false}, {false, true}}
+
+	tests := []struct {
+		name   string
+		invert bool
+		want   string
+	}{
+		{"Valid/DarkBackground", false, "██████\n██▄▀██\n██████\n"},
+		{"Valid/LightBackground", true, "      \n  ▀▄  \n      \n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := bytes.NewBuffer(nil)
+			if err := code.Write(out, tt.invert); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			if got := out.String(); got != tt.want {
+				t.Errorf("Write() = %q, want %q", got, tt.want)
+			}
+			if lines := strings.Count(out.String(), "\n"); lines != 3 {
+				t.Errorf("Write() lines = %d, want 3", lines)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+const (
+	//ECSCredentialsPath is the path the container credentials are served on
+	ECSCredentialsPath string = "/creds"
+
+	authorizationHeader string = "Authorization"
+
+	tokenBytes = 32
+)
+
+//ecsCredentials is the document served by the ECS container credentials endpoint
+type ecsCredentials struct {
+	AccessKeyID     string `json:"AccessKeyId"`
+	SecretAccessKey string
+	Token           string
+	Expiration      string
+	RoleArn         string
+}
+
+//ecsError is the document served by the ECS container credentials endpoint when no credentials are available
+type ecsError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+//ecsHandler implements the ECS container credentials endpoint
+type ecsHandler struct {
+	source             *Source
+	authorizationToken string
+}
+
+//NewECSHandler returns an http.Handler implementing the ECS container credentials endpoint
+func NewECSHandler(source *Source, authorizationToken string) http.Handler {
+	return &ecsHandler{
+		source:             source,
+		authorizationToken: authorizationToken,
+	}
+}
+
+func (h *ecsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != ECSCredentialsPath {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get(authorizationHeader)), []byte(h.authorizationToken)) != 1 {
+		writeJSON(w, http.StatusUnauthorized, &ecsError{Code: "Unauthorized", Message: "Invalid authorization token"})
+		return
+	}
+
+	creds, err := h.source.Credentials()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, &ecsError{Code: imdsErrorCode, Message: err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, &ecsCredentials{
+		AccessKeyID:     creds.AWSAccessKeyID,
+		SecretAccessKey: creds.AWSSecretAccessKey,
+		Token:           creds.AWSSessionToken,
+		Expiration:      creds.Expiration.UTC().Format(time.RFC3339),
+		RoleArn:         creds.PrincipalARN,
+	})
+}
+
+//NewAuthorizationToken returns a random token for clients of the ECS container credentials endpoint
+func NewAuthorizationToken() (string, error) {
+	return randomToken()
+}
+
+//randomToken returns tokenBytes of random data encoded for use in HTTP headers
+func randomToken() (string, error) {
+	b := make([]byte, tokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+//writeJSON writes document as the indented JSON body of a response with code
+func writeJSON(w http.ResponseWriter, code int, document interface{}) {
+	w.Header().Set(contentTypeHeader, contentTypeJSON)
+	w.WriteHeader(code)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(document)
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/credentials/endpointcreds"
+	"github.com/aws/aws-sdk-go/aws/defaults"
+)
+
+func newTestEndpointProvider(url string, authorizationToken string) *endpointcreds.Provider {
+	config := defaults.Config().WithRegion("us-east-1").WithMaxRetries(0)
+	return endpointcreds.NewProviderClient(*config, defaults.Handlers(), url+ECSCredentialsPath, func(p *endpointcreds.Provider) {
+		p.AuthorizationToken = authorizationToken
+	}).(*endpointcreds.Provider)
+}
+
+func TestECSHandlerEndpointProvider(t *testing.T) {
+	server := httptest.NewServer(NewECSHandler(newTestSource(nil), "blah"))
+	defer server.Close()
+
+	provider := newTestEndpointProvider(server.URL, "blah")
+	value, err := provider.Retrieve()
+	if err != nil {
+		t.Fatalf("Retrieve() error = %v", err)
+	}
+	if value.AccessKeyID != testCreds.AWSAccessKeyID ||
+		value.SecretAccessKey != testCreds.AWSSecretAccessKey ||
+		value.SessionToken != testCreds.AWSSessionToken {
+		t.Errorf("Retrieve() = %v, want %v", value, testCreds)
+	}
+	if provider.IsExpired() {
+		t.Errorf("IsExpired() = true, want false")
+	}
+}
+
+func TestECSHandlerEndpointProviderError(t *testing.T) {
+	tests := []struct {
+		name               string
+		source             *Source
+		authorizationToken string
+	}{
+		{"Invalid/AuthorizationToken", newTestSource(nil), "wrong"},
+		{"Invalid/MissingAuthorizationToken", newTestSource(nil), ""},
+		{"Invalid/SourceError", newTestSource(errors.New("Invalid token code")), "blah"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(NewECSHandler(tt.source, "blah"))
+			defer server.Close()
+
+			if _, err := newTestEndpointProvider(server.URL, tt.authorizationToken).Retrieve(); err == nil {
+				t.Errorf("Retrieve() expected an error")
+			}
+		})
+	}
+}
+
+func TestECSHandler(t *testing.T) {
+	handler := NewECSHandler(newTestSource(nil), "blah")
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		wantCode int
+	}{
+		{"Valid/Credentials", http.MethodGet, ECSCredentialsPath, http.StatusOK},
+		{"Invalid/Path", http.MethodGet, "/", http.StatusNotFound},
+		{"Invalid/Method", http.MethodPost, ECSCredentialsPath, http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+			r.Header.Set(authorizationHeader, "blah")
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Code != tt.wantCode {
+				t.Errorf("ServeHTTP() code = %d, want %d", w.Code, tt.wantCode)
+			}
+		})
+	}
+}
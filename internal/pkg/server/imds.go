@@ -0,0 +1,162 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	imdsTokenPath               string = "/latest/api/token"
+	imdsSecurityCredentialsPath string = "/latest/meta-data/iam/security-credentials/"
+
+	imdsTokenHeader    string = "X-aws-ec2-metadata-token"
+	imdsTokenTTLHeader string = "X-aws-ec2-metadata-token-ttl-seconds"
+	forwardedForHeader string = "X-Forwarded-For"
+
+	imdsMaxTokenTTL   = 6 * time.Hour
+	imdsSuccessCode   = "Success"
+	imdsCredsType     = "AWS-HMAC"
+	imdsErrorCode     = "Failure"
+	contentTypeHeader = "Content-Type"
+	contentTypeJSON   = "application/json"
+	contentTypeText   = "text/plain"
+)
+
+//imdsCredentials is the document served for an IAM role by the EC2 instance metadata service
+type imdsCredentials struct {
+	Code            string
+	Message         string `json:",omitempty"`
+	LastUpdated     string `json:",omitempty"`
+	Type            string `json:",omitempty"`
+	AccessKeyID     string `json:"AccessKeyId,omitempty"`
+	SecretAccessKey string `json:",omitempty"`
+	Token           string `json:",omitempty"`
+	Expiration      string `json:",omitempty"`
+}
+
+//imdsHandler implements the IMDSv2 endpoints serving the Credentials of source
+type imdsHandler struct {
+	source   *Source
+	roleName string
+
+	mu     sync.Mutex
+	tokens map[string]time.Time
+	now    func() time.Time
+}
+
+//NewIMDSHandler returns an http.Handler emulating the EC2 instance metadata service
+func NewIMDSHandler(source *Source, roleName string) http.Handler {
+	return &imdsHandler{
+		source:   source,
+		roleName: roleName,
+		tokens:   map[string]time.Time{},
+		now:      time.Now,
+	}
+}
+
+func (h *imdsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == imdsTokenPath {
+		h.serveToken(w, r)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.validToken(r.Header.Get(imdsTokenHeader)) {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	switch r.URL.Path {
+	case imdsSecurityCredentialsPath, strings.TrimSuffix(imdsSecurityCredentialsPath, "/"):
+		w.Header().Set(contentTypeHeader, contentTypeText)
+		fmt.Fprint(w, h.roleName)
+	case imdsSecurityCredentialsPath + h.roleName:
+		h.serveCredentials(w)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+//serveToken issues a session token for the requested TTL. As with EC2, forwarded requests are refused
+func (h *imdsHandler) serveToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	if len(r.Header.Get(forwardedForHeader)) != 0 {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+
+	ttlSeconds, err := strconv.ParseInt(r.Header.Get(imdsTokenTTLHeader), 10, 64)
+	ttl := time.Duration(ttlSeconds) * time.Second
+	if err != nil || ttl <= 0 || ttl > imdsMaxTokenTTL {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	token, err := h.newToken(ttl)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set(imdsTokenTTLHeader, strconv.FormatInt(ttlSeconds, 10))
+	w.Header().Set(contentTypeHeader, contentTypeText)
+	fmt.Fprint(w, token)
+}
+
+func (h *imdsHandler) serveCredentials(w http.ResponseWriter) {
+	document := &imdsCredentials{Code: imdsSuccessCode}
+
+	creds, err := h.source.Credentials()
+	if err != nil {
+		document.Code = imdsErrorCode
+		document.Message = err.Error()
+	} else {
+		document.LastUpdated = h.now().UTC().Format(time.RFC3339)
+		document.Type = imdsCredsType
+		document.AccessKeyID = creds.AWSAccessKeyID
+		document.SecretAccessKey = creds.AWSSecretAccessKey
+		document.Token = creds.AWSSessionToken
+		document.Expiration = creds.Expiration.UTC().Format(time.RFC3339)
+	}
+
+	writeJSON(w, http.StatusOK, document)
+}
+
+//newToken returns a random session token valid for ttl, discarding any expired tokens
+func (h *imdsHandler) newToken(ttl time.Duration) (string, error) {
+	token, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := h.now()
+	for x, expiration := range h.tokens {
+		if !now.Before(expiration) {
+			delete(h.tokens, x)
+		}
+	}
+	h.tokens[token] = now.Add(ttl)
+
+	return token, nil
+}
+
+func (h *imdsHandler) validToken(token string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	expiration, ok := h.tokens[token]
+	return ok && h.now().Before(expiration)
+}
@@ -0,0 +1,137 @@
+package server
+
+import (
+	"errors"
+	"mfa4aws/internal/pkg/aws"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	sdkaws "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials/ec2rolecreds"
+	"github.com/aws/aws-sdk-go/aws/ec2metadata"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+var testCreds = &aws.Credentials{
+	AWSAccessKeyID:     "AHIAACNB4F5KCDQXSGYW4",
+	AWSSecretAccessKey: "Xoy7ogSQXyTyZI3Oqv8JdAkk1PsbSYzt/vqQ1v+9",
+	AWSSessionToken:    "FQoGZXIvYshgsSJHIOSLKj6nr0FOKIuOP68yKRKvPp3nj9MyaPcvN8PApmWd3yKuTJWf",
+	PrincipalARN:       "arn:aws:iam::162171167783:user/johnsmith",
+}
+
+func newTestSource(err error) *Source {
+	return NewSource(func() (*aws.Credentials, error) {
+		if err != nil {
+			return nil, err
+		}
+		creds := *testCreds
+		creds.Expiration = time.Now().Add(time.Hour).Truncate(time.Second)
+		return &creds, nil
+	}, 5*time.Minute)
+}
+
+func newTestEC2RoleProvider(t *testing.T, url string) *ec2rolecreds.EC2RoleProvider {
+	sess, err := session.NewSession(&sdkaws.Config{Region: sdkaws.String("us-east-1")})
+	if err != nil {
+		t.Fatalf("NewSession() error = %v", err)
+	}
+	return &ec2rolecreds.EC2RoleProvider{
+		Client: ec2metadata.New(sess, &sdkaws.Config{Endpoint: sdkaws.String(url + "/latest")}),
+	}
+}
+
+func TestIMDSHandlerEC2RoleProvider(t *testing.T) {
+	server := httptest.NewServer(NewIMDSHandler(newTestSource(nil), "dev"))
+	defer server.Close()
+
+	provider := newTestEC2RoleProvider(t, server.URL)
+	value, err := provider.Retrieve()
+	if err != nil {
+		t.Fatalf("Retrieve() error = %v", err)
+	}
+	if value.AccessKeyID != testCreds.AWSAccessKeyID ||
+		value.SecretAccessKey != testCreds.AWSSecretAccessKey ||
+		value.SessionToken != testCreds.AWSSessionToken {
+		t.Errorf("Retrieve() = %v, want %v", value, testCreds)
+	}
+	if provider.IsExpired() {
+		t.Errorf("IsExpired() = true, want false")
+	}
+}
+
+func TestIMDSHandlerEC2RoleProviderError(t *testing.T) {
+	server := httptest.NewServer(NewIMDSHandler(newTestSource(errors.New("Invalid token code")), "dev"))
+	defer server.Close()
+
+	if _, err := newTestEC2RoleProvider(t, server.URL).Retrieve(); err == nil {
+		t.Errorf("Retrieve() expected an error")
+	}
+}
+
+func TestIMDSHandler(t *testing.T) {
+	handler := NewIMDSHandler(newTestSource(nil), "dev")
+
+	request := func(method string, path string, header map[string]string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, nil)
+		for k, v := range header {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	tokenResponse := request(http.MethodPut, imdsTokenPath, map[string]string{imdsTokenTTLHeader: "60"})
+	if tokenResponse.Code != http.StatusOK || tokenResponse.Header().Get(imdsTokenTTLHeader) != "60" {
+		t.Fatalf("token request = %d, want %d", tokenResponse.Code, http.StatusOK)
+	}
+	token := tokenResponse.Body.String()
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		header   map[string]string
+		wantCode int
+		wantBody string
+	}{
+		{"Valid/RoleList", http.MethodGet, imdsSecurityCredentialsPath, map[string]string{imdsTokenHeader: token}, http.StatusOK, "dev"},
+		{"Invalid/Token/MissingTTL", http.MethodPut, imdsTokenPath, nil, http.StatusBadRequest, ""},
+		{"Invalid/Token/TTLTooLong", http.MethodPut, imdsTokenPath, map[string]string{imdsTokenTTLHeader: "21601"}, http.StatusBadRequest, ""},
+		{"Invalid/Token/Forwarded", http.MethodPut, imdsTokenPath, map[string]string{imdsTokenTTLHeader: "60", forwardedForHeader: "10.0.0.1"}, http.StatusForbidden, ""},
+		{"Invalid/Token/Method", http.MethodGet, imdsTokenPath, nil, http.StatusMethodNotAllowed, ""},
+		{"Invalid/MissingToken", http.MethodGet, imdsSecurityCredentialsPath + "dev", nil, http.StatusUnauthorized, ""},
+		{"Invalid/UnknownToken", http.MethodGet, imdsSecurityCredentialsPath + "dev", map[string]string{imdsTokenHeader: "blah"}, http.StatusUnauthorized, ""},
+		{"Invalid/UnknownRole", http.MethodGet, imdsSecurityCredentialsPath + "prod", map[string]string{imdsTokenHeader: token}, http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := request(tt.method, tt.path, tt.header)
+			if w.Code != tt.wantCode {
+				t.Errorf("ServeHTTP() code = %d, want %d", w.Code, tt.wantCode)
+			}
+			if len(tt.wantBody) != 0 && w.Body.String() != tt.wantBody {
+				t.Errorf("ServeHTTP() body = %v, want %v", w.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestIMDSHandlerExpiredToken(t *testing.T) {
+	handler := NewIMDSHandler(newTestSource(nil), "dev").(*imdsHandler)
+
+	token, err := handler.newToken(time.Second)
+	if err != nil {
+		t.Fatalf("newToken() error = %v", err)
+	}
+	if !handler.validToken(token) {
+		t.Errorf("validToken() = false, want true")
+	}
+
+	handler.now = func() time.Time { return time.Now().Add(time.Second) }
+	if handler.validToken(token) {
+		t.Errorf("validToken() = true for an expired token, want false")
+	}
+}
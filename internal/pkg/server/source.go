@@ -0,0 +1,46 @@
+package server
+
+import (
+	"mfa4aws/internal/pkg/aws"
+	"sync"
+	"time"
+)
+
+//GenerateFunc returns new Credentials, prompting for the MFA value when a new session is required
+type GenerateFunc func() (*aws.Credentials, error)
+
+//Source holds the Credentials served to clients in memory, generating new ones once they are about to expire
+type Source struct {
+	mu              sync.Mutex
+	generate        GenerateFunc
+	minimumLifetime time.Duration
+	creds           *aws.Credentials
+	now             func() time.Time
+}
+
+//NewSource returns a Source which calls generate whenever its Credentials have less than minimumLifetime remaining
+func NewSource(generate GenerateFunc, minimumLifetime time.Duration) *Source {
+	return &Source{
+		generate:        generate,
+		minimumLifetime: minimumLifetime,
+		now:             time.Now,
+	}
+}
+
+//Credentials returns the current Credentials, generating new ones when they are missing or about to expire
+func (s *Source) Credentials() (*aws.Credentials, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.creds != nil && s.creds.Expiration.Sub(s.now()) > s.minimumLifetime {
+		return s.creds, nil
+	}
+
+	creds, err := s.generate()
+	if err != nil {
+		return nil, err
+	}
+	s.creds = creds
+
+	return creds, nil
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"errors"
+	"mfa4aws/internal/pkg/aws"
+	"testing"
+	"time"
+)
+
+func TestSourceCredentials(t *testing.T) {
+	now := time.Date(2020, 1, 2, 13, 4, 5, 0, time.UTC)
+
+	var generated int
+	source := NewSource(func() (*aws.Credentials, error) {
+		generated++
+		return &aws.Credentials{
+			AWSAccessKeyID: "AHIAACNB4F5KCDQXSGYW4",
+			Expiration:     now.Add(time.Hour),
+		}, nil
+	}, 5*time.Minute)
+	source.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if _, err := source.Credentials(); err != nil {
+			t.Fatalf("Credentials() error = %v", err)
+		}
+	}
+	if generated != 1 {
+		t.Errorf("Credentials() generated %d sessions, want 1", generated)
+	}
+
+	now = now.Add(56 * time.Minute)
+	if _, err := source.Credentials(); err != nil {
+		t.Fatalf("Credentials() error = %v", err)
+	}
+	if generated != 2 {
+		t.Errorf("Credentials() generated %d sessions once about to expire, want 2", generated)
+	}
+}
+
+func TestSourceCredentialsError(t *testing.T) {
+	source := NewSource(func() (*aws.Credentials, error) {
+		return nil, errors.New("blah")
+	}, 5*time.Minute)
+
+	if _, err := source.Credentials(); err == nil {
+		t.Errorf("Credentials() expected an error")
+	}
+}
@@ -0,0 +1,217 @@
+package shell
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+const (
+	//DialectPOSIX is used by sh, bash, zsh and ksh
+	DialectPOSIX string = "posix"
+	//DialectFish is used by fish
+	DialectFish string = "fish"
+	//DialectPowerShell is used by PowerShell and pwsh
+	DialectPowerShell string = "powershell"
+	//DialectCmd is used by cmd.exe
+	DialectCmd string = "cmd"
+	//DialectTcsh is used by tcsh and csh
+	DialectTcsh string = "tcsh"
+	//DialectNushell is used by nushell
+	DialectNushell string = "nushell"
+)
+
+var (
+	posixSafeValueRegex = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]*$`)
+
+	dialects = map[string]Dialect{
+		DialectPOSIX:      posixDialect{},
+		DialectFish:       fishDialect{},
+		DialectPowerShell: powerShellDialect{},
+		DialectCmd:        cmdDialect{},
+		DialectTcsh:       tcshDialect{},
+		DialectNushell:    nushellDialect{},
+	}
+
+	dialectAliases = map[string]string{
+		"sh":      DialectPOSIX,
+		"bash":    DialectPOSIX,
+		"zsh":     DialectPOSIX,
+		"ksh":     DialectPOSIX,
+		"dash":    DialectPOSIX,
+		"pwsh":    DialectPowerShell,
+		"cmd.exe": DialectCmd,
+		"csh":     DialectTcsh,
+		"nu":      DialectNushell,
+	}
+)
+
+//EnvVar represents an environment variable name and value
+type EnvVar struct {
+	Name  string
+	Value string
+}
+
+//Dialect formats environment variables as statements for a particular shell
+type Dialect interface {
+	//Export returns the statements which set vars in the shell, or an error when a value cannot be set in it
+	Export(vars []EnvVar) ([]string, error)
+
+	//Unset returns the statements which remove the variables names from the shell
+	Unset(names []string) []string
+}
+
+//DialectNames returns the sorted names of the supported dialects
+func DialectNames() []string {
+	names := make([]string, 0, len(dialects))
+	for name := range dialects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+//LookupDialect returns the Dialect for a dialect or shell name such as bash, fish or pwsh
+func LookupDialect(name string) (Dialect, error) {
+	name = strings.ToLower(name)
+	if alias, ok := dialectAliases[name]; ok {
+		name = alias
+	}
+
+	dialect, ok := dialects[name]
+	if !ok {
+		return nil, fmt.Errorf("Unsupported shell %s, must be one of %s", name, strings.Join(DialectNames(), ", "))
+	}
+	return dialect, nil
+}
+
+//DetectDialect returns the Dialect for the shell at shellPath, usually $SHELL
+func DetectDialect(shellPath string) Dialect {
+	if len(shellPath) == 0 {
+		if runtime.GOOS == "windows" {
+			return dialects[DialectPowerShell]
+		}
+		return dialects[DialectPOSIX]
+	}
+
+	name := strings.TrimSuffix(strings.ToLower(filepath.Base(shellPath)), ".exe")
+
+	dialect, err := LookupDialect(name)
+	if err != nil {
+		return dialects[DialectPOSIX]
+	}
+	return dialect
+}
+
+type posixDialect struct{}
+
+func (posixDialect) Export(vars []EnvVar) (statements []string, err error) {
+	for _, x := range vars {
+		statements = append(statements, fmt.Sprintf("%s %s=%s", bashExport, x.Name, posixQuote(x.Value)))
+	}
+	return statements, nil
+}
+
+func (posixDialect) Unset(names []string) (statements []string) {
+	for _, x := range names {
+		statements = append(statements, fmt.Sprintf("unset %s", x))
+	}
+	return statements
+}
+
+//posixQuote single quotes value unless it only contains characters which need no quoting
+func posixQuote(value string) string {
+	if posixSafeValueRegex.MatchString(value) {
+		return value
+	}
+	return "'" + strings.Replace(value, "'", `'\''`, -1) + "'"
+}
+
+type fishDialect struct{}
+
+func (fishDialect) Export(vars []EnvVar) (statements []string, err error) {
+	replacer := strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+	for _, x := range vars {
+		statements = append(statements, fmt.Sprintf("set -gx %s '%s';", x.Name, replacer.Replace(x.Value)))
+	}
+	return statements, nil
+}
+
+func (fishDialect) Unset(names []string) (statements []string) {
+	for _, x := range names {
+		statements = append(statements, fmt.Sprintf("set -e %s;", x))
+	}
+	return statements
+}
+
+type powerShellDialect struct{}
+
+func (powerShellDialect) Export(vars []EnvVar) (statements []string, err error) {
+	for _, x := range vars {
+		statements = append(statements, fmt.Sprintf("$Env:%s = '%s'", x.Name, strings.Replace(x.Value, "'", "''", -1)))
+	}
+	return statements, nil
+}
+
+func (powerShellDialect) Unset(names []string) (statements []string) {
+	for _, x := range names {
+		statements = append(statements, fmt.Sprintf("Remove-Item Env:%s -ErrorAction SilentlyContinue", x))
+	}
+	return statements
+}
+
+type cmdDialect struct{}
+
+func (cmdDialect) Export(vars []EnvVar) (statements []string, err error) {
+	for _, x := range vars {
+		//cmd.exe has no escape for " inside a quoted set, and expands % differently at the prompt and in batch files
+		if strings.ContainsAny(x.Value, `"%`) {
+			return nil, fmt.Errorf("Value of %s holds \" or %%, which cannot be set in cmd.exe, use the powershell shell instead", x.Name)
+		}
+		statements = append(statements, fmt.Sprintf(`set "%s=%s"`, x.Name, x.Value))
+	}
+	return statements, nil
+}
+
+func (cmdDialect) Unset(names []string) (statements []string) {
+	for _, x := range names {
+		statements = append(statements, fmt.Sprintf(`set "%s="`, x))
+	}
+	return statements
+}
+
+type tcshDialect struct{}
+
+func (tcshDialect) Export(vars []EnvVar) (statements []string, err error) {
+	replacer := strings.NewReplacer(`'`, `'\''`, `!`, `\!`)
+	for _, x := range vars {
+		statements = append(statements, fmt.Sprintf("setenv %s '%s';", x.Name, replacer.Replace(x.Value)))
+	}
+	return statements, nil
+}
+
+func (tcshDialect) Unset(names []string) (statements []string) {
+	for _, x := range names {
+		statements = append(statements, fmt.Sprintf("unsetenv %s;", x))
+	}
+	return statements
+}
+
+type nushellDialect struct{}
+
+func (nushellDialect) Export(vars []EnvVar) ([]string, error) {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+	fields := make([]string, 0, len(vars))
+	for _, x := range vars {
+		fields = append(fields, fmt.Sprintf(`%s: "%s"`, x.Name, replacer.Replace(x.Value)))
+	}
+	return []string{fmt.Sprintf("load-env { %s }", strings.Join(fields, ", "))}, nil
+}
+
+func (nushellDialect) Unset(names []string) []string {
+	return []string{fmt.Sprintf("hide-env -i %s", strings.Join(names, " "))}
+}
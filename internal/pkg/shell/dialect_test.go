@@ -0,0 +1,273 @@
+package shell
+
+import (
+	"mfa4aws/internal/pkg/aws"
+	"reflect"
+	"testing"
+)
+
+func TestBuildDialectEnvVars(t *testing.T) {
+	creds := &aws.Credentials{
+		AWSAccessKeyID:     "AHIAACNB4F5KCDQXSGYW4",
+		AWSSecretAccessKey: "Xoy7ogSQXyTyZI3Oqv8JdAkk1PsbSYzt/vqQ1v+9",
+		AWSSessionToken:    "FQoGZXIvYshgsSJHIOSLKj6nr0FOKIuOP68yKRKvPp3nj9MyaPcvN8PApmWd3yKuTJWf",
+		AWSSecurityToken:   "FQoGZXIvYshgsSJHIOSLKj6nr0FOKIuOP68yKRKvPp3nj9MyaPcvN8PApmWd3yKuTJWf",
+		PrincipalARN:       "arn:aws:iam::162171167783:user/john's $(smith)!",
+	}
+
+	type args struct {
+		dialect string
+		creds   *aws.Credentials
+	}
+	tests := []struct {
+		name        string
+		args        args
+		wantEnvVars []string
+		wantErr     bool
+	}{
+		{
+			"Valid/POSIX",
+			args{
+				dialect: DialectPOSIX,
+				creds:   creds,
+			},
+			[]string{
+				"export AWS_ACCESS_KEY_ID=AHIAACNB4F5KCDQXSGYW4",
+				"export AWS_SECRET_ACCESS_KEY=Xoy7ogSQXyTyZI3Oqv8JdAkk1PsbSYzt/vqQ1v+9",
+				"export AWS_SESSION_TOKEN=FQoGZXIvYshgsSJHIOSLKj6nr0FOKIuOP68yKRKvPp3nj9MyaPcvN8PApmWd3yKuTJWf",
+				"export AWS_SECURITY_TOKEN=FQoGZXIvYshgsSJHIOSLKj6nr0FOKIuOP68yKRKvPp3nj9MyaPcvN8PApmWd3yKuTJWf",
+				`export X_PRINCIPAL_ARN='arn:aws:iam::162171167783:user/john'\''s $(smith)!'`,
+			},
+			false,
+		},
+		{
+			"Valid/Fish",
+			args{
+				dialect: DialectFish,
+				creds:   creds,
+			},
+			[]string{
+				"set -gx AWS_ACCESS_KEY_ID 'AHIAACNB4F5KCDQXSGYW4';",
+				"set -gx AWS_SECRET_ACCESS_KEY 'Xoy7ogSQXyTyZI3Oqv8JdAkk1PsbSYzt/vqQ1v+9';",
+				"set -gx AWS_SESSION_TOKEN 'FQoGZXIvYshgsSJHIOSLKj6nr0FOKIuOP68yKRKvPp3nj9MyaPcvN8PApmWd3yKuTJWf';",
+				"set -gx AWS_SECURITY_TOKEN 'FQoGZXIvYshgsSJHIOSLKj6nr0FOKIuOP68yKRKvPp3nj9MyaPcvN8PApmWd3yKuTJWf';",
+				`set -gx X_PRINCIPAL_ARN 'arn:aws:iam::162171167783:user/john\'s $(smith)!';`,
+			},
+			false,
+		},
+		{
+			"Valid/PowerShell",
+			args{
+				dialect: DialectPowerShell,
+				creds:   creds,
+			},
+			[]string{
+				"$Env:AWS_ACCESS_KEY_ID = 'AHIAACNB4F5KCDQXSGYW4'",
+				"$Env:AWS_SECRET_ACCESS_KEY = 'Xoy7ogSQXyTyZI3Oqv8JdAkk1PsbSYzt/vqQ1v+9'",
+				"$Env:AWS_SESSION_TOKEN = 'FQoGZXIvYshgsSJHIOSLKj6nr0FOKIuOP68yKRKvPp3nj9MyaPcvN8PApmWd3yKuTJWf'",
+				"$Env:AWS_SECURITY_TOKEN = 'FQoGZXIvYshgsSJHIOSLKj6nr0FOKIuOP68yKRKvPp3nj9MyaPcvN8PApmWd3yKuTJWf'",
+				"$Env:X_PRINCIPAL_ARN = 'arn:aws:iam::162171167783:user/john''s $(smith)!'",
+			},
+			false,
+		},
+		{
+			"Valid/Cmd",
+			args{
+				dialect: DialectCmd,
+				creds:   creds,
+			},
+			[]string{
+				`set "AWS_ACCESS_KEY_ID=AHIAACNB4F5KCDQXSGYW4"`,
+				`set "AWS_SECRET_ACCESS_KEY=Xoy7ogSQXyTyZI3Oqv8JdAkk1PsbSYzt/vqQ1v+9"`,
+				`set "AWS_SESSION_TOKEN=FQoGZXIvYshgsSJHIOSLKj6nr0FOKIuOP68yKRKvPp3nj9MyaPcvN8PApmWd3yKuTJWf"`,
+				`set "AWS_SECURITY_TOKEN=FQoGZXIvYshgsSJHIOSLKj6nr0FOKIuOP68yKRKvPp3nj9MyaPcvN8PApmWd3yKuTJWf"`,
+				`set "X_PRINCIPAL_ARN=arn:aws:iam::162171167783:user/john's $(smith)!"`,
+			},
+			false,
+		},
+		{
+			"Valid/Tcsh",
+			args{
+				dialect: DialectTcsh,
+				creds:   creds,
+			},
+			[]string{
+				"setenv AWS_ACCESS_KEY_ID 'AHIAACNB4F5KCDQXSGYW4';",
+				"setenv AWS_SECRET_ACCESS_KEY 'Xoy7ogSQXyTyZI3Oqv8JdAkk1PsbSYzt/vqQ1v+9';",
+				"setenv AWS_SESSION_TOKEN 'FQoGZXIvYshgsSJHIOSLKj6nr0FOKIuOP68yKRKvPp3nj9MyaPcvN8PApmWd3yKuTJWf';",
+				"setenv AWS_SECURITY_TOKEN 'FQoGZXIvYshgsSJHIOSLKj6nr0FOKIuOP68yKRKvPp3nj9MyaPcvN8PApmWd3yKuTJWf';",
+				`setenv X_PRINCIPAL_ARN 'arn:aws:iam::162171167783:user/john'\''s $(smith)\!';`,
+			},
+			false,
+		},
+		{
+			"Valid/Nushell",
+			args{
+				dialect: DialectNushell,
+				creds:   creds,
+			},
+			[]string{
+				`load-env { AWS_ACCESS_KEY_ID: "AHIAACNB4F5KCDQXSGYW4", AWS_SECRET_ACCESS_KEY: "Xoy7ogSQXyTyZI3Oqv8JdAkk1PsbSYzt/vqQ1v+9", AWS_SESSION_TOKEN: "FQoGZXIvYshgsSJHIOSLKj6nr0FOKIuOP68yKRKvPp3nj9MyaPcvN8PApmWd3yKuTJWf", AWS_SECURITY_TOKEN: "FQoGZXIvYshgsSJHIOSLKj6nr0FOKIuOP68yKRKvPp3nj9MyaPcvN8PApmWd3yKuTJWf", X_PRINCIPAL_ARN: "arn:aws:iam::162171167783:user/john's $(smith)!" }`,
+			},
+			false,
+		},
+		{
+			"Valid/PowerShellEmptyCreds",
+			args{
+				dialect: DialectPowerShell,
+				creds:   &aws.Credentials{},
+			},
+			[]string{"$Env:AWS_ACCESS_KEY_ID = ''", "$Env:AWS_SECRET_ACCESS_KEY = ''", "$Env:AWS_SESSION_TOKEN = ''", "$Env:AWS_SECURITY_TOKEN = ''", "$Env:X_PRINCIPAL_ARN = ''"},
+			false,
+		},
+		{
+			"Invalid/CmdQuote",
+			args{
+				dialect: DialectCmd,
+				creds:   &aws.Credentials{PrincipalARN: `arn:aws:iam::162171167783:user/"smith" & calc`},
+			},
+			nil,
+			true,
+		},
+		{
+			"Invalid/CmdPercent",
+			args{
+				dialect: DialectCmd,
+				creds:   &aws.Credentials{PrincipalARN: "arn:aws:iam::162171167783:user/%PATH%"},
+			},
+			nil,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dialect, err := LookupDialect(tt.args.dialect)
+			if err != nil {
+				t.Fatalf("LookupDialect() error = %v", err)
+			}
+			gotEnvVars, err := BuildDialectEnvVars(dialect, tt.args.creds)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("BuildDialectEnvVars() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(gotEnvVars, tt.wantEnvVars) {
+				t.Errorf("BuildDialectEnvVars() = %v, want %v", gotEnvVars, tt.wantEnvVars)
+			}
+		})
+	}
+}
+
+func TestBuildDialectUnsetVars(t *testing.T) {
+	tests := []struct {
+		name        string
+		dialect     string
+		wantEnvVars []string
+	}{
+		{
+			"Valid/POSIX",
+			DialectPOSIX,
+			[]string{"unset AWS_ACCESS_KEY_ID", "unset AWS_SECRET_ACCESS_KEY", "unset AWS_SESSION_TOKEN", "unset AWS_SECURITY_TOKEN", "unset X_PRINCIPAL_ARN"},
+		},
+		{
+			"Valid/Fish",
+			DialectFish,
+			[]string{"set -e AWS_ACCESS_KEY_ID;", "set -e AWS_SECRET_ACCESS_KEY;", "set -e AWS_SESSION_TOKEN;", "set -e AWS_SECURITY_TOKEN;", "set -e X_PRINCIPAL_ARN;"},
+		},
+		{
+			"Valid/PowerShell",
+			DialectPowerShell,
+			[]string{
+				"Remove-Item Env:AWS_ACCESS_KEY_ID -ErrorAction SilentlyContinue",
+				"Remove-Item Env:AWS_SECRET_ACCESS_KEY -ErrorAction SilentlyContinue",
+				"Remove-Item Env:AWS_SESSION_TOKEN -ErrorAction SilentlyContinue",
+				"Remove-Item Env:AWS_SECURITY_TOKEN -ErrorAction SilentlyContinue",
+				"Remove-Item Env:X_PRINCIPAL_ARN -ErrorAction SilentlyContinue",
+			},
+		},
+		{
+			"Valid/Cmd",
+			DialectCmd,
+			[]string{`set "AWS_ACCESS_KEY_ID="`, `set "AWS_SECRET_ACCESS_KEY="`, `set "AWS_SESSION_TOKEN="`, `set "AWS_SECURITY_TOKEN="`, `set "X_PRINCIPAL_ARN="`},
+		},
+		{
+			"Valid/Tcsh",
+			DialectTcsh,
+			[]string{"unsetenv AWS_ACCESS_KEY_ID;", "unsetenv AWS_SECRET_ACCESS_KEY;", "unsetenv AWS_SESSION_TOKEN;", "unsetenv AWS_SECURITY_TOKEN;", "unsetenv X_PRINCIPAL_ARN;"},
+		},
+		{
+			"Valid/Nushell",
+			DialectNushell,
+			[]string{"hide-env -i AWS_ACCESS_KEY_ID AWS_SECRET_ACCESS_KEY AWS_SESSION_TOKEN AWS_SECURITY_TOKEN X_PRINCIPAL_ARN"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dialect, err := LookupDialect(tt.dialect)
+			if err != nil {
+				t.Fatalf("LookupDialect() error = %v", err)
+			}
+			if gotEnvVars := BuildDialectUnsetVars(dialect); !reflect.DeepEqual(gotEnvVars, tt.wantEnvVars) {
+				t.Errorf("BuildDialectUnsetVars() = %v, want %v", gotEnvVars, tt.wantEnvVars)
+			}
+		})
+	}
+}
+
+func TestLookupDialect(t *testing.T) {
+	type args struct {
+		name string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    Dialect
+		wantErr bool
+	}{
+		{"Valid/Bash", args{"bash"}, posixDialect{}, false},
+		{"Valid/Zsh", args{"zsh"}, posixDialect{}, false},
+		{"Valid/Fish", args{"fish"}, fishDialect{}, false},
+		{"Valid/Pwsh", args{"pwsh"}, powerShellDialect{}, false},
+		{"Valid/PowerShell", args{"PowerShell"}, powerShellDialect{}, false},
+		{"Valid/Cmd", args{"cmd"}, cmdDialect{}, false},
+		{"Valid/Csh", args{"csh"}, tcshDialect{}, false},
+		{"Valid/Nu", args{"nu"}, nushellDialect{}, false},
+		{"Invalid/Unknown", args{"blah"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LookupDialect(tt.args.name)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("LookupDialect() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LookupDialect() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectDialect(t *testing.T) {
+	type args struct {
+		shellPath string
+	}
+	tests := []struct {
+		name string
+		args args
+		want Dialect
+	}{
+		{"Valid/Bash", args{"/bin/bash"}, posixDialect{}},
+		{"Valid/Fish", args{"/usr/local/bin/fish"}, fishDialect{}},
+		{"Valid/Tcsh", args{"/bin/tcsh"}, tcshDialect{}},
+		{"Valid/Nushell", args{"/opt/homebrew/bin/nu"}, nushellDialect{}},
+		{"Valid/PwshExe", args{"pwsh.exe"}, powerShellDialect{}},
+		{"Valid/CmdExe", args{"cmd.exe"}, cmdDialect{}},
+		{"Valid/UnknownShell", args{"/bin/blah"}, posixDialect{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectDialect(tt.args.shellPath); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DetectDialect() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
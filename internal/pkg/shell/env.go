@@ -3,27 +3,144 @@ package shell
 import (
 	"fmt"
 	"mfa4aws/internal/pkg/aws"
+	"strings"
 )
 
 const (
-	envNameAWSAccessKey     string = "AWS_ACCESS_KEY_ID"
-	envNameAWSSecretKey     string = "AWS_SECRET_ACCESS_KEY"
-	envNameAWSSessionToken  string = "AWS_SESSION_TOKEN"
-	envNameAWSSecurityToken string = "AWS_SECURITY_TOKEN"
-	envNameXPrincipalARN    string = "X_PRINCIPAL_ARN"
+	envNameAWSAccessKey      string = "AWS_ACCESS_KEY_ID"
+	envNameAWSSecretKey      string = "AWS_SECRET_ACCESS_KEY"
+	envNameAWSSessionToken   string = "AWS_SESSION_TOKEN"
+	envNameAWSSecurityToken  string = "AWS_SECURITY_TOKEN"
+	envNameXPrincipalARN     string = "X_PRINCIPAL_ARN"
+	envNameAWSProfile        string = "AWS_PROFILE"
+	envNameAWSDefaultProfile string = "AWS_DEFAULT_PROFILE"
+
+	//EnvNameContainerCredentialsFullURI is the endpoint SDKs request container credentials from
+	EnvNameContainerCredentialsFullURI string = "AWS_CONTAINER_CREDENTIALS_FULL_URI"
+	//EnvNameContainerAuthorizationToken is the Authorization header SDKs send to the container credentials endpoint
+	EnvNameContainerAuthorizationToken string = "AWS_CONTAINER_AUTHORIZATION_TOKEN"
 
 	bashExport string = "export"
 )
 
+//credentialsEnvVars returns the environment variables set from the Credentials
+func credentialsEnvVars(creds *aws.Credentials) []EnvVar {
+	return []EnvVar{
+		{envNameAWSAccessKey, creds.AWSAccessKeyID},
+		{envNameAWSSecretKey, creds.AWSSecretAccessKey},
+		{envNameAWSSessionToken, creds.AWSSessionToken},
+		{envNameAWSSecurityToken, creds.AWSSecurityToken},
+		{envNameXPrincipalARN, creds.PrincipalARN},
+	}
+}
+
 //BuildEnvVars - constructs a string array from the Credentials
 func BuildEnvVars(creds *aws.Credentials) (envVars []string) {
-	envVars = append(envVars,
-		fmt.Sprintf("%s %s=%s", bashExport, envNameAWSAccessKey, creds.AWSAccessKeyID),
-		fmt.Sprintf("%s %s=%s", bashExport, envNameAWSSecretKey, creds.AWSSecretAccessKey),
-		fmt.Sprintf("%s %s=%s", bashExport, envNameAWSSessionToken, creds.AWSSessionToken),
-		fmt.Sprintf("%s %s=%s", bashExport, envNameAWSSecurityToken, creds.AWSSecurityToken),
-		fmt.Sprintf("%s %s=%s", bashExport, envNameXPrincipalARN, creds.PrincipalARN),
-	)
+	envVars, _ = BuildDialectEnvVars(posixDialect{}, creds)
+	return envVars
+}
+
+//BuildDialectEnvVars - constructs a string array from the Credentials for the shell Dialect
+func BuildDialectEnvVars(dialect Dialect, creds *aws.Credentials) (envVars []string, err error) {
+	return dialect.Export(credentialsEnvVars(creds))
+}
+
+//BuildDialectUnsetVars - constructs a string array removing the Credentials variables for the shell Dialect
+func BuildDialectUnsetVars(dialect Dialect) (envVars []string) {
+	vars := credentialsEnvVars(&aws.Credentials{})
+	names := make([]string, 0, len(vars))
+	for _, x := range vars {
+		names = append(names, x.Name)
+	}
+	return dialect.Unset(names)
+}
+
+//BuildExecEnv - removes any AWS credential or profile variables from environ and appends the Credentials
+func BuildExecEnv(environ []string, creds *aws.Credentials) (envVars []string) {
+	for _, x := range environ {
+		if !isCredentialVar(x) {
+			envVars = append(envVars, x)
+		}
+	}
+
+	for _, x := range credentialsEnvVars(creds) {
+		envVars = append(envVars, fmt.Sprintf("%s=%s", x.Name, x.Value))
+	}
 
 	return envVars
 }
+
+//ContainerEnvVars returns the environment variables pointing SDKs at the container credentials endpoint uri
+func ContainerEnvVars(uri string, authorizationToken string) []EnvVar {
+	return []EnvVar{
+		{EnvNameContainerCredentialsFullURI, uri},
+		{EnvNameContainerAuthorizationToken, authorizationToken},
+	}
+}
+
+//BuildContainerEnv - removes any AWS credential variables from environ and points SDKs at uri
+func BuildContainerEnv(environ []string, uri string, authorizationToken string) (envVars []string) {
+	for _, x := range environ {
+		if !isCredentialVar(x) {
+			envVars = append(envVars, x)
+		}
+	}
+
+	for _, x := range ContainerEnvVars(uri, authorizationToken) {
+		envVars = append(envVars, fmt.Sprintf("%s=%s", x.Name, x.Value))
+	}
+
+	return envVars
+}
+
+//EnvCredentials returns the Credentials set in environ, or nil when it holds no access keys
+func EnvCredentials(environ []string) *aws.Credentials {
+	vars := envMap(environ)
+	if len(vars[envNameAWSAccessKey]) == 0 || len(vars[envNameAWSSecretKey]) == 0 {
+		return nil
+	}
+
+	creds := &aws.Credentials{
+		AWSAccessKeyID:     vars[envNameAWSAccessKey],
+		AWSSecretAccessKey: vars[envNameAWSSecretKey],
+		AWSSessionToken:    vars[envNameAWSSessionToken],
+		AWSSecurityToken:   vars[envNameAWSSecurityToken],
+		PrincipalARN:       vars[envNameXPrincipalARN],
+	}
+	if len(creds.AWSSessionToken) == 0 {
+		creds.AWSSessionToken = creds.AWSSecurityToken
+	}
+	return creds
+}
+
+//EnvProfile returns the profile named by AWS_PROFILE, or AWS_DEFAULT_PROFILE, in environ
+func EnvProfile(environ []string) string {
+	vars := envMap(environ)
+	if profile := vars[envNameAWSProfile]; len(profile) != 0 {
+		return profile
+	}
+	return vars[envNameAWSDefaultProfile]
+}
+
+func envMap(environ []string) map[string]string {
+	vars := map[string]string{}
+	for _, x := range environ {
+		if pair := strings.SplitN(x, "=", 2); len(pair) == 2 {
+			vars[pair[0]] = pair[1]
+		}
+	}
+	return vars
+}
+
+func isCredentialVar(envVar string) bool {
+	name := strings.SplitN(envVar, "=", 2)[0]
+
+	switch name {
+	case envNameAWSAccessKey, envNameAWSSecretKey, envNameAWSSessionToken, envNameAWSSecurityToken,
+		envNameXPrincipalARN, envNameAWSProfile, envNameAWSDefaultProfile,
+		EnvNameContainerCredentialsFullURI, EnvNameContainerAuthorizationToken:
+		return true
+	}
+
+	return false
+}
@@ -45,3 +45,95 @@ func TestBuildEnvVars(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildExecEnv(t *testing.T) {
+
+	type args struct {
+		environ []string
+		creds   *aws.Credentials
+	}
+	tests := []struct {
+		name        string
+		args        args
+		wantEnvVars []string
+	}{
+		{
+			"Invalid/EmptyCreds",
+			args{
+				environ: nil,
+				creds:   &aws.Credentials{},
+			},
+			[]string{"AWS_ACCESS_KEY_ID=", "AWS_SECRET_ACCESS_KEY=", "AWS_SESSION_TOKEN=", "AWS_SECURITY_TOKEN=", "X_PRINCIPAL_ARN="},
+		},
+		{
+			"Valid/RemovesExistingCredentials",
+			args{
+				environ: []string{"HOME=/home/johnsmith", "AWS_ACCESS_KEY_ID=AKIAOLD", "AWS_PROFILE=default", "AWS_DEFAULT_PROFILE=default", "AWS_REGION=us-east-1"},
+				creds: &aws.Credentials{
+					AWSAccessKeyID:     "AHIAACNB4F5KCDQXSGYW4",
+					AWSSecretAccessKey: "Xoy7ogSQXyTyZI3Oqv8JdAkk1PsbSYzt/vqQ1v+9",
+					AWSSessionToken:    "FQoGZXIvYshgsSJHIOSLKj6nr0FOKIuOP68yKRKvPp3nj9MyaPcvN8PApmWd3yKuTJWf",
+					AWSSecurityToken:   "FQoGZXIvYshgsSJHIOSLKj6nr0FOKIuOP68yKRKvPp3nj9MyaPcvN8PApmWd3yKuTJWf",
+					PrincipalARN:       "162171167783:user/johnsmith",
+				},
+			},
+			[]string{"HOME=/home/johnsmith", "AWS_REGION=us-east-1", "AWS_ACCESS_KEY_ID=AHIAACNB4F5KCDQXSGYW4", "AWS_SECRET_ACCESS_KEY=Xoy7ogSQXyTyZI3Oqv8JdAkk1PsbSYzt/vqQ1v+9", "AWS_SESSION_TOKEN=FQoGZXIvYshgsSJHIOSLKj6nr0FOKIuOP68yKRKvPp3nj9MyaPcvN8PApmWd3yKuTJWf", "AWS_SECURITY_TOKEN=FQoGZXIvYshgsSJHIOSLKj6nr0FOKIuOP68yKRKvPp3nj9MyaPcvN8PApmWd3yKuTJWf", "X_PRINCIPAL_ARN=162171167783:user/johnsmith"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if gotEnvVars := BuildExecEnv(tt.args.environ, tt.args.creds); !reflect.DeepEqual(gotEnvVars, tt.wantEnvVars) {
+				t.Errorf("BuildExecEnv() = %v, want %v", gotEnvVars, tt.wantEnvVars)
+			}
+		})
+	}
+}
+
+func TestBuildContainerEnv(t *testing.T) {
+	environ := []string{"HOME=/home/johnsmith", "AWS_ACCESS_KEY_ID=AKIAOLD", "AWS_PROFILE=default", "AWS_CONTAINER_AUTHORIZATION_TOKEN=old", "AWS_REGION=us-east-1"}
+	want := []string{"HOME=/home/johnsmith", "AWS_REGION=us-east-1", "AWS_CONTAINER_CREDENTIALS_FULL_URI=http://127.0.0.1:9098/creds", "AWS_CONTAINER_AUTHORIZATION_TOKEN=blah"}
+
+	if got := BuildContainerEnv(environ, "http://127.0.0.1:9098/creds", "blah"); !reflect.DeepEqual(got, want) {
+		t.Errorf("BuildContainerEnv() = %v, want %v", got, want)
+	}
+}
+
+func TestEnvCredentials(t *testing.T) {
+	tests := []struct {
+		name    string
+		environ []string
+		want    *aws.Credentials
+	}{
+		{
+			"Valid/SessionCredentials",
+			[]string{"HOME=/home/johnsmith", "AWS_ACCESS_KEY_ID=ASIAVALID", "AWS_SECRET_ACCESS_KEY=blahblah", "AWS_SESSION_TOKEN=token=", "X_PRINCIPAL_ARN=arn:aws:iam::123456789012:user/johnsmith"},
+			&aws.Credentials{AWSAccessKeyID: "ASIAVALID", AWSSecretAccessKey: "blahblah", AWSSessionToken: "token=", PrincipalARN: "arn:aws:iam::123456789012:user/johnsmith"},
+		},
+		{
+			"Valid/SecurityTokenOnly",
+			[]string{"AWS_ACCESS_KEY_ID=ASIAVALID", "AWS_SECRET_ACCESS_KEY=blahblah", "AWS_SECURITY_TOKEN=token"},
+			&aws.Credentials{AWSAccessKeyID: "ASIAVALID", AWSSecretAccessKey: "blahblah", AWSSessionToken: "token", AWSSecurityToken: "token"},
+		},
+		{
+			"Invalid/NoSecretKey",
+			[]string{"AWS_ACCESS_KEY_ID=ASIAVALID"},
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EnvCredentials(tt.environ); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("EnvCredentials() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnvProfile(t *testing.T) {
+	if got := EnvProfile([]string{"AWS_DEFAULT_PROFILE=home", "AWS_PROFILE=work"}); got != "work" {
+		t.Errorf("EnvProfile() = %v, want work", got)
+	}
+	if got := EnvProfile([]string{"AWS_DEFAULT_PROFILE=home"}); got != "home" {
+		t.Errorf("EnvProfile() = %v, want home", got)
+	}
+}
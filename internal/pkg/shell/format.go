@@ -0,0 +1,175 @@
+package shell
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mfa4aws/internal/pkg/aws"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	//FormatShell outputs statements for a shell Dialect
+	FormatShell string = "shell"
+	//FormatJSON outputs a JSON document including the expiration, profile, principal and account
+	FormatJSON string = "json"
+	//FormatDotenv outputs NAME=value lines for .env files
+	FormatDotenv string = "dotenv"
+	//FormatYAML outputs a YAML document including the expiration, profile, principal and account
+	FormatYAML string = "yaml"
+	//FormatDockerEnv outputs NAME=value lines for docker run --env-file
+	FormatDockerEnv string = "docker-env"
+	//FormatCredentialProcess outputs the JSON document expected from an AWS credential_process
+	FormatCredentialProcess string = "credential-process"
+)
+
+var (
+	dotenvSafeValueRegex = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]*$`)
+
+	formatters = map[string]func(dialect Dialect) Formatter{
+		FormatShell:             func(dialect Dialect) Formatter { return shellFormatter{dialect} },
+		FormatJSON:              func(Dialect) Formatter { return jsonFormatter{} },
+		FormatDotenv:            func(Dialect) Formatter { return dotenvFormatter{} },
+		FormatYAML:              func(Dialect) Formatter { return yamlFormatter{} },
+		FormatDockerEnv:         func(Dialect) Formatter { return dockerEnvFormatter{} },
+		FormatCredentialProcess: func(Dialect) Formatter { return credentialProcessFormatter{} },
+	}
+)
+
+//Formatter writes Credentials to an io.Writer in a particular format
+type Formatter interface {
+	//Format writes the Credentials generated for profile to out
+	Format(out io.Writer, profile string, creds *aws.Credentials) error
+}
+
+//FormatNames returns the sorted names of the supported formats
+func FormatNames() []string {
+	names := make([]string, 0, len(formatters))
+	for name := range formatters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+//LookupFormatter returns the Formatter for name. The shell format outputs statements for dialect
+func LookupFormatter(name string, dialect Dialect) (Formatter, error) {
+	formatter, ok := formatters[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("Unsupported format %s, must be one of %s", name, strings.Join(FormatNames(), ", "))
+	}
+	return formatter(dialect), nil
+}
+
+//credentialsDocument represents the Credentials for the json and yaml formats
+type credentialsDocument struct {
+	Profile         string `json:"Profile"`
+	AccountID       string `json:"AccountId"`
+	PrincipalARN    string `json:"PrincipalArn"`
+	AccessKeyID     string `json:"AccessKeyId"`
+	SecretAccessKey string `json:"SecretAccessKey"`
+	SessionToken    string `json:"SessionToken"`
+	Expiration      string `json:"Expiration,omitempty"`
+}
+
+func newCredentialsDocument(profile string, creds *aws.Credentials) *credentialsDocument {
+	document := &credentialsDocument{
+		Profile:         profile,
+		AccountID:       creds.AccountID(),
+		PrincipalARN:    creds.PrincipalARN,
+		AccessKeyID:     creds.AWSAccessKeyID,
+		SecretAccessKey: creds.AWSSecretAccessKey,
+		SessionToken:    creds.AWSSessionToken,
+	}
+	if !creds.Expiration.IsZero() {
+		document.Expiration = creds.Expiration.UTC().Format(time.RFC3339)
+	}
+	return document
+}
+
+type shellFormatter struct {
+	dialect Dialect
+}
+
+func (f shellFormatter) Format(out io.Writer, profile string, creds *aws.Credentials) error {
+	envVars, err := BuildDialectEnvVars(f.dialect, creds)
+	if err != nil {
+		return err
+	}
+	PrintVars(out, envVars)
+	return nil
+}
+
+type jsonFormatter struct{}
+
+func (jsonFormatter) Format(out io.Writer, profile string, creds *aws.Credentials) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(newCredentialsDocument(profile, creds))
+}
+
+type yamlFormatter struct{}
+
+//Format writes each field as a double quoted scalar, which shares its escaping rules with JSON strings
+func (yamlFormatter) Format(out io.Writer, profile string, creds *aws.Credentials) error {
+	document := newCredentialsDocument(profile, creds)
+	fields := []EnvVar{
+		{"Profile", document.Profile},
+		{"AccountId", document.AccountID},
+		{"PrincipalArn", document.PrincipalARN},
+		{"AccessKeyId", document.AccessKeyID},
+		{"SecretAccessKey", document.SecretAccessKey},
+		{"SessionToken", document.SessionToken},
+	}
+	if len(document.Expiration) != 0 {
+		fields = append(fields, EnvVar{"Expiration", document.Expiration})
+	}
+
+	for _, x := range fields {
+		value, err := json.Marshal(x.Value)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(out, "%s: %s\n", x.Name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type dotenvFormatter struct{}
+
+func (dotenvFormatter) Format(out io.Writer, profile string, creds *aws.Credentials) error {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "$", `\$`)
+	for _, x := range credentialsEnvVars(creds) {
+		value := x.Value
+		if !dotenvSafeValueRegex.MatchString(value) {
+			value = `"` + replacer.Replace(value) + `"`
+		}
+		if _, err := fmt.Fprintf(out, "%s=%s\n", x.Name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type dockerEnvFormatter struct{}
+
+//Format writes the values unquoted, as docker run --env-file takes everything after the = literally
+func (dockerEnvFormatter) Format(out io.Writer, profile string, creds *aws.Credentials) error {
+	for _, x := range credentialsEnvVars(creds) {
+		if _, err := fmt.Fprintf(out, "%s=%s\n", x.Name, x.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type credentialProcessFormatter struct{}
+
+func (credentialProcessFormatter) Format(out io.Writer, profile string, creds *aws.Credentials) error {
+	return PrintCredentialProcess(out, creds)
+}
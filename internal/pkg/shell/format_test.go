@@ -0,0 +1,155 @@
+package shell
+
+import (
+	"bytes"
+	"mfa4aws/internal/pkg/aws"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var formatTestCreds = &aws.Credentials{
+	AWSAccessKeyID:     "AHIAACNB4F5KCDQXSGYW4",
+	AWSSecretAccessKey: "Xoy7ogSQXyTyZI3Oqv8JdAkk1PsbSYzt/vqQ1v+9",
+	AWSSessionToken:    "FQoGZXIvYshgsSJHIOSLKj6nr0FOKIuOP68yKRKvPp3nj9MyaPcvN8PApmWd3yKuTJWf",
+	AWSSecurityToken:   "FQoGZXIvYshgsSJHIOSLKj6nr0FOKIuOP68yKRKvPp3nj9MyaPcvN8PApmWd3yKuTJWf",
+	PrincipalARN:       "arn:aws:iam::162171167783:user/johnsmith",
+	Expiration:         time.Date(2020, 1, 2, 13, 4, 5, 0, time.FixedZone("AEDT", 11*60*60)),
+}
+
+func TestFormatter(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		creds   *aws.Credentials
+		wantOut string
+	}{
+		{
+			"Valid/Shell",
+			FormatShell,
+			formatTestCreds,
+			"export AWS_ACCESS_KEY_ID=AHIAACNB4F5KCDQXSGYW4\n" +
+				"export AWS_SECRET_ACCESS_KEY=Xoy7ogSQXyTyZI3Oqv8JdAkk1PsbSYzt/vqQ1v+9\n" +
+				"export AWS_SESSION_TOKEN=FQoGZXIvYshgsSJHIOSLKj6nr0FOKIuOP68yKRKvPp3nj9MyaPcvN8PApmWd3yKuTJWf\n" +
+				"export AWS_SECURITY_TOKEN=FQoGZXIvYshgsSJHIOSLKj6nr0FOKIuOP68yKRKvPp3nj9MyaPcvN8PApmWd3yKuTJWf\n" +
+				"export X_PRINCIPAL_ARN=arn:aws:iam::162171167783:user/johnsmith\n",
+		},
+		{
+			"Valid/JSON",
+			FormatJSON,
+			formatTestCreds,
+			"{\n" +
+				"  \"Profile\": \"dev\",\n" +
+				"  \"AccountId\": \"162171167783\",\n" +
+				"  \"PrincipalArn\": \"arn:aws:iam::162171167783:user/johnsmith\",\n" +
+				"  \"AccessKeyId\": \"AHIAACNB4F5KCDQXSGYW4\",\n" +
+				"  \"SecretAccessKey\": \"Xoy7ogSQXyTyZI3Oqv8JdAkk1PsbSYzt/vqQ1v+9\",\n" +
+				"  \"SessionToken\": \"FQoGZXIvYshgsSJHIOSLKj6nr0FOKIuOP68yKRKvPp3nj9MyaPcvN8PApmWd3yKuTJWf\",\n" +
+				"  \"Expiration\": \"2020-01-02T02:04:05Z\"\n" +
+				"}\n",
+		},
+		{
+			"Valid/JSON/NoExpiration",
+			FormatJSON,
+			&aws.Credentials{AWSAccessKeyID: "AHIAACNB4F5KCDQXSGYW4"},
+			"{\n" +
+				"  \"Profile\": \"dev\",\n" +
+				"  \"AccountId\": \"\",\n" +
+				"  \"PrincipalArn\": \"\",\n" +
+				"  \"AccessKeyId\": \"AHIAACNB4F5KCDQXSGYW4\",\n" +
+				"  \"SecretAccessKey\": \"\",\n" +
+				"  \"SessionToken\": \"\"\n" +
+				"}\n",
+		},
+		{
+			"Valid/YAML",
+			FormatYAML,
+			formatTestCreds,
+			"Profile: \"dev\"\n" +
+				"AccountId: \"162171167783\"\n" +
+				"PrincipalArn: \"arn:aws:iam::162171167783:user/johnsmith\"\n" +
+				"AccessKeyId: \"AHIAACNB4F5KCDQXSGYW4\"\n" +
+				"SecretAccessKey: \"Xoy7ogSQXyTyZI3Oqv8JdAkk1PsbSYzt/vqQ1v+9\"\n" +
+				"SessionToken: \"FQoGZXIvYshgsSJHIOSLKj6nr0FOKIuOP68yKRKvPp3nj9MyaPcvN8PApmWd3yKuTJWf\"\n" +
+				"Expiration: \"2020-01-02T02:04:05Z\"\n",
+		},
+		{
+			"Valid/Dotenv",
+			FormatDotenv,
+			formatTestCreds,
+			"AWS_ACCESS_KEY_ID=AHIAACNB4F5KCDQXSGYW4\n" +
+				"AWS_SECRET_ACCESS_KEY=Xoy7ogSQXyTyZI3Oqv8JdAkk1PsbSYzt/vqQ1v+9\n" +
+				"AWS_SESSION_TOKEN=FQoGZXIvYshgsSJHIOSLKj6nr0FOKIuOP68yKRKvPp3nj9MyaPcvN8PApmWd3yKuTJWf\n" +
+				"AWS_SECURITY_TOKEN=FQoGZXIvYshgsSJHIOSLKj6nr0FOKIuOP68yKRKvPp3nj9MyaPcvN8PApmWd3yKuTJWf\n" +
+				"X_PRINCIPAL_ARN=arn:aws:iam::162171167783:user/johnsmith\n",
+		},
+		{
+			"Valid/Dotenv/Quoted",
+			FormatDotenv,
+			&aws.Credentials{AWSAccessKeyID: "a b\"$c"},
+			"AWS_ACCESS_KEY_ID=\"a b\\\"\\$c\"\n" +
+				"AWS_SECRET_ACCESS_KEY=\n" +
+				"AWS_SESSION_TOKEN=\n" +
+				"AWS_SECURITY_TOKEN=\n" +
+				"X_PRINCIPAL_ARN=\n",
+		},
+		{
+			"Valid/DockerEnv",
+			FormatDockerEnv,
+			&aws.Credentials{AWSAccessKeyID: "a b\"$c", PrincipalARN: "arn:aws:iam::162171167783:user/johnsmith"},
+			"AWS_ACCESS_KEY_ID=a b\"$c\n" +
+				"AWS_SECRET_ACCESS_KEY=\n" +
+				"AWS_SESSION_TOKEN=\n" +
+				"AWS_SECURITY_TOKEN=\n" +
+				"X_PRINCIPAL_ARN=arn:aws:iam::162171167783:user/johnsmith\n",
+		},
+		{
+			"Valid/CredentialProcess",
+			FormatCredentialProcess,
+			&aws.Credentials{AWSAccessKeyID: "AHIAACNB4F5KCDQXSGYW4"},
+			"{\n  \"Version\": 1,\n  \"AccessKeyId\": \"AHIAACNB4F5KCDQXSGYW4\",\n  \"SecretAccessKey\": \"\"\n}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			formatter, err := LookupFormatter(tt.format, posixDialect{})
+			if err != nil {
+				t.Fatalf("LookupFormatter() error = %v", err)
+			}
+			out := &bytes.Buffer{}
+			if err := formatter.Format(out, "dev", tt.creds); err != nil {
+				t.Errorf("Format() error = %v", err)
+				return
+			}
+			if gotOut := out.String(); gotOut != tt.wantOut {
+				t.Errorf("Format() = %v, want %v", gotOut, tt.wantOut)
+			}
+		})
+	}
+}
+
+func TestLookupFormatter(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		want    Formatter
+		wantErr bool
+	}{
+		{"Valid/Shell", "shell", shellFormatter{fishDialect{}}, false},
+		{"Valid/UpperCase", "JSON", jsonFormatter{}, false},
+		{"Valid/DockerEnv", "docker-env", dockerEnvFormatter{}, false},
+		{"Invalid/Unknown", "toml", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LookupFormatter(tt.format, fishDialect{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("LookupFormatter() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LookupFormatter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
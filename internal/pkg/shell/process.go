@@ -0,0 +1,38 @@
+package shell
+
+import (
+	"encoding/json"
+	"io"
+	"mfa4aws/internal/pkg/aws"
+	"time"
+)
+
+const (
+	credentialProcessVersion int = 1
+)
+
+//credentialProcessOutput represents the JSON document the AWS SDKs expect from a credential_process
+type credentialProcessOutput struct {
+	Version         int    `json:"Version"`
+	AccessKeyID     string `json:"AccessKeyId"`
+	SecretAccessKey string `json:"SecretAccessKey"`
+	SessionToken    string `json:"SessionToken,omitempty"`
+	Expiration      string `json:"Expiration,omitempty"`
+}
+
+//PrintCredentialProcess prints the Credentials to io.writer in the AWS credential_process format
+func PrintCredentialProcess(out io.Writer, creds *aws.Credentials) error {
+	output := credentialProcessOutput{
+		Version:         credentialProcessVersion,
+		AccessKeyID:     creds.AWSAccessKeyID,
+		SecretAccessKey: creds.AWSSecretAccessKey,
+		SessionToken:    creds.AWSSessionToken,
+	}
+	if !creds.Expiration.IsZero() {
+		output.Expiration = creds.Expiration.UTC().Format(time.RFC3339)
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(&output)
+}
@@ -0,0 +1,53 @@
+package shell
+
+import (
+	"bytes"
+	"mfa4aws/internal/pkg/aws"
+	"testing"
+	"time"
+)
+
+func TestPrintCredentialProcess(t *testing.T) {
+	type args struct {
+		creds *aws.Credentials
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantOut string
+	}{
+		{
+			"Invalid/EmptyCreds",
+			args{
+				creds: &aws.Credentials{},
+			},
+			"{\n  \"Version\": 1,\n  \"AccessKeyId\": \"\",\n  \"SecretAccessKey\": \"\"\n}\n",
+		},
+		{
+			"Valid/Creds",
+			args{
+				creds: &aws.Credentials{
+					AWSAccessKeyID:     "AHIAACNB4F5KCDQXSGYW4",
+					AWSSecretAccessKey: "Xoy7ogSQXyTyZI3Oqv8JdAkk1PsbSYzt/vqQ1v+9",
+					AWSSessionToken:    "FQoGZXIvYshgsSJHIOSLKj6nr0FOKIuOP68yKRKvPp3nj9MyaPcvN8PApmWd3yKuTJWf",
+					AWSSecurityToken:   "FQoGZXIvYshgsSJHIOSLKj6nr0FOKIuOP68yKRKvPp3nj9MyaPcvN8PApmWd3yKuTJWf",
+					PrincipalARN:       "162171167783:user/johnsmith",
+					Expiration:         time.Date(2020, 1, 2, 13, 4, 5, 0, time.FixedZone("AEDT", 11*60*60)),
+				},
+			},
+			"{\n  \"Version\": 1,\n  \"AccessKeyId\": \"AHIAACNB4F5KCDQXSGYW4\",\n  \"SecretAccessKey\": \"Xoy7ogSQXyTyZI3Oqv8JdAkk1PsbSYzt/vqQ1v+9\",\n  \"SessionToken\": \"FQoGZXIvYshgsSJHIOSLKj6nr0FOKIuOP68yKRKvPp3nj9MyaPcvN8PApmWd3yKuTJWf\",\n  \"Expiration\": \"2020-01-02T02:04:05Z\"\n}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			if err := PrintCredentialProcess(out, tt.args.creds); err != nil {
+				t.Errorf("PrintCredentialProcess() error = %v", err)
+				return
+			}
+			if gotOut := out.String(); gotOut != tt.wantOut {
+				t.Errorf("PrintCredentialProcess() = %v, want %v", gotOut, tt.wantOut)
+			}
+		})
+	}
+}
@@ -0,0 +1,20 @@
+package totp
+
+import "errors"
+
+var (
+	//ErrInvalidSecret is returned when the secret is not valid base32
+	ErrInvalidSecret = errors.New("TOTP secret is not valid base32")
+
+	//ErrInvalidURI is returned when the URI is not an otpauth://totp/ URI
+	ErrInvalidURI = errors.New("TOTP URI must be an otpauth://totp/ URI")
+
+	//ErrInvalidPeriod is returned when the URI period is not a positive number of seconds
+	ErrInvalidPeriod = errors.New("TOTP period must be a positive number of seconds")
+
+	//ErrInvalidDigits is returned when the URI digits are not between 6 and 8, the lengths of MFA value AWS accepts
+	ErrInvalidDigits = errors.New("TOTP digits must be between 6 and 8")
+
+	//ErrInvalidAlgorithm is returned when the URI algorithm is not SHA1, SHA256 or SHA512
+	ErrInvalidAlgorithm = errors.New("TOTP algorithm must be SHA1, SHA256 or SHA512")
+)
@@ -0,0 +1,210 @@
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	uriScheme string = "otpauth"
+	uriType   string = "totp"
+
+	//AlgorithmSHA1 is the HMAC algorithm used by default and by AWS virtual MFA devices
+	AlgorithmSHA1 string = "SHA1"
+	//AlgorithmSHA256 is the HMAC-SHA-256 variant of RFC 6238
+	AlgorithmSHA256 string = "SHA256"
+	//AlgorithmSHA512 is the HMAC-SHA-512 variant of RFC 6238
+	AlgorithmSHA512 string = "SHA512"
+
+	defaultPeriod int = 30
+	defaultDigits int = 6
+	minDigits     int = 6
+	maxDigits     int = 8
+)
+
+//Key represents a TOTP seed along with the parameters used to generate codes from it
+type Key struct {
+	Secret    []byte
+	Period    int
+	Digits    int
+	Algorithm string
+	Issuer    string
+	Account   string
+}
+
+//Parse returns the Key for either a base32 encoded secret or an otpauth:// URI
+func Parse(seed string) (*Key, error) {
+	seed = strings.TrimSpace(seed)
+	if strings.HasPrefix(strings.ToLower(seed), uriScheme+"://") {
+		return ParseURI(seed)
+	}
+	return ParseSecret(seed)
+}
+
+//ParseSecret returns the Key for a base32 encoded secret using the default SHA1, 6 digit and 30 second parameters
+func ParseSecret(secret string) (*Key, error) {
+	decoded, err := decodeSecret(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Key{
+		Secret:    decoded,
+		Period:    defaultPeriod,
+		Digits:    defaultDigits,
+		Algorithm: AlgorithmSHA1,
+	}, nil
+}
+
+//ParseURI returns the Key for an otpauth://totp/ URI, taking the period, digits and algorithm from it
+func ParseURI(uri string) (*Key, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, ErrInvalidURI
+	}
+
+	if !strings.EqualFold(u.Scheme, uriScheme) || !strings.EqualFold(u.Host, uriType) {
+		return nil, ErrInvalidURI
+	}
+
+	query := u.Query()
+
+	key, err := ParseSecret(query.Get("secret"))
+	if err != nil {
+		return nil, err
+	}
+
+	if period := query.Get("period"); len(period) != 0 {
+		key.Period, err = strconv.Atoi(period)
+		if err != nil || key.Period <= 0 {
+			return nil, ErrInvalidPeriod
+		}
+	}
+
+	if digits := query.Get("digits"); len(digits) != 0 {
+		key.Digits, err = strconv.Atoi(digits)
+		if err != nil || key.Digits < minDigits || key.Digits > maxDigits {
+			return nil, ErrInvalidDigits
+		}
+	}
+
+	if algorithm := query.Get("algorithm"); len(algorithm) != 0 {
+		key.Algorithm = strings.ToUpper(algorithm)
+		if _, err := key.hash(); err != nil {
+			return nil, err
+		}
+	}
+
+	label := strings.TrimPrefix(u.Path, "/")
+	if i := strings.Index(label, ":"); i != -1 {
+		key.Issuer = label[:i]
+		label = strings.TrimSpace(label[i+1:])
+	}
+	key.Account = label
+	if issuer := query.Get("issuer"); len(issuer) != 0 {
+		key.Issuer = issuer
+	}
+
+	return key, nil
+}
+
+func decodeSecret(secret string) ([]byte, error) {
+	secret = strings.ToUpper(strings.Replace(strings.TrimSpace(secret), " ", "", -1))
+	secret = strings.TrimRight(secret, "=")
+	if len(secret) == 0 {
+		return nil, ErrInvalidSecret
+	}
+
+	decoded, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		return nil, ErrInvalidSecret
+	}
+	return decoded, nil
+}
+
+func (k *Key) hash() (func() hash.Hash, error) {
+	switch k.Algorithm {
+	case AlgorithmSHA1, "":
+		return sha1.New, nil
+	case AlgorithmSHA256:
+		return sha256.New, nil
+	case AlgorithmSHA512:
+		return sha512.New, nil
+	default:
+		return nil, ErrInvalidAlgorithm
+	}
+}
+
+//Generate returns the code for the time step containing t, as defined by RFC 6238
+func (k *Key) Generate(t time.Time) (string, error) {
+	h, err := k.hash()
+	if err != nil {
+		return "", err
+	}
+
+	period, digits := k.Period, k.Digits
+	if period <= 0 {
+		period = defaultPeriod
+	}
+	if digits <= 0 {
+		digits = defaultDigits
+	}
+
+	counter := make([]byte, 8)
+	binary.BigEndian.PutUint64(counter, uint64(t.Unix())/uint64(period))
+
+	mac := hmac.New(h, k.Secret)
+	mac.Write(counter)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := uint64(binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff)
+	code := value % uint64(math.Pow10(digits))
+
+	return fmt.Sprintf("%0*d", digits, code), nil
+}
+
+//Remaining returns how long the code for the time step containing t remains valid
+func (k *Key) Remaining(t time.Time) time.Duration {
+	period := k.Period
+	if period <= 0 {
+		period = defaultPeriod
+	}
+	return time.Duration(int64(period)-t.Unix()%int64(period)) * time.Second
+}
+
+//URI returns the Key as an otpauth://totp/ URI
+func (k *Key) URI() string {
+	query := url.Values{}
+	query.Set("secret", base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(k.Secret))
+	query.Set("period", strconv.Itoa(k.Period))
+	query.Set("digits", strconv.Itoa(k.Digits))
+	query.Set("algorithm", k.Algorithm)
+	if len(k.Issuer) != 0 {
+		query.Set("issuer", k.Issuer)
+	}
+
+	label := k.Account
+	if len(k.Issuer) != 0 {
+		label = k.Issuer + ":" + k.Account
+	}
+
+	u := url.URL{
+		Scheme:   uriScheme,
+		Host:     uriType,
+		Path:     "/" + label,
+		RawQuery: query.Encode(),
+	}
+	return u.String()
+}
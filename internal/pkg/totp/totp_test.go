@@ -0,0 +1,193 @@
+package totp
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+//RFC 6238 Appendix B test vectors
+func TestKeyGenerate(t *testing.T) {
+	sha1Key := &Key{Secret: []byte("12345678901234567890"), Period: 30, Digits: 8, Algorithm: AlgorithmSHA1}
+	sha256Key := &Key{Secret: []byte("12345678901234567890123456789012"), Period: 30, Digits: 8, Algorithm: AlgorithmSHA256}
+	sha512Key := &Key{Secret: []byte("1234567890123456789012345678901234567890123456789012345678901234"), Period: 30, Digits: 8, Algorithm: AlgorithmSHA512}
+
+	type args struct {
+		key  *Key
+		unix int64
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    string
+		wantErr bool
+	}{
+		{"Valid/SHA1/59", args{sha1Key, 59}, "94287082", false},
+		{"Valid/SHA256/59", args{sha256Key, 59}, "46119246", false},
+		{"Valid/SHA512/59", args{sha512Key, 59}, "90693936", false},
+		{"Valid/SHA1/1111111109", args{sha1Key, 1111111109}, "07081804", false},
+		{"Valid/SHA256/1111111109", args{sha256Key, 1111111109}, "68084774", false},
+		{"Valid/SHA512/1111111109", args{sha512Key, 1111111109}, "25091201", false},
+		{"Valid/SHA1/1111111111", args{sha1Key, 1111111111}, "14050471", false},
+		{"Valid/SHA256/1111111111", args{sha256Key, 1111111111}, "67062674", false},
+		{"Valid/SHA512/1111111111", args{sha512Key, 1111111111}, "99943326", false},
+		{"Valid/SHA1/1234567890", args{sha1Key, 1234567890}, "89005924", false},
+		{"Valid/SHA256/1234567890", args{sha256Key, 1234567890}, "91819424", false},
+		{"Valid/SHA512/1234567890", args{sha512Key, 1234567890}, "93441116", false},
+		{"Valid/SHA1/2000000000", args{sha1Key, 2000000000}, "69279037", false},
+		{"Valid/SHA256/2000000000", args{sha256Key, 2000000000}, "90698825", false},
+		{"Valid/SHA512/2000000000", args{sha512Key, 2000000000}, "38618901", false},
+		{"Valid/SHA1/20000000000", args{sha1Key, 20000000000}, "65353130", false},
+		{"Valid/SHA256/20000000000", args{sha256Key, 20000000000}, "77737706", false},
+		{"Valid/SHA512/20000000000", args{sha512Key, 20000000000}, "47863826", false},
+		{"Valid/SixDigits", args{&Key{Secret: []byte("12345678901234567890"), Period: 30, Digits: 6, Algorithm: AlgorithmSHA1}, 59}, "287082", false},
+		{"Invalid/Algorithm", args{&Key{Secret: []byte("12345678901234567890"), Algorithm: "MD5"}, 59}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.args.key.Generate(time.Unix(tt.args.unix, 0))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Generate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Generate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	type args struct {
+		seed string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    *Key
+		wantErr bool
+	}{
+		{
+			"Valid/Secret",
+			args{
+				seed: "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+			},
+			&Key{Secret: []byte("12345678901234567890"), Period: 30, Digits: 6, Algorithm: AlgorithmSHA1},
+			false,
+		},
+		{
+			"Valid/SecretLowercaseWithSpaces",
+			args{
+				seed: " gezd gnbv gy3t qojq gezd gnbv gy3t qojq ",
+			},
+			&Key{Secret: []byte("12345678901234567890"), Period: 30, Digits: 6, Algorithm: AlgorithmSHA1},
+			false,
+		},
+		{
+			"Valid/URI",
+			args{
+				seed: "otpauth://totp/Amazon%20Web%20Services:johnsmith@123456789012?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&issuer=Amazon%20Web%20Services",
+			},
+			&Key{Secret: []byte("12345678901234567890"), Period: 30, Digits: 6, Algorithm: AlgorithmSHA1, Issuer: "Amazon Web Services", Account: "johnsmith@123456789012"},
+			false,
+		},
+		{
+			"Valid/URIParameters",
+			args{
+				seed: "otpauth://totp/johnsmith?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&period=60&digits=8&algorithm=sha256",
+			},
+			&Key{Secret: []byte("12345678901234567890"), Period: 60, Digits: 8, Algorithm: AlgorithmSHA256, Account: "johnsmith"},
+			false,
+		},
+		{
+			"Invalid/Secret",
+			args{
+				seed: "not-base32!",
+			},
+			nil,
+			true,
+		},
+		{
+			"Invalid/EmptySecret",
+			args{
+				seed: "",
+			},
+			nil,
+			true,
+		},
+		{
+			"Invalid/HOTPURI",
+			args{
+				seed: "otpauth://hotp/johnsmith?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+			},
+			nil,
+			true,
+		},
+		{
+			"Invalid/URIPeriod",
+			args{
+				seed: "otpauth://totp/johnsmith?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&period=0",
+			},
+			nil,
+			true,
+		},
+		{
+			"Invalid/URIDigits",
+			args{
+				seed: "otpauth://totp/johnsmith?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&digits=9",
+			},
+			nil,
+			true,
+		},
+		{
+			"Invalid/URIDigitsTooFew",
+			args{
+				seed: "otpauth://totp/johnsmith?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&digits=5",
+			},
+			nil,
+			true,
+		},
+		{
+			"Invalid/URIAlgorithm",
+			args{
+				seed: "otpauth://totp/johnsmith?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&algorithm=MD5",
+			},
+			nil,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.args.seed)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeyURI(t *testing.T) {
+	key := &Key{Secret: []byte("12345678901234567890"), Period: 60, Digits: 8, Algorithm: AlgorithmSHA256, Issuer: "Amazon Web Services", Account: "johnsmith@123456789012"}
+
+	got, err := ParseURI(key.URI())
+	if err != nil {
+		t.Fatalf("ParseURI() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, key) {
+		t.Errorf("ParseURI(URI()) = %+v, want %+v", got, key)
+	}
+}
+
+func TestKeyRemaining(t *testing.T) {
+	key := &Key{Period: 30}
+	if got := key.Remaining(time.Unix(59, 0)); got != time.Second {
+		t.Errorf("Remaining() = %v, want %v", got, time.Second)
+	}
+	if got := key.Remaining(time.Unix(60, 0)); got != 30*time.Second {
+		t.Errorf("Remaining() = %v, want %v", got, 30*time.Second)
+	}
+}
@@ -0,0 +1,17 @@
+package vault
+
+import "errors"
+
+var (
+	//ErrInvalidVaultFile is returned when the vault file cannot be read
+	ErrInvalidVaultFile = errors.New("Vault file is invalid")
+
+	//ErrIncorrectPassphrase is returned when the passphrase does not decrypt the vault
+	ErrIncorrectPassphrase = errors.New("Incorrect vault passphrase")
+
+	//ErrVaultLocked is returned when reading or writing secrets before the vault is unlocked
+	ErrVaultLocked = errors.New("Vault is locked")
+
+	//ErrSecretNotFound is returned when the vault holds no secret with the given name
+	ErrSecretNotFound = errors.New("Secret not found in vault")
+)
@@ -0,0 +1,286 @@
+package vault
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"mfa4aws/internal/pkg/appfs"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+
+	"github.com/spf13/afero"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	vaultFolder   string = ".aws/mfa4aws"
+	vaultFile     string = "vault.json"
+	vaultFileMode        = 0600
+	vaultDirMode         = 0700
+
+	fileVersion  int    = 1
+	kdfAlgorithm string = "scrypt"
+	kdfN         int    = 1 << 15
+	kdfR         int    = 8
+	kdfP         int    = 1
+	keyLength    int    = 32
+	saltLength   int    = 32
+
+	//the cost read from a vault file is bounded, so a corrupt or tampered file can neither weaken the key nor exhaust memory
+	maxKDFN      int = 1 << 20
+	maxKDFR      int = 16
+	maxKDFP      int = 4
+	maxKDFMemory int = 1 << 30
+
+	checkName  string = "mfa4aws-vault-check"
+	checkValue string = "mfa4aws"
+)
+
+//Vault is a file of secrets, each encrypted with AES-GCM using a key derived from a passphrase
+type Vault struct {
+	path string
+	data *vaultData
+	key  []byte
+}
+
+type vaultData struct {
+	Version int                      `json:"version"`
+	KDF     *kdfParams               `json:"kdf,omitempty"`
+	Check   *sealedSecret            `json:"check,omitempty"`
+	Secrets map[string]*sealedSecret `json:"secrets"`
+}
+
+type kdfParams struct {
+	Algorithm string `json:"algorithm"`
+	Salt      []byte `json:"salt"`
+	N         int    `json:"n"`
+	R         int    `json:"r"`
+	P         int    `json:"p"`
+}
+
+//valid returns true when the parameters are no weaker than those new vaults are created with, and within the maximums
+func (k *kdfParams) valid() bool {
+	return k.N >= kdfN && k.N <= maxKDFN && k.N&(k.N-1) == 0 &&
+		k.R >= kdfR && k.R <= maxKDFR &&
+		k.P >= kdfP && k.P <= maxKDFP &&
+		128*k.N*k.R <= maxKDFMemory &&
+		len(k.Salt) >= saltLength
+}
+
+type sealedSecret struct {
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+//DefaultPath returns $HOME/.aws/mfa4aws/vault.json
+func DefaultPath() (string, error) {
+	user, err := user.Current()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(user.HomeDir, vaultFolder, vaultFile), nil
+}
+
+//Open reads the vault at path, or $HOME/.aws/mfa4aws/vault.json when path is empty. A missing file is an empty vault
+func Open(path string) (*Vault, error) {
+	if len(path) == 0 {
+		var err error
+		path, err = DefaultPath()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	v := &Vault{
+		path: path,
+		data: &vaultData{
+			Version: fileVersion,
+			Secrets: map[string]*sealedSecret{},
+		},
+	}
+
+	f, err := afero.ReadFile(appfs.Fs, path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return v, nil
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(f, v.data); err != nil {
+		return nil, ErrInvalidVaultFile
+	}
+	if v.data.Version != fileVersion || v.data.KDF == nil || v.data.KDF.Algorithm != kdfAlgorithm || !v.data.KDF.valid() {
+		return nil, ErrInvalidVaultFile
+	}
+	if v.data.Secrets == nil {
+		v.data.Secrets = map[string]*sealedSecret{}
+	}
+
+	return v, nil
+}
+
+//Has returns true when the vault holds a secret called name
+func (v *Vault) Has(name string) bool {
+	_, ok := v.data.Secrets[name]
+	return ok
+}
+
+//Names returns the sorted names of the secrets in the vault
+func (v *Vault) Names() []string {
+	names := make([]string, 0, len(v.data.Secrets))
+	for name := range v.data.Secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+//Unlock derives the key from passphrase, returning ErrIncorrectPassphrase when it does not match the existing vault
+func (v *Vault) Unlock(passphrase []byte) error {
+	if v.data.KDF == nil {
+		salt := make([]byte, saltLength)
+		if _, err := rand.Read(salt); err != nil {
+			return err
+		}
+		v.data.KDF = &kdfParams{
+			Algorithm: kdfAlgorithm,
+			Salt:      salt,
+			N:         kdfN,
+			R:         kdfR,
+			P:         kdfP,
+		}
+	}
+
+	key, err := scrypt.Key(passphrase, v.data.KDF.Salt, v.data.KDF.N, v.data.KDF.R, v.data.KDF.P, keyLength)
+	if err != nil {
+		return err
+	}
+
+	if v.data.Check == nil {
+		v.key = key
+		v.data.Check, err = v.seal(checkName, checkValue)
+		return err
+	}
+
+	v.key = key
+	if value, err := v.open(checkName, v.data.Check); err != nil || value != checkValue {
+		v.key = nil
+		return ErrIncorrectPassphrase
+	}
+
+	return nil
+}
+
+//Get returns the decrypted secret called name
+func (v *Vault) Get(name string) (string, error) {
+	if v.key == nil {
+		return "", ErrVaultLocked
+	}
+
+	sealed, ok := v.data.Secrets[name]
+	if !ok {
+		return "", ErrSecretNotFound
+	}
+
+	return v.open(name, sealed)
+}
+
+//Set encrypts value and stores it as name, replacing any existing secret
+func (v *Vault) Set(name string, value string) error {
+	if v.key == nil {
+		return ErrVaultLocked
+	}
+
+	sealed, err := v.seal(name, value)
+	if err != nil {
+		return err
+	}
+
+	v.data.Secrets[name] = sealed
+	return nil
+}
+
+//Delete removes the secret called name
+func (v *Vault) Delete(name string) {
+	delete(v.data.Secrets, name)
+}
+
+//Save writes the vault to disk readable only by the current user
+func (v *Vault) Save() error {
+	data, err := json.MarshalIndent(v.data, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(v.path)
+	if err := appfs.Fs.MkdirAll(dir, vaultDirMode); err != nil {
+		return err
+	}
+
+	tmp, err := afero.TempFile(appfs.Fs, dir, "."+filepath.Base(v.path))
+	if err != nil {
+		return err
+	}
+	defer appfs.Fs.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := appfs.Fs.Chmod(tmp.Name(), vaultFileMode); err != nil {
+		return err
+	}
+
+	return appfs.Fs.Rename(tmp.Name(), v.path)
+}
+
+func (v *Vault) aead() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(v.key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+//seal encrypts value, binding it to name so secrets cannot be swapped between names
+func (v *Vault) seal(name string, value string) (*sealedSecret, error) {
+	aead, err := v.aead()
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return &sealedSecret{
+		Nonce:      nonce,
+		Ciphertext: aead.Seal(nil, nonce, []byte(value), []byte(name)),
+	}, nil
+}
+
+func (v *Vault) open(name string, sealed *sealedSecret) (string, error) {
+	aead, err := v.aead()
+	if err != nil {
+		return "", err
+	}
+
+	if len(sealed.Nonce) != aead.NonceSize() {
+		return "", ErrInvalidVaultFile
+	}
+
+	value, err := aead.Open(nil, sealed.Nonce, sealed.Ciphertext, []byte(name))
+	if err != nil {
+		return "", ErrIncorrectPassphrase
+	}
+	return string(value), nil
+}
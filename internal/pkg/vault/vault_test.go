@@ -0,0 +1,173 @@
+package vault
+
+import (
+	"encoding/json"
+	"mfa4aws/internal/pkg/appfs"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+func init() {
+	appfs.Fs = afero.NewMemMapFs()
+}
+
+func TestVault(t *testing.T) {
+	const path = "/vault/vault.json"
+
+	v, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	if err := v.Set("totp/default", "blahblah"); err != ErrVaultLocked {
+		t.Errorf("Set() error = %v, want %v", err, ErrVaultLocked)
+	}
+
+	if err := v.Unlock([]byte("correct horse")); err != nil {
+		t.Fatalf("Unlock() error = %v", err)
+	}
+	if err := v.Set("totp/default", "blahblah"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := v.Set("totp/work", "worksecret"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := v.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	info, err := appfs.Fs.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if info.Mode().Perm() != vaultFileMode {
+		t.Errorf("Save() mode = %v, want %v", info.Mode().Perm(), vaultFileMode)
+	}
+
+	raw, err := afero.ReadFile(appfs.Fs, path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if strings.Contains(string(raw), "worksecret") {
+		t.Errorf("Save() wrote plaintext secret")
+	}
+
+	v, err = Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if !v.Has("totp/work") || v.Has("totp/unknown") {
+		t.Errorf("Has() = %v, want secrets listed without unlocking", v.Names())
+	}
+	if _, err := v.Get("totp/work"); err != ErrVaultLocked {
+		t.Errorf("Get() error = %v, want %v", err, ErrVaultLocked)
+	}
+
+	if err := v.Unlock([]byte("wrong")); err != ErrIncorrectPassphrase {
+		t.Errorf("Unlock() error = %v, want %v", err, ErrIncorrectPassphrase)
+	}
+	if err := v.Unlock([]byte("correct horse")); err != nil {
+		t.Fatalf("Unlock() error = %v", err)
+	}
+
+	got, err := v.Get("totp/work")
+	if err != nil || got != "worksecret" {
+		t.Errorf("Get() = %v, %v, want %v", got, err, "worksecret")
+	}
+	if _, err := v.Get("totp/unknown"); err != ErrSecretNotFound {
+		t.Errorf("Get() error = %v, want %v", err, ErrSecretNotFound)
+	}
+
+	v.data.Secrets["totp/default"], v.data.Secrets["totp/work"] = v.data.Secrets["totp/work"], v.data.Secrets["totp/default"]
+	if _, err := v.Get("totp/work"); err != ErrIncorrectPassphrase {
+		t.Errorf("Get() of swapped secret error = %v, want %v", err, ErrIncorrectPassphrase)
+	}
+
+	v.Delete("totp/default")
+	if names := v.Names(); len(names) != 1 || names[0] != "totp/work" {
+		t.Errorf("Names() = %v, want [totp/work]", names)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	if err := afero.WriteFile(appfs.Fs, "/vault/invalid.json", []byte(`blah`), 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err := afero.WriteFile(appfs.Fs, "/vault/unknownversion.json", []byte(`{"version": 99, "secrets": {}}`), 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	for path, kdf := range map[string]*kdfParams{
+		"/vault/defaultcost.json": {Algorithm: kdfAlgorithm, Salt: make([]byte, saltLength), N: kdfN, R: kdfR, P: kdfP},
+		"/vault/weakcost.json":    {Algorithm: kdfAlgorithm, Salt: make([]byte, saltLength), N: 2, R: 1, P: 1},
+		"/vault/hugecost.json":    {Algorithm: kdfAlgorithm, Salt: make([]byte, saltLength), N: 1 << 30, R: kdfR, P: kdfP},
+	} {
+		data, err := json.Marshal(&vaultData{Version: fileVersion, KDF: kdf})
+		if err != nil {
+			t.Fatalf("Marshal() error = %v", err)
+		}
+		if err := afero.WriteFile(appfs.Fs, path, data, 0600); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+
+	type args struct {
+		path string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{
+			"Valid/MissingFile",
+			args{
+				path: "/vault/missing.json",
+			},
+			false,
+		},
+		{
+			"Invalid/InvalidFile",
+			args{
+				path: "/vault/invalid.json",
+			},
+			true,
+		},
+		{
+			"Valid/DefaultCost",
+			args{
+				path: "/vault/defaultcost.json",
+			},
+			false,
+		},
+		{
+			"Invalid/UnknownVersion",
+			args{
+				path: "/vault/unknownversion.json",
+			},
+			true,
+		},
+		{
+			"Invalid/WeakCost",
+			args{
+				path: "/vault/weakcost.json",
+			},
+			true,
+		},
+		{
+			"Invalid/HugeCost",
+			args{
+				path: "/vault/hugecost.json",
+			},
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Open(tt.args.path); (err != nil) != tt.wantErr {
+				t.Errorf("Open() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}